@@ -0,0 +1,214 @@
+// Package adminapi provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/deepmap/oapi-codegen version v1.9.1 DO NOT EDIT.
+package adminapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ApiKeyHeaderScopes = "apiKeyHeader.Scopes"
+)
+
+// Defines values for ImportJobState.
+const (
+	ImportJobStateCanceled ImportJobState = "canceled"
+
+	ImportJobStateFailed ImportJobState = "failed"
+
+	ImportJobStatePending ImportJobState = "pending"
+
+	ImportJobStateRunning ImportJobState = "running"
+
+	ImportJobStateSucceeded ImportJobState = "succeeded"
+)
+
+// Defines values for ImportRequestFormat.
+const (
+	ImportRequestFormatAuto ImportRequestFormat = "auto"
+
+	ImportRequestFormatCsv ImportRequestFormat = "csv"
+
+	ImportRequestFormatJsonl ImportRequestFormat = "jsonl"
+
+	ImportRequestFormatParquet ImportRequestFormat = "parquet"
+)
+
+// Dataset defines model for dataset.
+type Dataset struct {
+	// When the dataset was activated the last time, missing if never.
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+	Active      bool       `json:"active"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Number of stored IP locations, counted on activation.
+	Records int `json:"records"`
+	Version int `json:"version"`
+}
+
+// Error defines model for error.
+type Error struct {
+	ErrorDetails string `json:"errorDetails"`
+
+	// ID of the request to find it in the server logs, also returned in X-Request-Id header.
+	RequestId *string `json:"requestId,omitempty"`
+}
+
+// ImportJob defines model for importJob.
+type ImportJob struct {
+	CancelRequested bool      `json:"cancel_requested"`
+	CreatedAt       time.Time `json:"created_at"`
+
+	// Why the job failed.
+	Error      *string        `json:"error,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Format     string         `json:"format"`
+	Id         int            `json:"id"`
+	Source     string         `json:"source"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	State      ImportJobState `json:"state"`
+
+	// Progress of a running job, or the result of a finished one.
+	Statistics ImportStatistics `json:"statistics"`
+}
+
+// ImportJobState defines model for ImportJob.State.
+type ImportJobState string
+
+// ImportRequest defines model for importRequest.
+type ImportRequest struct {
+	// Format of the data, detected by the extension by default.
+	Format *ImportRequestFormat `json:"format,omitempty"`
+
+	// Path relative to the server data directory (e.g. of an uploaded file) or http(s) URL.
+	Source string `json:"source"`
+}
+
+// Format of the data, detected by the extension by default.
+type ImportRequestFormat string
+
+// Progress of a running job, or the result of a finished one.
+type ImportStatistics struct {
+	// Version of the dataset the job imports to, once it is created.
+	DatasetVersion *int `json:"dataset_version,omitempty"`
+	Duplicated     int  `json:"duplicated"`
+	Imported       int  `json:"imported"`
+	NonValid       int  `json:"non_valid"`
+
+	// Breakdown of non-valid records by reason.
+	NonValidReasons *ImportStatistics_NonValidReasons `json:"non_valid_reasons,omitempty"`
+
+	// Results of quality gates checked before activation of the dataset.
+	QualityGates *[]QualityGate `json:"quality_gates,omitempty"`
+}
+
+// Breakdown of non-valid records by reason.
+type ImportStatistics_NonValidReasons struct {
+	AdditionalProperties map[string]int `json:"-"`
+}
+
+// QualityGate defines model for qualityGate.
+type QualityGate struct {
+	Gate      string  `json:"gate"`
+	Passed    bool    `json:"passed"`
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+}
+
+// Upload defines model for upload.
+type Upload struct {
+	// Source to import the uploaded file.
+	Source string `json:"source"`
+}
+
+// JobId defines model for jobId.
+type JobId int
+
+// BadRequest defines model for badRequest.
+type BadRequest Error
+
+// Conflict defines model for conflict.
+type Conflict Error
+
+// Forbidden defines model for forbidden.
+type Forbidden Error
+
+// NotFound defines model for notFound.
+type NotFound Error
+
+// TooManyRequests defines model for tooManyRequests.
+type TooManyRequests Error
+
+// Unauthorized defines model for unauthorized.
+type Unauthorized Error
+
+// Unavailable defines model for unavailable.
+type Unavailable Error
+
+// GetAdminV1ImportsParams defines parameters for GetAdminV1Imports.
+type GetAdminV1ImportsParams struct {
+	// Maximum number of jobs.
+	Limit *int `json:"limit,omitempty"`
+}
+
+// PostAdminV1ImportsJSONBody defines parameters for PostAdminV1Imports.
+type PostAdminV1ImportsJSONBody ImportRequest
+
+// PostAdminV1ImportsJSONRequestBody defines body for PostAdminV1Imports for application/json ContentType.
+type PostAdminV1ImportsJSONRequestBody PostAdminV1ImportsJSONBody
+
+// Getter for additional properties for ImportStatistics_NonValidReasons. Returns the specified
+// element and whether it was found
+func (a ImportStatistics_NonValidReasons) Get(fieldName string) (value int, found bool) {
+	if a.AdditionalProperties != nil {
+		value, found = a.AdditionalProperties[fieldName]
+	}
+	return
+}
+
+// Setter for additional properties for ImportStatistics_NonValidReasons
+func (a *ImportStatistics_NonValidReasons) Set(fieldName string, value int) {
+	if a.AdditionalProperties == nil {
+		a.AdditionalProperties = make(map[string]int)
+	}
+	a.AdditionalProperties[fieldName] = value
+}
+
+// Override default JSON handling for ImportStatistics_NonValidReasons to handle AdditionalProperties
+func (a *ImportStatistics_NonValidReasons) UnmarshalJSON(b []byte) error {
+	object := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &object)
+	if err != nil {
+		return err
+	}
+
+	if len(object) != 0 {
+		a.AdditionalProperties = make(map[string]int)
+		for fieldName, fieldBuf := range object {
+			var fieldVal int
+			err := json.Unmarshal(fieldBuf, &fieldVal)
+			if err != nil {
+				return fmt.Errorf("error unmarshaling field %s: %w", fieldName, err)
+			}
+			a.AdditionalProperties[fieldName] = fieldVal
+		}
+	}
+	return nil
+}
+
+// Override default JSON handling for ImportStatistics_NonValidReasons to handle AdditionalProperties
+func (a ImportStatistics_NonValidReasons) MarshalJSON() ([]byte, error) {
+	var err error
+	object := make(map[string]json.RawMessage)
+
+	for fieldName, field := range a.AdditionalProperties {
+		object[fieldName], err = json.Marshal(field)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling '%s': %w", fieldName, err)
+		}
+	}
+	return json.Marshal(object)
+}
@@ -4,6 +4,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -13,6 +14,9 @@ import (
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Export IP locations.
+	// (GET /v1/export)
+	GetV1Export(w http.ResponseWriter, r *http.Request, params GetV1ExportParams)
 	// Get prediction of IP-address location.
 	// (GET /v1/iplocation)
 	GetV1Iplocation(w http.ResponseWriter, r *http.Request, params GetV1IplocationParams)
@@ -27,12 +31,73 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
 
+// GetV1Export operation middleware
+func (siw *ServerInterfaceWrapper) GetV1Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyHeaderScopes, []string{""})
+
+	ctx = context.WithValue(ctx, ApiKeyQueryScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetV1ExportParams
+
+	// ------------- Optional query parameter "format" -------------
+	if paramValue := r.URL.Query().Get("format"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "format", r.URL.Query(), &params.Format)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "format", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "country_code" -------------
+	if paramValue := r.URL.Query().Get("country_code"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "country_code", r.URL.Query(), &params.CountryCode)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "country_code", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "dataset_version" -------------
+	if paramValue := r.URL.Query().Get("dataset_version"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "dataset_version", r.URL.Query(), &params.DatasetVersion)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "dataset_version", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetV1Export(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // GetV1Iplocation operation middleware
 func (siw *ServerInterfaceWrapper) GetV1Iplocation(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
 
+	ctx = context.WithValue(ctx, ApiKeyHeaderScopes, []string{""})
+
+	ctx = context.WithValue(ctx, ApiKeyQueryScopes, []string{""})
+
 	// Parameter object where we will unmarshal all parameters from the context
 	var params GetV1IplocationParams
 
@@ -50,6 +115,38 @@ func (siw *ServerInterfaceWrapper) GetV1Iplocation(w http.ResponseWriter, r *htt
 		return
 	}
 
+	// ------------- Optional query parameter "include" -------------
+	if paramValue := r.URL.Query().Get("include"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "include", r.URL.Query(), &params.Include)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "include", Err: err})
+		return
+	}
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-None-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-None-Match", runtime.ParamLocationHeader, valueList[0], &IfNoneMatch)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-None-Match", Err: err})
+			return
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+
+	}
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetV1Iplocation(w, r, params)
 	}
@@ -174,6 +271,9 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/v1/export", wrapper.GetV1Export)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/v1/iplocation", wrapper.GetV1Iplocation)
 	})
@@ -0,0 +1,229 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: api/geolocationpb/geolocation.proto
+
+package geolocationpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	GeolocationService_Lookup_FullMethodName      = "/geolocation.v1.GeolocationService/Lookup"
+	GeolocationService_BatchLookup_FullMethodName = "/geolocation.v1.GeolocationService/BatchLookup"
+	GeolocationService_GetDataset_FullMethodName  = "/geolocation.v1.GeolocationService/GetDataset"
+)
+
+// GeolocationServiceClient is the client API for GeolocationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Geolocation API for internal services, the same as REST API, see geolocation_1.0.0.yaml.
+type GeolocationServiceClient interface {
+	// Lookup predicts IP-address location.
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	// BatchLookup predicts locations of streamed IP-addresses, responses are sent in the order of requests.
+	BatchLookup(ctx context.Context, opts ...grpc.CallOption) (GeolocationService_BatchLookupClient, error)
+	// GetDataset returns information about the dataset version, the active one by default.
+	GetDataset(ctx context.Context, in *GetDatasetRequest, opts ...grpc.CallOption) (*Dataset, error)
+}
+
+type geolocationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGeolocationServiceClient(cc grpc.ClientConnInterface) GeolocationServiceClient {
+	return &geolocationServiceClient{cc}
+}
+
+func (c *geolocationServiceClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupResponse)
+	err := c.cc.Invoke(ctx, GeolocationService_Lookup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geolocationServiceClient) BatchLookup(ctx context.Context, opts ...grpc.CallOption) (GeolocationService_BatchLookupClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GeolocationService_ServiceDesc.Streams[0], GeolocationService_BatchLookup_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &geolocationServiceBatchLookupClient{ClientStream: stream}
+	return x, nil
+}
+
+type GeolocationService_BatchLookupClient interface {
+	Send(*LookupRequest) error
+	Recv() (*LookupResponse, error)
+	grpc.ClientStream
+}
+
+type geolocationServiceBatchLookupClient struct {
+	grpc.ClientStream
+}
+
+func (x *geolocationServiceBatchLookupClient) Send(m *LookupRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *geolocationServiceBatchLookupClient) Recv() (*LookupResponse, error) {
+	m := new(LookupResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *geolocationServiceClient) GetDataset(ctx context.Context, in *GetDatasetRequest, opts ...grpc.CallOption) (*Dataset, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dataset)
+	err := c.cc.Invoke(ctx, GeolocationService_GetDataset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GeolocationServiceServer is the server API for GeolocationService service.
+// All implementations must embed UnimplementedGeolocationServiceServer
+// for forward compatibility
+//
+// Geolocation API for internal services, the same as REST API, see geolocation_1.0.0.yaml.
+type GeolocationServiceServer interface {
+	// Lookup predicts IP-address location.
+	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	// BatchLookup predicts locations of streamed IP-addresses, responses are sent in the order of requests.
+	BatchLookup(GeolocationService_BatchLookupServer) error
+	// GetDataset returns information about the dataset version, the active one by default.
+	GetDataset(context.Context, *GetDatasetRequest) (*Dataset, error)
+	mustEmbedUnimplementedGeolocationServiceServer()
+}
+
+// UnimplementedGeolocationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedGeolocationServiceServer struct {
+}
+
+func (UnimplementedGeolocationServiceServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedGeolocationServiceServer) BatchLookup(GeolocationService_BatchLookupServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchLookup not implemented")
+}
+func (UnimplementedGeolocationServiceServer) GetDataset(context.Context, *GetDatasetRequest) (*Dataset, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDataset not implemented")
+}
+func (UnimplementedGeolocationServiceServer) mustEmbedUnimplementedGeolocationServiceServer() {}
+
+// UnsafeGeolocationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GeolocationServiceServer will
+// result in compilation errors.
+type UnsafeGeolocationServiceServer interface {
+	mustEmbedUnimplementedGeolocationServiceServer()
+}
+
+func RegisterGeolocationServiceServer(s grpc.ServiceRegistrar, srv GeolocationServiceServer) {
+	s.RegisterService(&GeolocationService_ServiceDesc, srv)
+}
+
+func _GeolocationService_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeolocationServiceServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeolocationService_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeolocationServiceServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeolocationService_BatchLookup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GeolocationServiceServer).BatchLookup(&geolocationServiceBatchLookupServer{ServerStream: stream})
+}
+
+type GeolocationService_BatchLookupServer interface {
+	Send(*LookupResponse) error
+	Recv() (*LookupRequest, error)
+	grpc.ServerStream
+}
+
+type geolocationServiceBatchLookupServer struct {
+	grpc.ServerStream
+}
+
+func (x *geolocationServiceBatchLookupServer) Send(m *LookupResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *geolocationServiceBatchLookupServer) Recv() (*LookupRequest, error) {
+	m := new(LookupRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _GeolocationService_GetDataset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDatasetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeolocationServiceServer).GetDataset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeolocationService_GetDataset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeolocationServiceServer).GetDataset(ctx, req.(*GetDatasetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GeolocationService_ServiceDesc is the grpc.ServiceDesc for GeolocationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GeolocationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "geolocation.v1.GeolocationService",
+	HandlerType: (*GeolocationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Lookup",
+			Handler:    _GeolocationService_Lookup_Handler,
+		},
+		{
+			MethodName: "GetDataset",
+			Handler:    _GeolocationService_GetDataset_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchLookup",
+			Handler:       _GeolocationService_BatchLookup_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/geolocationpb/geolocation.proto",
+}
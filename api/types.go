@@ -3,9 +3,21 @@
 // Code generated by github.com/deepmap/oapi-codegen version v1.9.1 DO NOT EDIT.
 package api
 
+import (
+	"time"
+)
+
+const (
+	ApiKeyHeaderScopes = "apiKeyHeader.Scopes"
+	ApiKeyQueryScopes  = "apiKeyQuery.Scopes"
+)
+
 // Error defines model for error.
 type Error struct {
 	ErrorDetails string `json:"errorDetails"`
+
+	// ID of the request to find it in the server logs, also returned in X-Request-Id header.
+	RequestId *string `json:"requestId,omitempty"`
 }
 
 // IpLocation defines model for ipLocation.
@@ -15,10 +27,67 @@ type IpLocation struct {
 	CountryCode string  `json:"country_code"`
 	Latitude    float64 `json:"latitude"`
 	Longitude   float64 `json:"longitude"`
+
+	// Sent if requested with include=provenance, fields are empty for locations imported without it.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Sent if requested with include=provenance, fields are empty for locations imported without it.
+type Provenance struct {
+	// Version of the dataset the location belongs to.
+	DatasetVersion int `json:"dataset_version"`
+
+	// ID of the import which stored the location.
+	ImportRunId string `json:"import_run_id"`
+
+	// Line of the source file, or row number for Parquet.
+	Line int `json:"line"`
+
+	// When the location was observed, the import time unless given by the source.
+	ObservedAt *time.Time `json:"observed_at,omitempty"`
+
+	// Checksum of the source file.
+	SourceId string `json:"source_id"`
+
+	// Name of the source file or feed.
+	SourceName string `json:"source_name"`
 }
 
+// Forbidden defines model for forbidden.
+type Forbidden Error
+
+// TooManyRequests defines model for tooManyRequests.
+type TooManyRequests Error
+
+// Unauthorized defines model for unauthorized.
+type Unauthorized Error
+
+// GetV1ExportParams defines parameters for GetV1Export.
+type GetV1ExportParams struct {
+	// Output format.
+	Format *GetV1ExportParamsFormat `json:"format,omitempty"`
+
+	// Export only locations of the country.
+	CountryCode *string `json:"country_code,omitempty"`
+
+	// Version of the dataset to export, the active one is used by default.
+	DatasetVersion *int `json:"dataset_version,omitempty"`
+}
+
+// GetV1ExportParamsFormat defines parameters for GetV1Export.
+type GetV1ExportParamsFormat string
+
 // GetV1IplocationParams defines parameters for GetV1Iplocation.
 type GetV1IplocationParams struct {
 	// IP-address to locate.
 	Ip string `json:"ip"`
+
+	// Additional details of the location, provenance is where the location comes from.
+	Include *GetV1IplocationParamsInclude `json:"include,omitempty"`
+
+	// ETags of cached responses, the location is not sent if any of them is still current.
+	IfNoneMatch *string `json:"If-None-Match,omitempty"`
 }
+
+// GetV1IplocationParamsInclude defines parameters for GetV1Iplocation.
+type GetV1IplocationParamsInclude string
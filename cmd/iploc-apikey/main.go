@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dronnix/search-accomodation/internal/flags"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/apikey"
+	"github.com/dronnix/search-accomodation/storage"
+)
+
+type options struct {
+	Create createCommand `command:"create" description:"create an API key, the secret is printed once"`
+	Revoke revokeCommand `command:"revoke" description:"revoke an API key"`
+	List   struct{}      `command:"list" description:"list API keys"`
+	*flags.Postgres
+	*flags.Logging
+}
+
+type createCommand struct {
+	Name          string  `long:"name" description:"name of the client the key is issued for" required:"true"`
+	Admin         bool    `long:"admin" description:"allow the key to use the admin API"`
+	RatePerSecond float64 `long:"rate" description:"allowed requests per second, unlimited if 0" default:"0"`
+	Burst         int     `long:"burst" description:"requests allowed at once above the rate" default:"1"`
+	DailyQuota    int     `long:"daily-quota" description:"allowed requests per UTC day, unlimited if 0" default:"0"`
+}
+
+type revokeCommand struct {
+	ID int `long:"id" description:"ID of the key to revoke" required:"true"`
+}
+
+const exitCodeOK = 0
+const exitCodeError = 1
+
+func main() {
+	os.Exit(_main())
+}
+
+func _main() int { // separate function to avoid "defer" in main
+	opts := &options{}
+	command := flags.ParseCommand(opts)
+
+	logger, err := logging.New(os.Stderr, opts.LoggingOptions()) // Stdout is used for the output.
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not setup logger: %v\n", err)
+		return exitCodeError
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := storage.CreateConnectionPool(ctx, opts.PostgresConnectionString(), opts.PoolOptions(), logger)
+	if err != nil {
+		logger.Error("could not create connection pool", "error", err)
+		return exitCodeError
+	}
+	defer pool.Close()
+	keys := storage.NewAPIKeyStorage(pool, logger)
+
+	switch command {
+	case "create":
+		err = create(ctx, os.Stdout, opts.Create, keys)
+	case "revoke":
+		err = keys.RevokeAPIKey(ctx, opts.Revoke.ID)
+	case "list":
+		err = list(ctx, os.Stdout, keys)
+	}
+	if err != nil {
+		logger.Error("could not "+command+" API key", "error", err)
+		return exitCodeError
+	}
+	return exitCodeOK
+}
+
+func create(ctx context.Context, out io.Writer, cmd createCommand, manager apikey.Manager) error {
+	limits := apikey.Limits{RatePerSecond: cmd.RatePerSecond, Burst: cmd.Burst, DailyQuota: cmd.DailyQuota}
+	secret, key, err := apikey.CreateKey(ctx, cmd.Name, cmd.Admin, limits, manager)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	fmt.Fprintf(out, "ID: %d\nKey: %s\n", key.ID, secret)
+	return nil
+}
+
+func list(ctx context.Context, out io.Writer, manager apikey.Manager) error {
+	keys, err := manager.ListAPIKeys(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tADMIN\tRATE\tBURST\tDAILY QUOTA\tCREATED\tREVOKED")
+	for _, k := range keys {
+		revoked := "-"
+		if k.Revoked() {
+			revoked = k.RevokedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%t\t%g\t%d\t%d\t%s\t%s\n", k.ID, k.Name, k.Admin, k.RatePerSecond, k.Burst, k.DailyQuota,
+			k.CreatedAt.UTC().Format(time.RFC3339), revoked)
+	}
+	return w.Flush() //nolint:wrapcheck
+}
@@ -2,22 +2,50 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
 
 	"github.com/dronnix/search-accomodation/internal/flags"
+	"github.com/dronnix/search-accomodation/internal/import_progress"
+	"github.com/dronnix/search-accomodation/internal/import_report"
 	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/internal/metrics"
+	"github.com/dronnix/search-accomodation/internal/tracing"
 	"github.com/dronnix/search-accomodation/model/geolocation"
 	"github.com/dronnix/search-accomodation/storage"
 )
 
 type options struct {
-	PathToCSV string `long:"path-to-csv" default:"data_dump.csv" env:"PATH_TO_CSV"`
+	Path                string        `long:"path" description:"path to the file to import, data_dump.csv if not set" env:"PATH_TO_DATA"`                                                                                                                                                    // nolint:lll
+	PathToCSV           string        `long:"path-to-csv" description:"deprecated alias of --path" env:"PATH_TO_CSV" hidden:"true"`                                                                                                                                                          // nolint:lll
+	Format              string        `long:"format" description:"format of the file, detected by extension if auto" default:"auto" choice:"auto" choice:"csv" choice:"jsonl" choice:"parquet" env:"IMPORT_FORMAT"`                                                                          // nolint:lll
+	Progress            string        `long:"progress" description:"how the progress is shown: bar on a terminal, otherwise log if auto; json prints an object per line to stdout" default:"auto" choice:"auto" choice:"bar" choice:"log" choice:"json" choice:"none" env:"IMPORT_PROGRESS"` // nolint:lll
+	ProgressInterval    time.Duration `long:"progress-interval" description:"how often the progress is logged" default:"10s" env:"IMPORT_PROGRESS_INTERVAL"`                                                                                                                                 // nolint:lll
+	Report              string        `long:"report" description:"format of the import report" default:"text" choice:"text" choice:"json" choice:"yaml" env:"IMPORT_REPORT"`                                                                                                                 // nolint:lll
+	ReportOut           string        `long:"report-out" description:"file to write the report to, stdout if empty (skipped with --progress=json)" env:"IMPORT_REPORT_OUT"`                                                                                                                  // nolint:lll
+	MetricsPushURL      string        `long:"metrics-push-url" description:"Prometheus Pushgateway URL, metrics are not pushed if empty" env:"METRICS_PUSH_URL"`                                                                                                                             // nolint:lll
+	MetricsPushInterval time.Duration `long:"metrics-push-interval" description:"how often metrics are pushed during import" default:"10s" env:"METRICS_PUSH_INTERVAL"`                                                                                                                      // nolint:lll
+	NormalizedSchema    bool          `long:"normalized-schema" description:"store the dataset to the normalized schema" env:"NORMALIZED_SCHEMA"`                                                                                                                                            // nolint:lll
+	Partitioned         bool          `long:"partitioned" description:"store the dataset to a partition of its own" env:"PARTITIONED"`                                                                                                                                                       // nolint:lll
+	ObservedAt          string        `long:"observed-at" description:"when the data was observed, RFC 3339; the import time if empty" env:"IMPORT_OBSERVED_AT"`                                                                                                                             // nolint:lll
+	*flags.Storage
 	*flags.Postgres
+	*flags.QualityGates
+	*flags.Logging
+	*flags.Tracing
 }
 
 const exitCodeOK = 0
 const exitCodeError = 1
+const exitCodeRejected = 2 // Quality gates failed.
 
 func main() {
 	os.Exit(_main())
@@ -27,55 +55,274 @@ func _main() int { // separate function to avoid "defer" in main
 	opts := &options{}
 	flags.Parse(opts)
 
-	importer, err := setupImporter(opts.PathToCSV)
+	logger, err := logging.New(os.Stderr, opts.LoggingOptions())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not setup logger: %v\n", err)
+		return exitCodeError
+	}
+	if opts.Path, err = resolvePath(opts, logger); err != nil {
+		logger.Error("could not setup path", "error", err)
+		return exitCodeError
+	}
+
+	importer, closeFile, err := iplocation_importer.OpenFile(opts.Path, iplocation_importer.Format(opts.Format))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "could not setup importer: %v\n", err)
+		logger.Error("could not setup importer", "error", err)
+		return exitCodeError
+	}
+	defer closeFile.Close()
+	input, err := describeInput(opts)
+	if err != nil {
+		logger.Error("could not read input", "error", err)
 		return exitCodeError
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	storage, err := setupStorage(ctx, opts.Postgres)
+	shutdownTracing, err := tracing.Setup(ctx, "iploc-data-importer", opts.TracingOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "could not setup storage: %v\n", err)
+		logger.Error("could not setup tracing", "error", err)
 		return exitCodeError
 	}
+	defer func() {
+		if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+			logger.Warn("could not flush spans", "error", shutdownErr)
+		}
+	}()
 
-	stats, err := geolocation.ImportIPLocations(ctx, importer, storage)
+	storage, closeStorage, err := setupStorage(ctx, opts, logger)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "could not import IP locations: %v\n", err)
+		logger.Error("could not setup storage", "error", err)
+		return exitCodeError
+	}
+	defer closeStorage()
+
+	registry := prometheus.NewRegistry()
+	importMetrics := metrics.NewImporter(registry)
+	if opts.MetricsPushURL != "" {
+		pusher := push.New(opts.MetricsPushURL, "iploc_data_importer").Gatherer(registry)
+		stopPushing := startMetricsPusher(ctx, pusher, opts.MetricsPushInterval, logger)
+		defer stopPushing()
+	}
+
+	progressMode := import_progress.ResolveMode(import_progress.Mode(opts.Progress), os.Stderr)
+	importOpts, err := setupImportOptions(progressMode, importer, opts.ProgressInterval, logger)
+	if err != nil {
+		logger.Error("could not setup progress reporting", "error", err)
+		return exitCodeError
+	}
+	importOpts.Gates, err = opts.QualityGatesOptions()
+	if err == nil {
+		importOpts.Gates, err = iplocation_importer.LoadQualityGates(importOpts.Gates, opts.CountryCentroids)
+	}
+	if err != nil {
+		logger.Error("could not setup quality gates", "error", err)
+		return exitCodeError
+	}
+	importOpts.Profiler, importOpts.Remover = storage, storage
+	if importOpts.Source, err = importSource(opts, input); err != nil {
+		logger.Error("could not setup provenance", "error", err)
+		return exitCodeError
+	}
+	var lastStats geolocation.ImportStatistics // Of the stored batches, if the import fails.
+	reportProgress, observeProgress := importOpts.Progress, importMetrics.ObserveProgress()
+	importOpts.Progress = func(p geolocation.ImportProgress) {
+		lastStats = p.Statistics
+		observeProgress(p)
+		if reportProgress != nil {
+			reportProgress(p)
+		}
+	}
+
+	logger.Info("import started", "path", opts.Path, "format", opts.Format,
+		"import_run_id", importOpts.Source.ImportRunID)
+	startedAt := time.Now()
+	stats, err := geolocation.ImportIPLocations(ctx,
+		importMetrics.InstrumentImporter(importer), importMetrics.InstrumentStorer(storage), importOpts)
+	exitCode := exitCodeOK
+	switch {
+	case errors.Is(err, geolocation.ErrQualityGateFailed):
+		logger.Error("dataset failed quality gates", "error", err)
+		exitCode = exitCodeRejected
+	case err != nil:
+		logger.Error("could not import IP locations", "error", err)
+		stats, exitCode = lastStats, exitCodeError
+	default:
+		logger.Info("import finished", "dataset_version", stats.DatasetVersion, "imported", stats.Imported,
+			"non_valid", stats.NonValid, "duplicated", stats.Duplicated, "duration", stats.TimeSpent)
+	}
+
+	report := import_report.New(input, startedAt, stats, err)
+	report.ExitCode = exitCode
+	if err == nil {
+		dataset, fetchErr := storage.FetchDataset(ctx, stats.DatasetVersion)
+		if fetchErr != nil {
+			logger.Warn("could not fetch imported dataset", "error", fetchErr)
+		} else {
+			report.DatasetRecords = &dataset.Records
+		}
+	}
+	if err = writeReport(opts, progressMode, report); err != nil {
+		logger.Error("could not write report", "error", err)
 		return exitCodeError
 	}
+	return exitCode
+}
 
-	fmt.Printf("Time spent(sec): %d\n", int(stats.TimeSpent.Seconds()))
-	fmt.Printf("Total records found: %d\n", stats.Total())
-	fmt.Printf("Non-valid records: %d\n", stats.NonValid)
-	fmt.Printf("Duplicated records: %d\n", stats.Duplicated)
-	fmt.Printf("Imported records: %d\n", stats.Imported)
+func describeInput(opts *options) (import_report.Input, error) {
+	format := iplocation_importer.Format(opts.Format)
+	if format == iplocation_importer.FormatAuto {
+		var err error
+		if format, err = iplocation_importer.DetectFormat(opts.Path); err != nil {
+			return import_report.Input{}, err //nolint:wrapcheck
+		}
+	}
+	return import_report.DescribeInput(opts.Path, string(format)) //nolint:wrapcheck
+}
 
-	return exitCodeOK
+// importSource returns provenance of the imported locations, the file is identified by its checksum.
+func importSource(opts *options, input import_report.Input) (geolocation.Provenance, error) {
+	source := geolocation.Provenance{
+		SourceID:    "sha256:" + input.SHA256,
+		SourceName:  filepath.Base(opts.Path),
+		ImportRunID: geolocation.NewImportRunID(),
+	}
+	if opts.ObservedAt != "" {
+		observedAt, err := time.Parse(time.RFC3339, opts.ObservedAt)
+		if err != nil {
+			return source, fmt.Errorf("invalid --observed-at: %w", err)
+		}
+		source.ObservedAt = observedAt.UTC()
+	}
+	return source, nil
 }
 
-// setupImporter creates an importer from the given CSV file and checks the header.
-func setupImporter(pathToCSV string) (*iplocation_importer.CSVImporter, error) {
-	f, err := os.Open(pathToCSV)
+// writeReport writes the report to --report-out or stdout. With JSON progress stdout is kept for the progress,
+// the last object of which is the summary.
+func writeReport(opts *options, progressMode import_progress.Mode, report import_report.Report) error {
+	if opts.ReportOut == "" {
+		if progressMode == import_progress.ModeJSON {
+			return nil
+		}
+		return import_report.Write(os.Stdout, report, import_report.Format(opts.Report)) //nolint:wrapcheck
+	}
+	f, err := os.Create(opts.ReportOut)
 	if err != nil {
-		return nil, fmt.Errorf("could not open file: %w", err)
+		return fmt.Errorf("could not create report file: %w", err)
+	}
+	if err = import_report.Write(f, report, import_report.Format(opts.Report)); err != nil {
+		_ = f.Close()
+		return err //nolint:wrapcheck
 	}
-	return iplocation_importer.NewCSVImporter(f) //nolint:wrapcheck
+	return f.Close() //nolint:wrapcheck
 }
 
-// setupStorage connects to the database and performs any necessary migrations.
-func setupStorage(ctx context.Context, opts *flags.Postgres) (*storage.IPLocationStorage, error) {
-	pool, err := storage.CreateConnectionPool(ctx, opts.PostgresConnectionString())
+// resolvePath returns the path to import, given by --path or its deprecated alias, but not both.
+func resolvePath(opts *options, logger *slog.Logger) (string, error) {
+	const defaultPath = "data_dump.csv"
+	if opts.PathToCSV == "" {
+		if opts.Path == "" {
+			return defaultPath, nil
+		}
+		return opts.Path, nil
+	}
+	if opts.Path != "" {
+		return "", errors.New("--path-to-csv or PATH_TO_CSV is set along with --path or PATH_TO_DATA, set only the latter")
+	}
+	logger.Warn("--path-to-csv and PATH_TO_CSV are deprecated, use --path or PATH_TO_DATA")
+	return opts.PathToCSV, nil
+}
+
+// setupImportOptions reports the progress in the mode, JSON to stdout and the bar to stderr.
+func setupImportOptions(
+	mode import_progress.Mode,
+	importer geolocation.IPLocationImporter,
+	interval time.Duration,
+	logger *slog.Logger,
+) (geolocation.ImportOptions, error) {
+	out := os.Stderr
+	if mode == import_progress.ModeJSON {
+		out = os.Stdout
+	}
+	report, err := import_progress.NewReporter(mode, out, interval, logger)
 	if err != nil {
-		return nil, fmt.Errorf("could not create connection pool: %w", err)
+		return geolocation.ImportOptions{}, fmt.Errorf("could not create progress reporter: %w", err)
 	}
-	s := storage.NewIPLocationStorage(pool)
-	const migrationsPath = "storage/migrations/iplocation" // TODO: move to config
-	if err := s.MigrateUp(ctx, migrationsPath); err != nil {
-		return nil, fmt.Errorf("could not migrate up: %w", err)
+	sizer, _ := importer.(geolocation.ImportSizer)
+	return geolocation.ImportOptions{Progress: report, Sizer: sizer}, nil
+}
+
+// ipLocationStorage - what the importer needs from any storage backend.
+type ipLocationStorage interface {
+	geolocation.IPLocationStorer
+	geolocation.DatasetRemover
+	geolocation.DatasetProfiler
+}
+
+// setupStorage opens the storage backend. PostgreSQL is connected to and migrated up.
+func setupStorage(
+	ctx context.Context,
+	opts *options,
+	logger *slog.Logger,
+) (s ipLocationStorage, closeStorage func(), err error) {
+	if opts.NormalizedSchema && opts.Partitioned {
+		return nil, nil, errors.New("--normalized-schema and --partitioned are mutually exclusive")
+	}
+	if opts.StorageBackend == flags.StorageBolt {
+		bolt, err := storage.NewBoltIPLocationStorage(opts.BoltPath, logger)
+		if err != nil {
+			return nil, nil, err //nolint:wrapcheck
+		}
+		return bolt, func() { _ = bolt.Close() }, nil
+	}
+	pool, err := storage.CreateConnectionPool(ctx, opts.PostgresConnectionString(), opts.PoolOptions(), logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create connection pool: %w", err)
+	}
+	pg := storage.NewIPLocationStorage(pool, logger).WithQueryTimeout(opts.PostgresQueryTimeout)
+	if err := pg.MigrateUp(ctx, storage.IPLocationMigrations()); err != nil {
+		pool.Close()
+		return nil, nil, fmt.Errorf("could not migrate up: %w", err)
+	}
+	if opts.NormalizedSchema {
+		pg = pg.WithNormalizedSchema()
+	}
+	if opts.Partitioned {
+		pg = pg.WithPartitionedLayout()
+	}
+	return pg, pool.Close, nil
+}
+
+// startMetricsPusher pushes metrics periodically until the returned function is called, which makes the final push.
+func startMetricsPusher(
+	ctx context.Context,
+	pusher *push.Pusher,
+	interval time.Duration,
+	logger *slog.Logger,
+) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := pusher.PushContext(ctx); err != nil {
+					logger.Warn("could not push metrics", "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		if err := pusher.Push(); err != nil {
+			logger.Warn("could not push metrics", "error", err)
+		}
 	}
-	return s, nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/dronnix/search-accomodation/internal/flags"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/storage"
+)
+
+type options struct {
+	GC gcCommand `command:"gc" description:"remove datasets expired by the retention policy"`
+	*flags.Retention
+	*flags.Storage
+	*flags.Postgres
+	*flags.Logging
+}
+
+type gcCommand struct {
+	DryRun bool `long:"dry-run" description:"only print the datasets which would be removed"`
+}
+
+const exitCodeOK = 0
+const exitCodeError = 1
+
+func main() {
+	os.Exit(_main())
+}
+
+func _main() int { // separate function to avoid "defer" in main
+	opts := &options{}
+	command := flags.ParseCommand(opts)
+
+	logger, err := logging.New(os.Stderr, opts.LoggingOptions()) // Stdout is used for the output.
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not setup logger: %v\n", err)
+		return exitCodeError
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	remover, closeStorage, err := setupStorage(ctx, opts, logger)
+	if err != nil {
+		logger.Error("could not setup storage", "error", err)
+		return exitCodeError
+	}
+	defer closeStorage()
+
+	if command == "gc" {
+		err = gc(ctx, os.Stdout, opts.RetentionPolicy(), opts.GC.DryRun, remover)
+	}
+	if err != nil {
+		logger.Error("could not run "+command+" command", "error", err)
+		return exitCodeError
+	}
+	return exitCodeOK
+}
+
+// setupStorage opens the storage backend, the schema of PostgreSQL is expected to be migrated.
+func setupStorage(
+	ctx context.Context,
+	opts *options,
+	logger *slog.Logger,
+) (geolocation.DatasetRemover, func(), error) {
+	if opts.StorageBackend == flags.StorageBolt {
+		bolt, err := storage.NewBoltIPLocationStorage(opts.BoltPath, logger)
+		if err != nil {
+			return nil, nil, err //nolint:wrapcheck
+		}
+		return bolt, func() { _ = bolt.Close() }, nil
+	}
+	pool, err := storage.CreateConnectionPool(ctx, opts.PostgresConnectionString(), opts.PoolOptions(), logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create connection pool: %w", err)
+	}
+	return storage.NewIPLocationStorage(pool, logger), pool.Close, nil
+}
+
+func gc(
+	ctx context.Context,
+	out io.Writer,
+	policy geolocation.RetentionPolicy,
+	dryRun bool,
+	remover geolocation.DatasetRemover,
+) error {
+	if !policy.Enabled() {
+		return errors.New("retention policy has no rules, set --retention-keep-last or --retention-keep-newer-than")
+	}
+	if dryRun {
+		datasets, err := remover.ListDatasets(ctx)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		for _, d := range policy.Expired(datasets, time.Now()) {
+			fmt.Fprintf(out, "Would remove dataset %d created at %s\n", d.Version, d.CreatedAt.UTC().Format(time.RFC3339))
+		}
+		return nil
+	}
+	report, err := geolocation.CollectGarbage(ctx, policy, remover, time.Now())
+	for _, d := range report.Removed {
+		fmt.Fprintf(out, "Removed dataset %d created at %s\n", d.Version, d.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(out, "Reclaimed %d bytes\n", report.ReclaimedBytes)
+	return err //nolint:wrapcheck
+}
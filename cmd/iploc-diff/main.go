@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/dronnix/search-accomodation/internal/diff_report"
+	"github.com/dronnix/search-accomodation/internal/flags"
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/storage"
+)
+
+type options struct {
+	OldVersion    int     `long:"old-version" description:"old dataset to compare, the active one if 0" default:"0" env:"DIFF_OLD_VERSION"`                                                            // nolint:lll
+	OldPath       string  `long:"old-path" description:"file to compare instead of the old dataset" env:"DIFF_OLD_PATH"`                                                                               // nolint:lll
+	NewVersion    int     `long:"new-version" description:"new dataset to compare, the active one if 0" default:"0" env:"DIFF_NEW_VERSION"`                                                            // nolint:lll
+	NewPath       string  `long:"new-path" description:"file to compare instead of the new dataset" env:"DIFF_NEW_PATH"`                                                                               // nolint:lll
+	Format        string  `long:"format" description:"format of the files, detected by extension if auto" default:"auto" choice:"auto" choice:"csv" choice:"jsonl" choice:"parquet" env:"DIFF_FORMAT"` // nolint:lll
+	MinDistanceKm float64 `long:"min-distance-km" description:"locations moved by this distance or less are not relocated" default:"0" env:"DIFF_MIN_DISTANCE_KM"`                                     // nolint:lll
+	Out           string  `long:"out" description:"file to write the differences as JSON Lines to, stdout if '-'" default:"-" env:"DIFF_OUT"`                                                          // nolint:lll
+	Summary       string  `long:"summary" description:"format of the summary written to stderr" default:"text" choice:"text" choice:"json" env:"DIFF_SUMMARY"`                                         // nolint:lll
+	*flags.Postgres
+	*flags.Logging
+}
+
+const exitCodeOK = 0
+const exitCodeError = 1
+
+func main() {
+	os.Exit(_main())
+}
+
+func _main() int { // separate function to avoid "defer" in main
+	opts := &options{}
+	flags.Parse(opts)
+
+	logger, err := logging.New(os.Stderr, opts.LoggingOptions()) // Stdout may be used for the output.
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not setup logger: %v\n", err)
+		return exitCodeError
+	}
+
+	if err = validateSides(opts); err != nil {
+		logger.Error("could not compare", "error", err)
+		return exitCodeError
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pool *pgxpool.Pool
+	if opts.OldPath == "" || opts.NewPath == "" {
+		pool, err = storage.CreateConnectionPool(ctx, opts.PostgresConnectionString(), opts.PoolOptions(), logger)
+		if err != nil {
+			logger.Error("could not create connection pool", "error", err)
+			return exitCodeError
+		}
+		defer pool.Close()
+	}
+	oldBatches, closeOld, err := setupBatches(opts.OldPath, opts.OldVersion, opts.Format, pool, logger)
+	if err != nil {
+		logger.Error("could not setup old IP locations", "error", err)
+		return exitCodeError
+	}
+	defer closeOld()
+	newBatches, closeNew, err := setupBatches(opts.NewPath, opts.NewVersion, opts.Format, pool, logger)
+	if err != nil {
+		logger.Error("could not setup new IP locations", "error", err)
+		return exitCodeError
+	}
+	defer closeNew()
+
+	out, closeOut, err := setupOutput(opts.Out)
+	if err != nil {
+		logger.Error("could not setup output", "error", err)
+		return exitCodeError
+	}
+	defer func() { _ = closeOut() }() // Closed once written, cleans up on errors only.
+
+	writer := diff_report.NewJSONLWriter(out)
+	summary, err := geolocation.DiffIPLocations(ctx, oldBatches, newBatches,
+		geolocation.DiffOptions{MinDistanceKm: opts.MinDistanceKm}, writer.Write)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		logger.Error("could not diff IP locations", "error", err)
+		return exitCodeError
+	}
+	if err = closeOut(); err != nil {
+		logger.Error("could not close output", "error", err)
+		return exitCodeError
+	}
+
+	if err = diff_report.WriteSummary(os.Stderr, summary, diff_report.Format(opts.Summary)); err != nil {
+		logger.Error("could not write summary", "error", err)
+		return exitCodeError
+	}
+	return exitCodeOK
+}
+
+// validateSides rejects comparing a dataset or a file with itself, the active dataset with itself by default.
+func validateSides(opts *options) error {
+	if opts.OldPath != opts.NewPath || (opts.OldPath == "" && opts.OldVersion != opts.NewVersion) {
+		return nil
+	}
+	if opts.OldPath != "" {
+		return fmt.Errorf("both sides are %s, set different --old-path and --new-path", opts.OldPath)
+	}
+	if opts.OldVersion == 0 {
+		return errors.New("both sides are the active dataset, set --old-version/--old-path or --new-version/--new-path")
+	}
+	return fmt.Errorf("both sides are dataset %d, set different --old-version and --new-version", opts.OldVersion)
+}
+
+// setupBatches opens the file if the path is set, otherwise reads the dataset of the version from the database.
+func setupBatches(
+	path string,
+	version int,
+	format string,
+	pool *pgxpool.Pool,
+	logger *slog.Logger,
+) (geolocation.IPLocationBatches, func(), error) {
+	if path == "" {
+		return geolocation.DatasetBatches(storage.NewIPLocationStorage(pool, logger), version), func() {}, nil
+	}
+	importer, closer, err := iplocation_importer.OpenFile(path, iplocation_importer.Format(format))
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+	return geolocation.ImporterBatches(importer), func() { _ = closer.Close() }, nil
+}
+
+// setupOutput opens the file to write the differences to, "-" means stdout.
+func setupOutput(path string) (out io.Writer, closeOut func() error, err error) {
+	if path == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create file: %w", err)
+	}
+	return f, f.Close, nil
+}
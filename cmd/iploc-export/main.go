@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dronnix/search-accomodation/internal/flags"
+	"github.com/dronnix/search-accomodation/internal/iplocation_exporter"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/storage"
+)
+
+type options struct {
+	Out            string `long:"out" description:"file to export to, stdout if '-'" default:"-" env:"EXPORT_OUT"`
+	Format         string `long:"format" description:"format of the export" default:"csv" choice:"csv" choice:"jsonl" env:"EXPORT_FORMAT"` // nolint:lll
+	CountryCode    string `long:"country-code" description:"export only locations of the country" env:"EXPORT_COUNTRY_CODE"`
+	DatasetVersion int    `long:"dataset-version" description:"dataset to export, the active one if 0" default:"0" env:"EXPORT_DATASET_VERSION"` // nolint:lll
+	*flags.Postgres
+	*flags.Logging
+}
+
+const exitCodeOK = 0
+const exitCodeError = 1
+
+func main() {
+	os.Exit(_main())
+}
+
+func _main() int { // separate function to avoid "defer" in main
+	opts := &options{}
+	flags.Parse(opts)
+
+	logger, err := logging.New(os.Stderr, opts.LoggingOptions()) // Stdout may be used for the output.
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not setup logger: %v\n", err)
+		return exitCodeError
+	}
+
+	out, closeOut, err := setupOutput(opts.Out)
+	if err != nil {
+		logger.Error("could not setup output", "error", err)
+		return exitCodeError
+	}
+	defer func() { _ = closeOut() }() // Closed once written, cleans up on errors only.
+
+	exporter, err := iplocation_exporter.NewExporter(iplocation_exporter.Format(opts.Format), out)
+	if err != nil {
+		logger.Error("could not setup exporter", "error", err)
+		return exitCodeError
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := storage.CreateConnectionPool(ctx, opts.PostgresConnectionString(), opts.PoolOptions(), logger)
+	if err != nil {
+		logger.Error("could not create connection pool", "error", err)
+		return exitCodeError
+	}
+	defer pool.Close()
+
+	filter := geolocation.ExportFilter{CountryCode: opts.CountryCode, DatasetVersion: opts.DatasetVersion}
+	n, err := geolocation.ExportIPLocations(ctx, filter, storage.NewIPLocationStorage(pool, logger), exporter)
+	if err != nil {
+		logger.Error("could not export IP locations", "error", err)
+		return exitCodeError
+	}
+	if err = closeOut(); err != nil {
+		logger.Error("could not close output", "error", err)
+		return exitCodeError
+	}
+
+	logger.Info("export finished", "records", n)
+	return exitCodeOK
+}
+
+// setupOutput opens the file to export to, "-" means stdout.
+func setupOutput(path string) (out io.Writer, closeOut func() error, err error) {
+	if path == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create file: %w", err)
+	}
+	return f, f.Close, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/dronnix/search-accomodation/internal/flags"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/storage"
+)
+
+type options struct {
+	Up      struct{}    `command:"up" description:"apply all migrations not applied yet"`
+	Down    downCommand `command:"down" description:"roll migrations back down to the version"`
+	Status  struct{}    `command:"status" description:"list migrations and whether they are applied"`
+	Version struct{}    `command:"version" description:"print the current schema version"`
+	*flags.Postgres
+	*flags.Logging
+}
+
+type downCommand struct {
+	To int32 `long:"to" description:"version to roll back to, 0 rolls all migrations back" required:"true"`
+}
+
+const exitCodeOK = 0
+const exitCodeError = 1
+
+func main() {
+	os.Exit(_main())
+}
+
+func _main() int { // separate function to avoid "defer" in main
+	opts := &options{}
+	command := flags.ParseCommand(opts)
+
+	logger, err := logging.New(os.Stderr, opts.LoggingOptions()) // Stdout is used for the output.
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not setup logger: %v\n", err)
+		return exitCodeError
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := storage.CreateConnectionPool(ctx, opts.PostgresConnectionString(), opts.PoolOptions(), logger)
+	if err != nil {
+		logger.Error("could not create connection pool", "error", err)
+		return exitCodeError
+	}
+	defer pool.Close()
+
+	switch command {
+	case "up":
+		err = up(ctx, os.Stdout, pool)
+	case "down":
+		err = down(ctx, os.Stdout, pool, opts.Down.To)
+	case "status":
+		err = status(ctx, os.Stdout, pool)
+	case "version":
+		err = version(ctx, os.Stdout, pool)
+	}
+	if err != nil {
+		logger.Error("could not run "+command+" command", "error", err)
+		return exitCodeError
+	}
+	return exitCodeOK
+}
+
+func up(ctx context.Context, out io.Writer, pool *pgxpool.Pool) error {
+	version, err := storage.Migrate(ctx, pool, storage.IPLocationMigrations())
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	fmt.Fprintf(out, "Migrated up to version %d\n", version)
+	return nil
+}
+
+func down(ctx context.Context, out io.Writer, pool *pgxpool.Pool, to int32) error {
+	migrations := storage.IPLocationMigrations()
+	status, err := storage.FetchMigrationStatus(ctx, pool, migrations)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if to > status.Current {
+		return fmt.Errorf("version %d is above the current one %d, use up to migrate up", to, status.Current)
+	}
+	if err = storage.MigrateTo(ctx, pool, migrations, to); err != nil {
+		return err //nolint:wrapcheck
+	}
+	fmt.Fprintf(out, "Migrated down from version %d to %d\n", status.Current, to)
+	return nil
+}
+
+func status(ctx context.Context, out io.Writer, pool *pgxpool.Pool) error {
+	status, err := storage.FetchMigrationStatus(ctx, pool, storage.IPLocationMigrations())
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	for i, name := range status.Migrations {
+		state := "pending"
+		if int32(i) < status.Current {
+			state = "applied"
+		}
+		fmt.Fprintf(out, "%-8s %s\n", state, name)
+	}
+	fmt.Fprintf(out, "Version: %d of %d\n", status.Current, status.Latest())
+	return nil
+}
+
+func version(ctx context.Context, out io.Writer, pool *pgxpool.Pool) error {
+	status, err := storage.FetchMigrationStatus(ctx, pool, storage.IPLocationMigrations())
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	fmt.Fprintln(out, status.Current)
+	return nil
+}
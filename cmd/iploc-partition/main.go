@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dronnix/search-accomodation/internal/flags"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/storage"
+)
+
+type options struct {
+	List   struct{}         `command:"list" description:"list partitions of datasets, detached ones included"`
+	Attach partitionCommand `command:"attach" description:"attach the detached partition of the dataset back"`
+	Detach partitionCommand `command:"detach" description:"detach the partition of the inactive dataset, keeping it"`
+	Drop   partitionCommand `command:"drop" description:"drop the partition of the inactive dataset and the dataset"`
+	*flags.Postgres
+	*flags.Logging
+}
+
+type partitionCommand struct {
+	Version int `long:"version" description:"version of the dataset" required:"true"`
+}
+
+const exitCodeOK = 0
+const exitCodeError = 1
+
+func main() {
+	os.Exit(_main())
+}
+
+func _main() int { // separate function to avoid "defer" in main
+	opts := &options{}
+	command := flags.ParseCommand(opts)
+
+	logger, err := logging.New(os.Stderr, opts.LoggingOptions()) // Stdout is used for the output.
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not setup logger: %v\n", err)
+		return exitCodeError
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := storage.CreateConnectionPool(ctx, opts.PostgresConnectionString(), opts.PoolOptions(), logger)
+	if err != nil {
+		logger.Error("could not create connection pool", "error", err)
+		return exitCodeError
+	}
+	defer pool.Close()
+	s := storage.NewIPLocationStorage(pool, logger)
+
+	switch command {
+	case "list":
+		err = list(ctx, os.Stdout, s)
+	case "attach":
+		err = s.AttachPartition(ctx, opts.Attach.Version)
+	case "detach":
+		err = s.DetachPartition(ctx, opts.Detach.Version)
+	case "drop":
+		err = s.DropPartition(ctx, opts.Drop.Version)
+	}
+	if err != nil {
+		logger.Error("could not "+command+" partition", "error", err)
+		return exitCodeError
+	}
+	return exitCodeOK
+}
+
+func list(ctx context.Context, out io.Writer, s *storage.IPLocationStorage) error {
+	partitions, err := s.ListPartitions(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATASET\tTABLE\tATTACHED\tBYTES")
+	for _, p := range partitions {
+		fmt.Fprintf(w, "%d\t%s\t%t\t%d\n", p.DatasetVersion, p.Table, p.Attached, p.Bytes)
+	}
+	return w.Flush() //nolint:wrapcheck
+}
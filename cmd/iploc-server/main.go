@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,16 +14,66 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
 
 	"github.com/dronnix/search-accomodation/api"
+	"github.com/dronnix/search-accomodation/api/adminapi"
+	"github.com/dronnix/search-accomodation/api/geolocationpb"
+	"github.com/dronnix/search-accomodation/internal/admin_api"
+	"github.com/dronnix/search-accomodation/internal/apikey_auth"
 	"github.com/dronnix/search-accomodation/internal/flags"
+	"github.com/dronnix/search-accomodation/internal/health"
+	"github.com/dronnix/search-accomodation/internal/import_jobs"
 	"github.com/dronnix/search-accomodation/internal/iplocation_api"
+	"github.com/dronnix/search-accomodation/internal/iplocation_cache"
+	"github.com/dronnix/search-accomodation/internal/iplocation_grpc"
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/internal/metrics"
+	"github.com/dronnix/search-accomodation/internal/tracing"
+	"github.com/dronnix/search-accomodation/model/geolocation"
 	"github.com/dronnix/search-accomodation/storage"
 )
 
 type options struct {
 	HTTPPort int `long:"http-port" default:"8080" env:"HTTP_PORT"`
+	GRPCPort int `long:"grpc-port" default:"9090" env:"GRPC_PORT"`
+	// Load balancers need some time to notice the server is not ready anymore.
+	DrainDelay time.Duration `long:"drain-delay" description:"time between failing readiness and shutdown" default:"5s" env:"DRAIN_DELAY"` // nolint:lll
+
+	AutoMigrate bool `long:"auto-migrate" description:"migrate the database schema up on startup, otherwise run iploc-migrate" env:"AUTO_MIGRATE"` // nolint:lll
+
+	HTTPCacheMaxAge time.Duration `long:"http-cache-max-age" description:"Cache-Control max-age of found locations, revalidation by ETag only if 0" default:"1h" env:"HTTP_CACHE_MAX_AGE"` // nolint:lll
+
+	AuthDisabled   bool          `long:"auth-disabled" description:"serve the API without API keys" env:"AUTH_DISABLED"`
+	APIKeyCacheTTL time.Duration `long:"api-key-cache-ttl" description:"how long API keys are cached, revoked keys are accepted until it expires" default:"1m" env:"API_KEY_CACHE_TTL"` // nolint:lll
+
+	AdminDataDir            string        `long:"admin-data-dir" description:"directory for uploaded and downloaded import sources" default:"data" env:"ADMIN_DATA_DIR"`                       // nolint:lll
+	ImportPollInterval      time.Duration `long:"import-poll-interval" description:"how often import jobs submitted to other servers are looked for" default:"10s" env:"IMPORT_POLL_INTERVAL"` // nolint:lll
+	ImportHeartbeatInterval time.Duration `long:"import-heartbeat-interval" description:"how often the progress of the running import is saved" default:"5s" env:"IMPORT_HEARTBEAT_INTERVAL"`  // nolint:lll
+	ImportStaleAfter        time.Duration `long:"import-stale-after" description:"running import jobs not updated for this long are run again" default:"1m" env:"IMPORT_STALE_AFTER"`          // nolint:lll
+	ImportNormalizedSchema  bool          `long:"import-normalized-schema" description:"store imported datasets to the normalized schema" env:"IMPORT_NORMALIZED_SCHEMA"`                      // nolint:lll
+	ImportPartitioned       bool          `long:"import-partitioned" description:"store imported datasets to partitions of their own" env:"IMPORT_PARTITIONED"`                                // nolint:lll
+
+	LookupEmbeddedIPv4 bool `long:"lookup-embedded-ipv4" description:"look 6to4 and Teredo addresses up by their embedded IPv4 address" env:"LOOKUP_EMBEDDED_IPV4"` // nolint:lll
+
+	CacheSize         int           `long:"cache-size" description:"max number of cached IP addresses, no cache if 0" default:"100000" env:"CACHE_SIZE"`                               // nolint:lll
+	CacheTTL          time.Duration `long:"cache-ttl" description:"how long found locations are cached" default:"10m" env:"CACHE_TTL"`                                                 // nolint:lll
+	CacheNegativeTTL  time.Duration `long:"cache-negative-ttl" description:"how long not found IPs are cached, not cached if 0" default:"1m" env:"CACHE_NEGATIVE_TTL"`                 // nolint:lll
+	CachePollInterval time.Duration `long:"cache-poll-interval" description:"how often the active dataset is checked to invalidate the cache" default:"10s" env:"CACHE_POLL_INTERVAL"` // nolint:lll
+
+	GCInterval time.Duration `long:"gc-interval" description:"how often datasets expired by the retention policy are removed, never if 0" env:"GC_INTERVAL"` // nolint:lll
+	*flags.Storage
 	*flags.Postgres
+	*flags.ReadReplicas
+	*flags.QualityGates
+	*flags.Retention
+	*flags.Logging
+	*flags.Tracing
 }
 
 const exitCodeOK = 0
@@ -35,58 +87,328 @@ func _main() int { // separate function to avoid "defer" in main
 	opts := &options{}
 	flags.Parse(opts)
 
+	logger, err := logging.New(os.Stdout, opts.LoggingOptions())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not setup logger: %v\n", err)
+		return exitCodeError
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	storage, err := setupStorage(ctx, opts.Postgres)
+	shutdownTracing, err := tracing.Setup(ctx, "iploc-server", opts.TracingOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "could not setup storage: %v\n", err)
+		logger.Error("could not setup tracing", "error", err)
+		return exitCodeError
+	}
+	defer func() {
+		if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+			logger.Warn("could not flush spans", "error", shutdownErr)
+		}
+	}()
+
+	gates, err := opts.QualityGatesOptions()
+	if err == nil {
+		gates, err = iplocation_importer.LoadQualityGates(gates, opts.CountryCentroids)
+	}
+	if err != nil {
+		logger.Error("could not setup quality gates", "error", err)
 		return exitCodeError
 	}
 
-	ipLocSrv := iplocation_api.NewIpLocationServer(storage)
-	httpServer := setupHTTPServer(opts, ipLocSrv)
+	s, pool, closeStorage, err := setupStorage(ctx, opts, logger)
+	if err != nil {
+		logger.Error("could not setup storage", "error", err)
+		return exitCodeError
+	}
+	defer closeStorage()
 
-	setupSignalHandler(ctx, cancel, httpServer) // Gracefully shutdown on SIGINT/SIGTERM.
+	registry, srvMetrics := setupMetrics(pool)
+	fetcher := setupFetcher(ctx, opts, s, registry, logger)
+	checker := setupHealthChecker(s, logger)
+	auth := setupAuthenticator(opts, pool, logger)
+	ipLocSrv := iplocation_api.NewIpLocationServer(fetcher, s, srvMetrics, logger, opts.HTTPCacheMaxAge)
+	adminSrv, importsDone := setupAdminServer(ctx, opts, gates, pool, s, auth, logger)
+	setupGarbageCollector(ctx, opts, s, logger)
+	httpServer := setupHTTPServer(opts, ipLocSrv, adminSrv, checker, auth, registry, srvMetrics, logger)
+	grpcServer := setupGRPCServer(iplocation_grpc.NewIPLocationServer(fetcher, s, srvMetrics, logger),
+		auth, srvMetrics, logger)
 
+	// Gracefully shutdown on SIGINT/SIGTERM.
+	setupSignalHandler(ctx, cancel, opts.DrainDelay, checker, httpServer, grpcServer, logger)
+
+	grpcErr := make(chan error, 1)
+	go func() {
+		err := serveGRPC(grpcServer, opts.GRPCPort)
+		if err != nil {
+			_ = httpServer.Shutdown(ctx) // Don't serve a half of the API.
+		}
+		grpcErr <- err
+	}()
+
+	logger.Info("server started", "http_port", opts.HTTPPort, "grpc_port", opts.GRPCPort)
 	if err = httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		fmt.Fprintf(os.Stderr, "server error: %v\n", err)
+		logger.Error("server error", "error", err)
 		return exitCodeError
 	}
+	if err = <-grpcErr; err != nil {
+		logger.Error("grpc server error", "error", err)
+		return exitCodeError
+	}
+	cancel() // The running import job is put back to pending.
+	<-importsDone
 
-	fmt.Fprintln(os.Stdout, "server stopped")
+	logger.Info("server stopped")
 	return exitCodeOK
 }
 
-// setupStorage connects to the database and performs any necessary migrations.
-func setupStorage(ctx context.Context, opts *flags.Postgres) (*storage.IPLocationStorage, error) {
-	pool, err := storage.CreateConnectionPool(ctx, opts.PostgresConnectionString())
+// ipLocationStorage - what the server needs from any storage backend.
+type ipLocationStorage interface {
+	geolocation.IPLocationFetcher
+	geolocation.IPLocationLister
+	geolocation.DatasetFetcher
+	geolocation.DatasetRemover
+	Ping(ctx context.Context) error
+}
+
+// setupStorage opens the storage backend. PostgreSQL is connected to, and migrated up if asked to, lookups are read
+// from the read replicas if any. The pool is of the primary, nil with other backends.
+func setupStorage(
+	ctx context.Context,
+	opts *options,
+	logger *slog.Logger,
+) (s ipLocationStorage, pool *pgxpool.Pool, closeStorage func(), err error) {
+	if opts.ImportNormalizedSchema && opts.ImportPartitioned {
+		return nil, nil, nil, errors.New("--import-normalized-schema and --import-partitioned are mutually exclusive")
+	}
+	if opts.StorageBackend == flags.StorageBolt {
+		if !opts.AuthDisabled {
+			return nil, nil, nil, errors.New("API keys are stored in PostgreSQL, --auth-disabled is required by bolt storage")
+		}
+		bolt, err := storage.NewBoltIPLocationStorage(opts.BoltPath, logger)
+		if err != nil {
+			return nil, nil, nil, err //nolint:wrapcheck
+		}
+		return bolt, nil, func() { _ = bolt.Close() }, nil
+	}
+	pool, err = storage.CreateConnectionPool(ctx, opts.PostgresConnectionString(), opts.PoolOptions(), logger)
 	if err != nil {
-		return nil, fmt.Errorf("could not create connection pool: %w", err)
+		return nil, nil, nil, fmt.Errorf("could not create connection pool: %w", err)
+	}
+	pg := storage.NewIPLocationStorage(pool, logger).WithQueryTimeout(opts.PostgresQueryTimeout)
+	if opts.AutoMigrate {
+		if err = pg.MigrateUp(ctx, storage.IPLocationMigrations()); err != nil {
+			pool.Close()
+			return nil, nil, nil, fmt.Errorf("could not migrate up: %w", err)
+		}
 	}
-	s := storage.NewIPLocationStorage(pool)
-	const migrationsPath = "storage/migrations/iplocation" // TODO: move to config
-	if err := s.MigrateUp(ctx, migrationsPath); err != nil {
-		return nil, fmt.Errorf("could not migrate up: %w", err)
+	if len(opts.PostgresReadDSNs) == 0 {
+		return pg, pool, pool.Close, nil
 	}
-	return s, nil
+	replicas, err := storage.ConnectReplicas(ctx, pool, opts.PostgresReadDSNs, opts.PoolOptions(), logger)
+	if err != nil {
+		pool.Close()
+		return nil, nil, nil, fmt.Errorf("could not setup read replicas: %w", err)
+	}
+	go replicas.Run(ctx, opts.PostgresReadHealthInterval)
+	return pg.WithReplicas(replicas), pool, func() {
+		replicas.Close()
+		pool.Close()
+	}, nil
+}
+
+// setupMetrics creates the registry with server, connection pool (if any) and runtime metrics.
+func setupMetrics(pool *pgxpool.Pool) (*prometheus.Registry, *metrics.Server) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if pool != nil {
+		registry.MustRegister(metrics.NewPgxPoolCollector(pool))
+	}
+	return registry, metrics.NewServer(registry)
+}
+
+// setupFetcher puts the cache in front of the storage if enabled.
+func setupFetcher(
+	ctx context.Context,
+	opts *options,
+	s ipLocationStorage,
+	registry *prometheus.Registry,
+	logger *slog.Logger,
+) geolocation.IPLocationFetcher {
+	var fetcher geolocation.IPLocationFetcher = s
+	if opts.CacheSize > 0 {
+		cache := iplocation_cache.New(s, iplocation_cache.Options{
+			Size:        opts.CacheSize,
+			TTL:         opts.CacheTTL,
+			NegativeTTL: opts.CacheNegativeTTL,
+		})
+		go cache.WatchDataset(ctx, s, opts.CachePollInterval, logger)
+		registry.MustRegister(metrics.NewCacheCollector(cache))
+		fetcher = cache
+	}
+	if opts.LookupEmbeddedIPv4 { // Outside the cache, so addresses embedding the same IPv4 share the entry.
+		fetcher = geolocation.EmbeddedIPv4Fetcher{IPLocationFetcher: fetcher}
+	}
+	return fetcher
+}
+
+// setupHealthChecker creates readiness checks of the database and the loaded data.
+func setupHealthChecker(s ipLocationStorage, logger *slog.Logger) *health.Checker {
+	const checkTimeout = 2 * time.Second
+	checker := health.NewChecker(checkTimeout, logger)
+	checker.Add("database", s.Ping)
+	if pg, ok := s.(*storage.IPLocationStorage); ok {
+		migrations := storage.IPLocationMigrations()
+		checker.Add("migrations", func(ctx context.Context) error { return pg.CheckMigrations(ctx, migrations) })
+	}
+	checker.Add("active_dataset", health.ActiveDatasetCheck(s))
+	return checker
+}
+
+// setupAuthenticator returns nil if the API is served without API keys.
+func setupAuthenticator(opts *options, pool *pgxpool.Pool, logger *slog.Logger) *apikey_auth.Authenticator {
+	if opts.AuthDisabled {
+		logger.Warn("API keys are not required")
+		return nil
+	}
+	return apikey_auth.NewAuthenticator(storage.NewAPIKeyStorage(pool, logger), opts.APIKeyCacheTTL, logger)
+}
+
+// setupGarbageCollector removes datasets expired by the retention policy in background, if asked to.
+func setupGarbageCollector(ctx context.Context, opts *options, backend ipLocationStorage, logger *slog.Logger) {
+	if opts.GCInterval <= 0 {
+		return
+	}
+	policy := opts.RetentionPolicy()
+	if !policy.Enabled() {
+		logger.Warn("garbage collection is disabled as the retention policy has no rules")
+		return
+	}
+	var remover geolocation.DatasetRemover = backend
+	if pg, ok := backend.(*storage.IPLocationStorage); ok {
+		remover = pg.Primary() // Datasets activated meanwhile are seen.
+	}
+	go collectGarbage(ctx, policy, remover, opts.GCInterval, logger)
+}
+
+// collectGarbage removes expired datasets every interval, until the context is done.
+func collectGarbage(
+	ctx context.Context,
+	policy geolocation.RetentionPolicy,
+	remover geolocation.DatasetRemover,
+	interval time.Duration,
+	logger *slog.Logger,
+) {
+	logger.Info("garbage collection started", "policy", policy.String(), "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		report, err := geolocation.CollectGarbage(ctx, policy, remover, time.Now())
+		if err != nil {
+			logger.WarnContext(ctx, "could not collect garbage", "error", err)
+		}
+		if len(report.Removed) > 0 {
+			logger.InfoContext(ctx, "garbage collected", "removed_datasets", len(report.Removed),
+				"reclaimed_bytes", report.ReclaimedBytes)
+		}
+	}
+}
+
+// setupAdminServer starts the import jobs runner, and returns the admin API server and a channel closed once
+// the runner is stopped by the context. The admin API requires API keys, so it is disabled (nil) without them.
+func setupAdminServer(
+	ctx context.Context,
+	opts *options,
+	gates geolocation.QualityGates,
+	pool *pgxpool.Pool,
+	backend ipLocationStorage,
+	auth *apikey_auth.Authenticator,
+	logger *slog.Logger,
+) (*admin_api.AdminServer, <-chan struct{}) {
+	done := make(chan struct{})
+	if auth == nil {
+		logger.Warn("admin API is disabled as API keys are not required")
+		close(done)
+		return nil, done
+	}
+	// API keys are stored in PostgreSQL, so is the rest of the admin API. It reads its own writes.
+	s := backend.(*storage.IPLocationStorage).Primary()
+	jobs := storage.NewImportJobStorage(pool, logger)
+	storer := s
+	if opts.ImportNormalizedSchema {
+		storer = s.WithNormalizedSchema()
+	}
+	if opts.ImportPartitioned {
+		storer = s.WithPartitionedLayout()
+	}
+	runner := import_jobs.NewRunner(jobs, storer, import_jobs.Options{
+		DataDir:           opts.AdminDataDir,
+		PollInterval:      opts.ImportPollInterval,
+		HeartbeatInterval: opts.ImportHeartbeatInterval,
+		StaleAfter:        opts.ImportStaleAfter,
+		Gates:             gates,
+		Profiler:          s,
+		Remover:           s,
+	}, logger)
+	go func() {
+		defer close(done)
+		runner.Run(ctx)
+	}()
+	return admin_api.NewAdminServer(runner, jobs, s, logger), done
 }
 
 // setupHTTPServer creates and configures the HTTP server and router.
-func setupHTTPServer(opts *options, ipLocSrv *iplocation_api.IPLocationServer) *http.Server {
+func setupHTTPServer(
+	opts *options,
+	ipLocSrv *iplocation_api.IPLocationServer,
+	adminSrv *admin_api.AdminServer,
+	checker *health.Checker,
+	auth *apikey_auth.Authenticator,
+	registry *prometheus.Registry,
+	srvMetrics *metrics.Server,
+	logger *slog.Logger,
+) *http.Server {
 	router := chi.NewRouter()
-	router.Use(
-		middleware.Logger,
-		middleware.SetHeader("Content-Type", "application/json"),
-		middleware.Heartbeat("/ping"),
-		middleware.Recoverer,
-	)
+	// Probes are frequent, so they are neither logged nor traced.
+	router.Get("/healthz", checker.LivenessHandler)
+	router.Get("/ping", checker.LivenessHandler) // The former heartbeat, kept for existing probes.
+	router.Get("/readyz", checker.ReadinessHandler)
 
-	router.Mount("/", api.Handler(ipLocSrv))
+	router.Group(func(r chi.Router) {
+		r.Use(
+			tracing.HTTPMiddleware,
+			logging.HTTPMiddleware(logger),
+			srvMetrics.HTTPMiddleware,
+			middleware.Recoverer,
+		)
+		r.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+		if adminSrv != nil {
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.SetHeader("Content-Type", "application/json"), auth.AdminHTTPMiddleware)
+				adminapi.HandlerFromMux(adminSrv, r)
+			})
+		}
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.SetHeader("Content-Type", "application/json"))
+			if auth != nil {
+				r.Use(auth.HTTPMiddleware)
+			}
+			r.Mount("/", api.Handler(ipLocSrv))
+		})
+	})
 
 	return &http.Server{
-		Handler: router,
-		Addr:    fmt.Sprintf(":%d", opts.HTTPPort),
+		Handler:  router,
+		Addr:     fmt.Sprintf(":%d", opts.HTTPPort),
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
 
 		ReadTimeout:    time.Second,
 		WriteTimeout:   time.Second,
@@ -95,14 +417,61 @@ func setupHTTPServer(opts *options, ipLocSrv *iplocation_api.IPLocationServer) *
 	}
 }
 
-func setupSignalHandler(ctx context.Context, cancel func(), apiSrv *http.Server) {
+// setupGRPCServer creates the gRPC server with the same logging, metrics and authentication as HTTP one.
+func setupGRPCServer(
+	ipLocSrv *iplocation_grpc.IPLocationServer,
+	auth *apikey_auth.Authenticator,
+	srvMetrics *metrics.Server,
+	logger *slog.Logger,
+) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{
+		tracing.UnaryServerInterceptor(),
+		iplocation_grpc.LoggingUnaryInterceptor(logger),
+		srvMetrics.UnaryInterceptor(),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		tracing.StreamServerInterceptor(),
+		iplocation_grpc.LoggingStreamInterceptor(logger),
+		srvMetrics.StreamInterceptor(),
+	}
+	if auth != nil {
+		unary = append(unary, auth.UnaryInterceptor())
+		stream = append(stream, auth.StreamInterceptor())
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	geolocationpb.RegisterGeolocationServiceServer(server, ipLocSrv)
+	return server
+}
+
+func serveGRPC(server *grpc.Server, port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("could not listen: %w", err)
+	}
+	return server.Serve(listener) //nolint:wrapcheck
+}
+
+// setupSignalHandler fails readiness on SIGINT/SIGTERM, and shuts down the servers after drainDelay.
+func setupSignalHandler(
+	ctx context.Context,
+	cancel func(),
+	drainDelay time.Duration,
+	checker *health.Checker,
+	apiSrv *http.Server,
+	grpcSrv *grpc.Server,
+	logger *slog.Logger,
+) {
 	quitChan := make(chan os.Signal, 1)
 	signal.Ignore(syscall.SIGHUP, syscall.SIGPIPE)
 	signal.Notify(quitChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-quitChan
+		checker.StartDraining()
+		logger.Info("draining", "delay", drainDelay)
+		time.Sleep(drainDelay)
+		grpcSrv.GracefulStop()
 		if err := apiSrv.Shutdown(ctx); err != nil {
-			fmt.Fprintf(os.Stderr, "unable to gracefully shutdown api server")
+			logger.Error("unable to gracefully shutdown api server", "error", err)
 		}
 		cancel()
 	}()
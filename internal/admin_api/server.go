@@ -0,0 +1,262 @@
+package admin_api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dronnix/search-accomodation/api/adminapi"
+	"github.com/dronnix/search-accomodation/internal/import_jobs"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// ImportRunner - runs import jobs in the background, see import_jobs.Runner.
+type ImportRunner interface {
+	Upload(name string, body io.Reader) (string, error)
+	Submit(ctx context.Context, source, format string) (geolocation.ImportJob, error)
+	Cancel(ctx context.Context, id int) (geolocation.ImportJob, error)
+}
+
+// ImportJobFetcher - interface for reading import jobs, see geolocation.ImportJobStorer.
+type ImportJobFetcher interface {
+	FetchImportJob(ctx context.Context, id int) (geolocation.ImportJob, error)
+	ListImportJobs(ctx context.Context, limit int) ([]geolocation.ImportJob, error)
+}
+
+const defaultJobsLimit = 100
+
+// AdminServer is handler-implementation for auto-generated admin API stub.
+type AdminServer struct {
+	runner   ImportRunner
+	jobs     ImportJobFetcher
+	datasets geolocation.DatasetManager
+	logger   *slog.Logger
+}
+
+func NewAdminServer(
+	runner ImportRunner,
+	jobs ImportJobFetcher,
+	datasets geolocation.DatasetManager,
+	logger *slog.Logger,
+) *AdminServer {
+	return &AdminServer{runner: runner, jobs: jobs, datasets: datasets, logger: logger}
+}
+
+// PutAdminV1UploadsName is handler-implementation for auto-generated API stub.
+func (s *AdminServer) PutAdminV1UploadsName(w http.ResponseWriter, r *http.Request, name string) {
+	// Uploads take much longer than usual requests, so the server read timeout is not applicable.
+	_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+	source, err := s.runner.Upload(name, r.Body)
+	if err != nil {
+		s.sendError(r.Context(), w, "could not upload file", err)
+		return
+	}
+	s.logger.InfoContext(r.Context(), "file uploaded", "source", source)
+	s.sendResponse(http.StatusCreated, w, adminapi.Upload{Source: source})
+}
+
+// PostAdminV1Imports is handler-implementation for auto-generated API stub.
+func (s *AdminServer) PostAdminV1Imports(w http.ResponseWriter, r *http.Request) {
+	var req adminapi.ImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendResponse(http.StatusBadRequest, w, adminapi.Error{ErrorDetails: "Malformed request body"})
+		return
+	}
+	var format string
+	if req.Format != nil {
+		format = string(*req.Format)
+	}
+	job, err := s.runner.Submit(r.Context(), req.Source, format)
+	if err != nil {
+		s.sendError(r.Context(), w, "could not submit import job", err)
+		return
+	}
+	s.logger.InfoContext(r.Context(), "import job submitted", "import_job_id", job.ID, "source", job.Source)
+	s.sendResponse(http.StatusAccepted, w, importJob(job))
+}
+
+// GetAdminV1Imports is handler-implementation for auto-generated API stub.
+func (s *AdminServer) GetAdminV1Imports(
+	w http.ResponseWriter,
+	r *http.Request,
+	params adminapi.GetAdminV1ImportsParams,
+) {
+	limit := defaultJobsLimit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	if limit < 1 || limit > 1000 {
+		s.sendResponse(http.StatusBadRequest, w, adminapi.Error{ErrorDetails: "Invalid limit"})
+		return
+	}
+	jobs, err := s.jobs.ListImportJobs(r.Context(), limit)
+	if err != nil {
+		s.sendError(r.Context(), w, "could not list import jobs", err)
+		return
+	}
+	resp := make([]adminapi.ImportJob, len(jobs))
+	for i := range jobs {
+		resp[i] = importJob(jobs[i])
+	}
+	s.sendResponse(http.StatusOK, w, resp)
+}
+
+// GetAdminV1ImportsId is handler-implementation for auto-generated API stub.
+func (s *AdminServer) GetAdminV1ImportsId(w http.ResponseWriter, r *http.Request, id adminapi.JobId) {
+	job, err := s.jobs.FetchImportJob(r.Context(), int(id))
+	if err != nil {
+		s.sendError(r.Context(), w, "could not fetch import job", err)
+		return
+	}
+	s.sendResponse(http.StatusOK, w, importJob(job))
+}
+
+// PostAdminV1ImportsIdCancel is handler-implementation for auto-generated API stub.
+func (s *AdminServer) PostAdminV1ImportsIdCancel(w http.ResponseWriter, r *http.Request, id adminapi.JobId) {
+	job, err := s.runner.Cancel(r.Context(), int(id))
+	if err != nil {
+		s.sendError(r.Context(), w, "could not cancel import job", err)
+		return
+	}
+	s.logger.InfoContext(r.Context(), "import job cancellation requested", "import_job_id", job.ID)
+	s.sendResponse(http.StatusAccepted, w, importJob(job))
+}
+
+// GetAdminV1Datasets is handler-implementation for auto-generated API stub.
+func (s *AdminServer) GetAdminV1Datasets(w http.ResponseWriter, r *http.Request) {
+	datasets, err := s.datasets.ListDatasets(r.Context())
+	if err != nil {
+		s.sendError(r.Context(), w, "could not list datasets", err)
+		return
+	}
+	resp := make([]adminapi.Dataset, len(datasets))
+	for i := range datasets {
+		resp[i] = dataset(datasets[i])
+	}
+	s.sendResponse(http.StatusOK, w, resp)
+}
+
+// PostAdminV1DatasetsVersionActivate is handler-implementation for auto-generated API stub.
+func (s *AdminServer) PostAdminV1DatasetsVersionActivate(w http.ResponseWriter, r *http.Request, version int) {
+	if version <= 0 {
+		s.sendResponse(http.StatusBadRequest, w, adminapi.Error{ErrorDetails: "Invalid dataset version"})
+		return
+	}
+	activated, err := geolocation.ReactivateDataset(r.Context(), version, s.datasets)
+	if err != nil {
+		s.sendError(r.Context(), w, "could not activate dataset", err)
+		return
+	}
+	s.sendResponse(http.StatusOK, w, dataset(activated))
+}
+
+// PostAdminV1DatasetsRollback is handler-implementation for auto-generated API stub.
+func (s *AdminServer) PostAdminV1DatasetsRollback(w http.ResponseWriter, r *http.Request) {
+	activated, err := geolocation.RollbackDataset(r.Context(), s.datasets)
+	if err != nil {
+		s.sendError(r.Context(), w, "could not roll back dataset", err)
+		return
+	}
+	s.sendResponse(http.StatusOK, w, dataset(activated))
+}
+
+// sendError responds with the error if it is caused by the client, otherwise logs it and responds with a generic one.
+func (s *AdminServer) sendError(ctx context.Context, w http.ResponseWriter, msg string, err error) {
+	for _, e := range []struct {
+		err  error
+		code int
+	}{
+		{import_jobs.ErrInvalidSource, http.StatusBadRequest},
+		{import_jobs.ErrInvalidFormat, http.StatusBadRequest},
+		{geolocation.ErrImportJobNotFound, http.StatusNotFound},
+		{geolocation.ErrImportJobFinished, http.StatusConflict},
+		{geolocation.ErrDatasetNotFound, http.StatusNotFound},
+		{geolocation.ErrDatasetNeverActivated, http.StatusConflict},
+	} {
+		if errors.Is(err, e.err) {
+			s.sendResponse(e.code, w, adminapi.Error{ErrorDetails: err.Error()})
+			return
+		}
+	}
+
+	s.logger.ErrorContext(ctx, msg, "error", err)
+	body := adminapi.Error{ErrorDetails: "Service temporarily unavailable"}
+	if id := logging.RequestID(ctx); id != "" {
+		body.RequestId = &id
+	}
+	s.sendResponse(http.StatusServiceUnavailable, w, body)
+}
+
+func (s *AdminServer) sendResponse(code int, w http.ResponseWriter, data interface{}) {
+	w.WriteHeader(code)
+	body, err := json.Marshal(data)
+	if err != nil {
+		panic(err) // Exceptional situation - response structure must be marshalable.
+	}
+	if _, err = w.Write(body); err != nil {
+		s.logger.Debug("could not write response", "error", err) // Usually the client has gone.
+	}
+}
+
+func importJob(job geolocation.ImportJob) adminapi.ImportJob {
+	resp := adminapi.ImportJob{
+		Id:              job.ID,
+		Source:          job.Source,
+		Format:          job.Format,
+		State:           adminapi.ImportJobState(job.State),
+		CancelRequested: job.CancelRequested,
+		CreatedAt:       job.CreatedAt,
+		StartedAt:       optionalTime(job.StartedAt),
+		FinishedAt:      optionalTime(job.FinishedAt),
+		Statistics: adminapi.ImportStatistics{
+			Imported:   job.Statistics.Imported,
+			NonValid:   job.Statistics.NonValid,
+			Duplicated: job.Statistics.Duplicated,
+		},
+	}
+	if job.Error != "" {
+		resp.Error = &job.Error
+	}
+	if job.Statistics.DatasetVersion != 0 {
+		resp.Statistics.DatasetVersion = &job.Statistics.DatasetVersion
+	}
+	if len(job.Statistics.NonValidReasons) > 0 {
+		reasons := &adminapi.ImportStatistics_NonValidReasons{}
+		for reason, n := range job.Statistics.NonValidReasons {
+			reasons.Set(string(reason), n)
+		}
+		resp.Statistics.NonValidReasons = reasons
+	}
+	if len(job.Statistics.QualityGates) > 0 {
+		gates := make([]adminapi.QualityGate, 0, len(job.Statistics.QualityGates))
+		for _, g := range job.Statistics.QualityGates {
+			gates = append(gates, adminapi.QualityGate{Gate: g.Gate, Value: g.Value, Threshold: g.Threshold,
+				Passed: g.Passed})
+		}
+		resp.Statistics.QualityGates = &gates
+	}
+	return resp
+}
+
+func dataset(d geolocation.Dataset) adminapi.Dataset {
+	return adminapi.Dataset{
+		Version:     d.Version,
+		CreatedAt:   d.CreatedAt,
+		ActivatedAt: optionalTime(d.ActivatedAt),
+		Active:      d.Active,
+		Records:     d.Records,
+	}
+}
+
+// optionalTime - zero time is missing in responses.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,196 @@
+package admin_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/api/adminapi"
+	"github.com/dronnix/search-accomodation/internal/import_jobs"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+var (
+	created = time.Date(2022, 7, 17, 8, 0, 0, 0, time.UTC)
+	job     = geolocation.ImportJob{ID: 1, Source: "uploads/data.csv", Format: "csv",
+		State: geolocation.ImportJobRunning, CreatedAt: created, StartedAt: created,
+		Statistics: geolocation.ImportStatistics{Imported: 10, NonValid: 2, Duplicated: 1, DatasetVersion: 3,
+			NonValidReasons: map[geolocation.NonValidReason]int{geolocation.NonValidIP: 2},
+			QualityGates: []geolocation.QualityGateResult{
+				{Gate: geolocation.GateMinRecords, Value: 10, Threshold: 5, Passed: true},
+			}}}
+)
+
+func serve(t *testing.T, server *AdminServer, method, url, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	adminapi.Handler(server).ServeHTTP(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(data)
+}
+
+func TestAdminServer_Imports(t *testing.T) {
+	t.Parallel()
+	runner := new(runnerMock)
+	runner.On("Upload", "data.csv", mock.Anything).Return("uploads/data.csv", nil)
+	runner.On("Upload", "..", mock.Anything).Return("", fmt.Errorf("%w: invalid file name", import_jobs.ErrInvalidSource))
+	runner.On("Submit", mock.Anything, "uploads/data.csv", "csv").Return(job, nil)
+	runner.On("Submit", mock.Anything, "data.xml", "").
+		Return(geolocation.ImportJob{}, fmt.Errorf("%w: unable to detect", import_jobs.ErrInvalidFormat))
+	runner.On("Cancel", mock.Anything, 2).Return(geolocation.ImportJob{}, geolocation.ErrImportJobFinished)
+	jobs := new(jobsMock)
+	jobs.On("FetchImportJob", mock.Anything, 1).Return(job, nil)
+	jobs.On("FetchImportJob", mock.Anything, 5).Return(geolocation.ImportJob{}, geolocation.ErrImportJobNotFound)
+	jobs.On("ListImportJobs", mock.Anything, 100).Return([]geolocation.ImportJob{job}, nil)
+	jobs.On("ListImportJobs", mock.Anything, 2).Return(nil, errors.New("no db"))
+	server := NewAdminServer(runner, jobs, nil, logging.Discard())
+	const jobJSON = `{"cancel_requested":false,"created_at":"2022-07-17T08:00:00Z","format":"csv","id":1,` +
+		`"source":"uploads/data.csv","started_at":"2022-07-17T08:00:00Z","state":"running","statistics":` +
+		`{"dataset_version":3,"duplicated":1,"imported":10,"non_valid":2,"non_valid_reasons":{"invalid_ip":2},` +
+		`"quality_gates":[{"gate":"min_records","passed":true,"threshold":5,"value":10}]}}`
+
+	tests := []struct {
+		name, method, url, body string
+		wantCode                int
+		wantBody                string
+	}{
+		{name: "upload", method: http.MethodPut, url: "/admin/v1/uploads/data.csv", body: "ip_address",
+			wantCode: http.StatusCreated, wantBody: `{"source":"uploads/data.csv"}`},
+		{name: "upload invalid name", method: http.MethodPut, url: "/admin/v1/uploads/..",
+			wantCode: http.StatusBadRequest, wantBody: `{"errorDetails":"invalid source: invalid file name"}`},
+		{name: "submit", method: http.MethodPost, url: "/admin/v1/imports",
+			body: `{"source":"uploads/data.csv","format":"csv"}`, wantCode: http.StatusAccepted, wantBody: jobJSON},
+		{name: "submit invalid format", method: http.MethodPost, url: "/admin/v1/imports", body: `{"source":"data.xml"}`,
+			wantCode: http.StatusBadRequest},
+		{name: "submit malformed", method: http.MethodPost, url: "/admin/v1/imports", body: `{`,
+			wantCode: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, url: "/admin/v1/imports/1", wantCode: http.StatusOK, wantBody: jobJSON},
+		{name: "get not found", method: http.MethodGet, url: "/admin/v1/imports/5", wantCode: http.StatusNotFound},
+		{name: "list", method: http.MethodGet, url: "/admin/v1/imports", wantCode: http.StatusOK,
+			wantBody: "[" + jobJSON + "]"},
+		{name: "list invalid limit", method: http.MethodGet, url: "/admin/v1/imports?limit=0",
+			wantCode: http.StatusBadRequest},
+		{name: "list db error", method: http.MethodGet, url: "/admin/v1/imports?limit=2",
+			wantCode: http.StatusServiceUnavailable, wantBody: `{"errorDetails":"Service temporarily unavailable"}`},
+		{name: "cancel finished", method: http.MethodPost, url: "/admin/v1/imports/2/cancel",
+			wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		code, body := serve(t, server, tt.method, tt.url, tt.body)
+		assert.Equal(t, tt.wantCode, code, tt.name)
+		if tt.wantBody != "" {
+			assert.Equal(t, tt.wantBody, body, tt.name)
+		}
+	}
+}
+
+func TestAdminServer_Datasets(t *testing.T) {
+	t.Parallel()
+	previous := geolocation.Dataset{Version: 1, CreatedAt: created, ActivatedAt: created, Records: 5}
+	active := geolocation.Dataset{Version: 2, CreatedAt: created, ActivatedAt: created.Add(time.Hour), Active: true}
+	failed := geolocation.Dataset{Version: 3, CreatedAt: created}
+	datasets := new(datasetManagerMock)
+	datasets.On("ListDatasets", mock.Anything).Return([]geolocation.Dataset{previous, active, failed}, nil)
+	datasets.On("FetchDataset", mock.Anything, 3).Return(failed, nil)
+	datasets.On("FetchDataset", mock.Anything, 4).Return(geolocation.Dataset{}, geolocation.ErrDatasetNotFound)
+	datasets.On("FetchDataset", mock.Anything, 1).Return(previous, nil)
+	datasets.On("ActivateDataset", mock.Anything, 1).Return(nil)
+	server := NewAdminServer(nil, nil, datasets, logging.Discard())
+	const previousJSON = `{"activated_at":"2022-07-17T08:00:00Z","active":false,"created_at":"2022-07-17T08:00:00Z",` +
+		`"records":5,"version":1}`
+
+	tests := []struct {
+		name, method, url string
+		wantCode          int
+		wantBody          string
+	}{
+		{name: "list", method: http.MethodGet, url: "/admin/v1/datasets", wantCode: http.StatusOK, wantBody: "[" +
+			previousJSON + `,{"activated_at":"2022-07-17T09:00:00Z","active":true,"created_at":"2022-07-17T08:00:00Z",` +
+			`"records":0,"version":2},{"active":false,"created_at":"2022-07-17T08:00:00Z","records":0,"version":3}]`},
+		{name: "activate", method: http.MethodPost, url: "/admin/v1/datasets/1/activate", wantCode: http.StatusOK,
+			wantBody: previousJSON},
+		{name: "activate never activated", method: http.MethodPost, url: "/admin/v1/datasets/3/activate",
+			wantCode: http.StatusConflict},
+		{name: "activate not found", method: http.MethodPost, url: "/admin/v1/datasets/4/activate",
+			wantCode: http.StatusNotFound},
+		{name: "activate invalid", method: http.MethodPost, url: "/admin/v1/datasets/0/activate",
+			wantCode: http.StatusBadRequest},
+		{name: "rollback", method: http.MethodPost, url: "/admin/v1/datasets/rollback", wantCode: http.StatusOK,
+			wantBody: previousJSON},
+	}
+	for _, tt := range tests {
+		code, body := serve(t, server, tt.method, tt.url, "")
+		assert.Equal(t, tt.wantCode, code, tt.name)
+		if tt.wantBody != "" {
+			assert.Equal(t, tt.wantBody, body, tt.name)
+		}
+	}
+	datasets.AssertNumberOfCalls(t, "ActivateDataset", 2)
+}
+
+type runnerMock struct {
+	mock.Mock
+}
+
+func (m *runnerMock) Upload(name string, body io.Reader) (string, error) {
+	args := m.Called(name, body)
+	return args.String(0), args.Error(1)
+}
+
+func (m *runnerMock) Submit(ctx context.Context, source, format string) (geolocation.ImportJob, error) {
+	args := m.Called(ctx, source, format)
+	return args.Get(0).(geolocation.ImportJob), args.Error(1)
+}
+
+func (m *runnerMock) Cancel(ctx context.Context, id int) (geolocation.ImportJob, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(geolocation.ImportJob), args.Error(1)
+}
+
+type jobsMock struct {
+	mock.Mock
+}
+
+func (m *jobsMock) FetchImportJob(ctx context.Context, id int) (geolocation.ImportJob, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(geolocation.ImportJob), args.Error(1)
+}
+
+func (m *jobsMock) ListImportJobs(ctx context.Context, limit int) ([]geolocation.ImportJob, error) {
+	args := m.Called(ctx, limit)
+	jobs, _ := args.Get(0).([]geolocation.ImportJob)
+	return jobs, args.Error(1)
+}
+
+type datasetManagerMock struct {
+	mock.Mock
+}
+
+func (m *datasetManagerMock) FetchDataset(ctx context.Context, version int) (geolocation.Dataset, error) {
+	args := m.Called(ctx, version)
+	return args.Get(0).(geolocation.Dataset), args.Error(1)
+}
+
+func (m *datasetManagerMock) ListDatasets(ctx context.Context) ([]geolocation.Dataset, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]geolocation.Dataset), args.Error(1)
+}
+
+func (m *datasetManagerMock) ActivateDataset(ctx context.Context, version int) error {
+	return m.Called(ctx, version).Error(0)
+}
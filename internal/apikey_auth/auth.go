@@ -0,0 +1,130 @@
+package apikey_auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+// Errors of authentication and authorization, limit errors are wrapped by LimitError.
+var (
+	ErrMissingKey    = errors.New("api key is missing")
+	ErrInvalidKey    = errors.New("api key is invalid")
+	ErrRevokedKey    = errors.New("api key is revoked")
+	ErrNotAdmin      = errors.New("api key has no admin access")
+	ErrRateLimited   = errors.New("rate limit exceeded")
+	ErrQuotaExceeded = errors.New("daily quota exceeded")
+)
+
+// LimitError - the request is over the limits of the key, and may be retried later.
+type LimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", e.Err, e.RetryAfter)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// Authenticator checks API keys and enforces their limits.
+// Keys are cached for keyTTL, so revocation takes effect within keyTTL.
+// Limits are counted in memory, so every server instance enforces them on its own.
+type Authenticator struct {
+	fetcher apikey.Fetcher
+	keyTTL  time.Duration
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu       sync.Mutex
+	keys     map[string]cachedKey // By hash.
+	limiters map[int]*limiter     // By key ID.
+}
+
+type cachedKey struct {
+	key     apikey.Key
+	expires time.Time
+}
+
+func NewAuthenticator(fetcher apikey.Fetcher, keyTTL time.Duration, logger *slog.Logger) *Authenticator {
+	return &Authenticator{
+		fetcher:  fetcher,
+		keyTTL:   keyTTL,
+		logger:   logger,
+		now:      time.Now,
+		keys:     make(map[string]cachedKey),
+		limiters: make(map[int]*limiter),
+	}
+}
+
+// Authorize authenticates the secret and counts the request against the limits of the key.
+func (a *Authenticator) Authorize(ctx context.Context, secret string) (apikey.Key, error) {
+	key, err := a.Authenticate(ctx, secret)
+	if err != nil {
+		return apikey.Key{}, err
+	}
+	if err = a.Allow(key); err != nil {
+		return apikey.Key{}, err
+	}
+	return key, nil
+}
+
+// Authenticate returns the key of the secret without counting the request.
+func (a *Authenticator) Authenticate(ctx context.Context, secret string) (apikey.Key, error) {
+	if secret == "" {
+		return apikey.Key{}, ErrMissingKey
+	}
+	hash := apikey.Hash(secret)
+	key, err := a.fetchKey(ctx, hash)
+	if errors.Is(err, apikey.ErrKeyNotFound) {
+		return apikey.Key{}, ErrInvalidKey
+	}
+	if err != nil {
+		return apikey.Key{}, fmt.Errorf("could not fetch api key: %w", err)
+	}
+	if key.Revoked() {
+		return apikey.Key{}, ErrRevokedKey
+	}
+	return key, nil
+}
+
+// Allow counts the request against the limits of the key, returns LimitError if it is over them.
+func (a *Authenticator) Allow(key apikey.Key) error {
+	a.mu.Lock()
+	l, ok := a.limiters[key.ID]
+	if !ok {
+		l = &limiter{}
+		a.limiters[key.ID] = l
+	}
+	a.mu.Unlock()
+	return l.allow(key.Limits, a.now())
+}
+
+// fetchKey returns the cached key if it has not expired. Unknown keys are not cached,
+// so random secrets can't fill the memory up.
+func (a *Authenticator) fetchKey(ctx context.Context, hash string) (apikey.Key, error) {
+	now := a.now()
+	a.mu.Lock()
+	cached, ok := a.keys[hash]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.key, nil
+	}
+
+	key, err := a.fetcher.FetchAPIKey(ctx, hash)
+	if err != nil {
+		return apikey.Key{}, err //nolint:wrapcheck
+	}
+	a.mu.Lock()
+	a.keys[hash] = cachedKey{key: key, expires: now.Add(a.keyTTL)}
+	a.mu.Unlock()
+	return key, nil
+}
@@ -0,0 +1,78 @@
+package apikey_auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+func TestAuthenticator_Authorize(t *testing.T) {
+	t.Parallel()
+	fetcher := new(fetcherMock)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("valid")).Return(validKey, nil).Once()
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("revoked")).Return(revokedKey, nil).Once()
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("unknown")).Return(apikey.Key{}, apikey.ErrKeyNotFound).Once()
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("broken")).Return(apikey.Key{}, errors.New("no db")).Once()
+	auth := NewAuthenticator(fetcher, time.Minute, logging.Discard())
+	ctx := context.Background()
+
+	key, err := auth.Authorize(ctx, "valid")
+	require.NoError(t, err)
+	assert.Equal(t, validKey.ID, key.ID)
+	_, err = auth.Authorize(ctx, "valid")
+	require.ErrorIs(t, err, ErrRateLimited, "the key is cached, but the limits are counted")
+
+	_, err = auth.Authorize(ctx, "")
+	require.ErrorIs(t, err, ErrMissingKey)
+	_, err = auth.Authorize(ctx, "revoked")
+	require.ErrorIs(t, err, ErrRevokedKey)
+	_, err = auth.Authorize(ctx, "unknown")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = auth.Authorize(ctx, "broken")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrInvalidKey)
+	fetcher.AssertExpectations(t)
+}
+
+func TestAuthenticator_Authenticate_KeyTTL(t *testing.T) {
+	t.Parallel()
+	fetcher := new(fetcherMock)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("valid")).Return(validKey, nil).Once()
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("valid")).Return(revokedKey, nil).Once()
+	auth := NewAuthenticator(fetcher, time.Minute, logging.Discard())
+	now := time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := auth.Authenticate(ctx, "valid")
+	require.NoError(t, err)
+	now = now.Add(59 * time.Second)
+	_, err = auth.Authenticate(ctx, "valid")
+	require.NoError(t, err)
+	now = now.Add(time.Second)
+	_, err = auth.Authenticate(ctx, "valid")
+	require.ErrorIs(t, err, ErrRevokedKey)
+	fetcher.AssertExpectations(t)
+}
+
+type fetcherMock struct {
+	mock.Mock
+}
+
+func (f *fetcherMock) FetchAPIKey(ctx context.Context, hash string) (apikey.Key, error) {
+	args := f.Called(ctx, hash)
+	return args.Get(0).(apikey.Key), args.Error(1) //nolint:wrapcheck
+}
+
+var (
+	validKey   = apikey.Key{ID: 1, Name: "partner", Limits: apikey.Limits{RatePerSecond: 0.001, Burst: 1}}
+	revokedKey = apikey.Key{ID: 2, Name: "former partner", RevokedAt: time.Date(2022, 7, 17, 0, 0, 0, 0, time.UTC)}
+)
@@ -0,0 +1,83 @@
+package apikey_auth
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+// metadataName is the metadata key to pass API key, the same as HTTP header.
+const metadataName = "x-api-key"
+
+// UnaryInterceptor rejects calls without a valid API key within its limits, see HTTPMiddleware.
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if _, err := a.Authorize(ctx, secretFromMetadata(ctx)); err != nil {
+			return nil, a.statusError(ctx, err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rejects streams without a valid API key, every received message is counted against its limits.
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		key, err := a.Authenticate(ss.Context(), secretFromMetadata(ss.Context()))
+		if err != nil {
+			return a.statusError(ss.Context(), err)
+		}
+		return handler(srv, &limitedStream{ServerStream: ss, auth: a, key: key})
+	}
+}
+
+func secretFromMetadata(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if secrets := md.Get(metadataName); len(secrets) > 0 {
+			return secrets[0]
+		}
+	}
+	return ""
+}
+
+// statusError maps errors the same way as HTTPMiddleware, Retry-After is sent in the header.
+func (a *Authenticator) statusError(ctx context.Context, err error) error {
+	var limitErr *LimitError
+	switch {
+	case errors.Is(err, ErrMissingKey) || errors.Is(err, ErrInvalidKey):
+		return status.Error(codes.Unauthenticated, "missing or invalid API key")
+	case errors.Is(err, ErrRevokedKey):
+		return status.Error(codes.PermissionDenied, "API key is revoked")
+	case errors.As(err, &limitErr):
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfterSeconds(limitErr.RetryAfter)))
+		return status.Error(codes.ResourceExhausted, limitErr.Err.Error())
+	default:
+		a.logger.ErrorContext(ctx, "could not authorize call", "error", err)
+		return status.Errorf(codes.Unavailable, "service temporarily unavailable, request ID: %s",
+			logging.RequestID(ctx))
+	}
+}
+
+// limitedStream counts every received message as a request of the key.
+type limitedStream struct {
+	grpc.ServerStream
+	auth *Authenticator
+	key  apikey.Key
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err //nolint:wrapcheck
+	}
+	if err := s.auth.Allow(s.key); err != nil {
+		return s.auth.statusError(s.Context(), err)
+	}
+	return nil
+}
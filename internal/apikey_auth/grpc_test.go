@@ -0,0 +1,82 @@
+package apikey_auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+func TestAuthenticator_UnaryInterceptor(t *testing.T) {
+	t.Parallel()
+	fetcher := new(fetcherMock)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("valid")).Return(validKey, nil)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("revoked")).Return(revokedKey, nil)
+	interceptor := NewAuthenticator(fetcher, time.Minute, logging.Discard()).UnaryInterceptor()
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+	call := func(secret string) (interface{}, error) {
+		ctx := context.Background()
+		if secret != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", secret))
+		}
+		return interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	}
+
+	resp, err := call("valid")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	_, err = call("valid")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = call("")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = call("revoked")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuthenticator_StreamInterceptor(t *testing.T) {
+	t.Parallel()
+	fetcher := new(fetcherMock)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("valid")).Return(
+		apikey.Key{ID: 1, Limits: apikey.Limits{DailyQuota: 2}}, nil)
+	interceptor := NewAuthenticator(fetcher, time.Minute, logging.Discard()).StreamInterceptor()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "valid"))
+
+	var received int
+	handler := func(_ interface{}, ss grpc.ServerStream) error {
+		for {
+			if err := ss.RecvMsg(nil); err != nil {
+				return err
+			}
+			received++
+		}
+	}
+	err := interceptor(nil, &streamStub{ctx: ctx}, &grpc.StreamServerInfo{}, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, 2, received, "every message is counted against the quota")
+
+	err = interceptor(nil, &streamStub{ctx: context.Background()}, &grpc.StreamServerInfo{}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+type streamStub struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *streamStub) Context() context.Context {
+	return s.ctx
+}
+
+func (s *streamStub) RecvMsg(interface{}) error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package apikey_auth
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dronnix/search-accomodation/api"
+	"github.com/dronnix/search-accomodation/internal/logging"
+)
+
+// Where clients pass API keys over HTTP, see securitySchemes of the OpenAPI spec.
+const (
+	HeaderName     = "X-API-Key"
+	QueryParamName = "api_key"
+)
+
+// HTTPMiddleware rejects requests without a valid API key within its limits:
+// 401 if the key is missing or unknown, 403 if it is revoked, 429 with Retry-After if it is over the limits.
+func (a *Authenticator) HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get(HeaderName)
+		if secret == "" {
+			secret = r.URL.Query().Get(QueryParamName)
+		}
+		_, err := a.Authorize(r.Context(), secret)
+		a.serveAuthorized(w, r, next, err)
+	})
+}
+
+// AdminHTTPMiddleware is HTTPMiddleware for admin keys, passed in the header only (not to leak into access logs).
+// Valid keys without admin access get 403.
+func (a *Authenticator) AdminHTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := a.Authorize(r.Context(), r.Header.Get(HeaderName))
+		if err == nil && !key.Admin {
+			err = ErrNotAdmin
+		}
+		a.serveAuthorized(w, r, next, err)
+	})
+}
+
+// serveAuthorized passes the request to next if authorization succeeded, otherwise responds with the error.
+func (a *Authenticator) serveAuthorized(w http.ResponseWriter, r *http.Request, next http.Handler, err error) {
+	if err == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	var limitErr *LimitError
+	switch {
+	case errors.Is(err, ErrMissingKey) || errors.Is(err, ErrInvalidKey):
+		sendError(w, http.StatusUnauthorized, api.Error{ErrorDetails: "Missing or invalid API key"})
+	case errors.Is(err, ErrRevokedKey):
+		sendError(w, http.StatusForbidden, api.Error{ErrorDetails: "API key is revoked"})
+	case errors.Is(err, ErrNotAdmin):
+		sendError(w, http.StatusForbidden, api.Error{ErrorDetails: "API key has no admin access"})
+	case errors.As(err, &limitErr):
+		w.Header().Set("Retry-After", retryAfterSeconds(limitErr.RetryAfter))
+		details := "Rate limit exceeded"
+		if errors.Is(err, ErrQuotaExceeded) {
+			details = "Daily quota exceeded"
+		}
+		sendError(w, http.StatusTooManyRequests, api.Error{ErrorDetails: details})
+	default:
+		a.logger.ErrorContext(r.Context(), "could not authorize request", "error", err)
+		body := api.Error{ErrorDetails: "Service temporarily unavailable"}
+		if id := logging.RequestID(r.Context()); id != "" {
+			body.RequestId = &id
+		}
+		sendError(w, http.StatusServiceUnavailable, body)
+	}
+}
+
+// retryAfterSeconds rounds the delay up, as Retry-After is in whole seconds.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(d.Seconds()))))
+}
+
+func sendError(w http.ResponseWriter, code int, body api.Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	data, err := json.Marshal(body)
+	if err != nil {
+		panic(err) // Exceptional situation - response structure must be marshalable.
+	}
+	_, _ = w.Write(data) // Usually fails if the client has gone only.
+}
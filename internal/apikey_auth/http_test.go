@@ -0,0 +1,110 @@
+package apikey_auth
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+func TestAuthenticator_HTTPMiddleware(t *testing.T) {
+	t.Parallel()
+	quotaKey := apikey.Key{ID: 3, Limits: apikey.Limits{DailyQuota: 1}}
+	fetcher := new(fetcherMock)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("valid")).Return(validKey, nil)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("quota")).Return(quotaKey, nil)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("revoked")).Return(revokedKey, nil)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("unknown")).Return(apikey.Key{}, apikey.ErrKeyNotFound)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("broken")).Return(apikey.Key{}, errors.New("no db"))
+	auth := NewAuthenticator(fetcher, time.Minute, logging.Discard())
+	auth.now = func() time.Time { return time.Date(2022, 7, 17, 12, 0, 0, 0, time.UTC) }
+	handler := auth.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		url            string
+		header         string
+		wantCode       int
+		wantRetryAfter string
+		wantBody       string
+	}{
+		{name: "header", url: "/v1/iplocation", header: "valid", wantCode: http.StatusOK},
+		{name: "rate limited", url: "/v1/iplocation", header: "valid", wantCode: http.StatusTooManyRequests,
+			wantRetryAfter: "1000", wantBody: `{"errorDetails":"Rate limit exceeded"}`},
+		{name: "query", url: "/v1/iplocation?api_key=quota", wantCode: http.StatusOK},
+		{name: "quota exceeded", url: "/v1/iplocation?api_key=quota", wantCode: http.StatusTooManyRequests,
+			wantRetryAfter: "43200", wantBody: `{"errorDetails":"Daily quota exceeded"}`},
+		{name: "missing", url: "/v1/iplocation", wantCode: http.StatusUnauthorized,
+			wantBody: `{"errorDetails":"Missing or invalid API key"}`},
+		{name: "unknown", url: "/v1/iplocation", header: "unknown", wantCode: http.StatusUnauthorized},
+		{name: "revoked", url: "/v1/iplocation", header: "revoked", wantCode: http.StatusForbidden,
+			wantBody: `{"errorDetails":"API key is revoked"}`},
+		{name: "db error", url: "/v1/iplocation", header: "broken", wantCode: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests { // Sequential, as the limits are shared.
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.header != "" {
+			req.Header.Set(HeaderName, tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		res := w.Result()
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		require.Equal(t, tt.wantCode, res.StatusCode, tt.name)
+		assert.Equal(t, tt.wantRetryAfter, res.Header.Get("Retry-After"), tt.name)
+		if tt.wantBody != "" {
+			assert.Equal(t, tt.wantBody, string(body), tt.name)
+		}
+	}
+}
+
+func TestAuthenticator_AdminHTTPMiddleware(t *testing.T) {
+	t.Parallel()
+	adminKey := apikey.Key{ID: 4, Admin: true}
+	fetcher := new(fetcherMock)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("admin")).Return(adminKey, nil)
+	fetcher.On("FetchAPIKey", mock.Anything, apikey.Hash("valid")).Return(validKey, nil)
+	handler := NewAuthenticator(fetcher, time.Minute, logging.Discard()).
+		AdminHTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	tests := []struct {
+		name     string
+		url      string
+		header   string
+		wantCode int
+	}{
+		{name: "admin", url: "/admin/v1/imports", header: "admin", wantCode: http.StatusOK},
+		{name: "not admin", url: "/admin/v1/imports", header: "valid", wantCode: http.StatusForbidden},
+		{name: "query is ignored", url: "/admin/v1/imports?api_key=admin", wantCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.header != "" {
+			req.Header.Set(HeaderName, tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, tt.wantCode, w.Code, tt.name)
+	}
+}
+
+func Test_retryAfterSeconds(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "1", retryAfterSeconds(0))
+	assert.Equal(t, "1", retryAfterSeconds(time.Millisecond))
+	assert.Equal(t, "2", retryAfterSeconds(1001*time.Millisecond))
+}
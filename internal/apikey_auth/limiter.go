@@ -0,0 +1,54 @@
+package apikey_auth
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+const day = 24 * time.Hour
+
+// limiter is a token bucket for the request rate plus a counter of requests of the current UTC day.
+type limiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time // Time of the last refill, zero for a full bucket.
+	day    time.Time // Start of the day the requests are counted for.
+	used   int
+}
+
+// allow takes a token and counts the request, or returns LimitError without doing anything.
+func (l *limiter) allow(limits apikey.Limits, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limits.DailyQuota > 0 {
+		today := now.UTC().Truncate(day)
+		if !today.Equal(l.day) {
+			l.day, l.used = today, 0
+		}
+		if l.used >= limits.DailyQuota {
+			return &LimitError{Err: ErrQuotaExceeded, RetryAfter: today.Add(day).Sub(now)}
+		}
+	}
+
+	if limits.RatePerSecond > 0 {
+		burst := float64(max(limits.Burst, 1))
+		if l.last.IsZero() {
+			l.tokens = burst
+		} else {
+			l.tokens = math.Min(burst, l.tokens+now.Sub(l.last).Seconds()*limits.RatePerSecond)
+		}
+		l.last = now
+		if l.tokens < 1 {
+			wait := (1 - l.tokens) / limits.RatePerSecond
+			return &LimitError{Err: ErrRateLimited, RetryAfter: time.Duration(wait * float64(time.Second))}
+		}
+		l.tokens--
+	}
+
+	l.used++
+	return nil
+}
@@ -0,0 +1,74 @@
+package apikey_auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+func Test_limiter_allow_Rate(t *testing.T) {
+	t.Parallel()
+	limits := apikey.Limits{RatePerSecond: 2, Burst: 3}
+	now := time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC)
+	l := &limiter{}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.allow(limits, now), "burst request %d", i)
+	}
+	err := l.allow(limits, now)
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 500*time.Millisecond, limitErr.RetryAfter)
+
+	require.NoError(t, l.allow(limits, now.Add(500*time.Millisecond)))
+	require.Error(t, l.allow(limits, now.Add(500*time.Millisecond)))
+	// The bucket is not filled above the burst.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.allow(limits, now))
+	}
+	require.Error(t, l.allow(limits, now))
+}
+
+func Test_limiter_allow_DailyQuota(t *testing.T) {
+	t.Parallel()
+	limits := apikey.Limits{DailyQuota: 2}
+	now := time.Date(2022, 7, 17, 23, 59, 0, 0, time.UTC)
+	l := &limiter{}
+
+	require.NoError(t, l.allow(limits, now))
+	require.NoError(t, l.allow(limits, now))
+	err := l.allow(limits, now)
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, time.Minute, limitErr.RetryAfter)
+
+	require.NoError(t, l.allow(limits, now.Add(time.Minute)), "quota is reset at UTC midnight")
+}
+
+func Test_limiter_allow_RateLimitedNotCounted(t *testing.T) {
+	t.Parallel()
+	limits := apikey.Limits{RatePerSecond: 1, Burst: 1, DailyQuota: 2}
+	now := time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC)
+	l := &limiter{}
+
+	require.NoError(t, l.allow(limits, now))
+	require.ErrorIs(t, l.allow(limits, now), ErrRateLimited)
+	require.NoError(t, l.allow(limits, now.Add(time.Second)))
+	require.ErrorIs(t, l.allow(limits, now.Add(time.Hour)), ErrQuotaExceeded)
+}
+
+func Test_limiter_allow_Unlimited(t *testing.T) {
+	t.Parallel()
+	l := &limiter{}
+	now := time.Now()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, l.allow(apikey.Limits{}, now))
+	}
+}
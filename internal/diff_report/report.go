@@ -0,0 +1,120 @@
+package diff_report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Format of the summary.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// JSONLWriter writes differences of IP locations as JSON Lines, one object per IP address.
+type JSONLWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewJSONLWriter creates a JSONLWriter to writer.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	writer := bufio.NewWriter(w)
+	return &JSONLWriter{writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+// Write buffers the difference, call Flush once all of them are written.
+func (j *JSONLWriter) Write(diff geolocation.IPLocationDiff) error {
+	rec := diffRecord{
+		Kind:           string(diff.Kind),
+		Old:            newLocationRecord(diff.Old),
+		New:            newLocationRecord(diff.New),
+		DistanceKm:     diff.DistanceKm,
+		Relocated:      diff.Relocated,
+		CountryChanged: diff.CountryChanged,
+		CityChanged:    diff.CityChanged,
+	}
+	if diff.New != nil {
+		rec.IP = diff.New.IP.String()
+	} else {
+		rec.IP = diff.Old.IP.String()
+	}
+	if err := j.encoder.Encode(rec); err != nil {
+		return fmt.Errorf("failed to write difference: %w", err)
+	}
+	return nil
+}
+
+func (j *JSONLWriter) Flush() error {
+	if err := j.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush jsonl: %w", err)
+	}
+	return nil
+}
+
+type diffRecord struct {
+	Kind           string          `json:"kind"`
+	IP             string          `json:"ip_address"`
+	Old            *locationRecord `json:"old,omitempty"`
+	New            *locationRecord `json:"new,omitempty"`
+	DistanceKm     float64         `json:"distance_km,omitempty"`
+	Relocated      bool            `json:"relocated,omitempty"`
+	CountryChanged bool            `json:"country_changed,omitempty"`
+	CityChanged    bool            `json:"city_changed,omitempty"`
+}
+
+type locationRecord struct {
+	CountryCode string  `json:"country_code"`
+	CountryName string  `json:"country"`
+	City        string  `json:"city"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+}
+
+func newLocationRecord(loc *geolocation.IPLocation) *locationRecord {
+	if loc == nil {
+		return nil
+	}
+	return &locationRecord{CountryCode: loc.CountryCode, CountryName: loc.CountryName, City: loc.City,
+		Latitude: loc.Lat, Longitude: loc.Lon}
+}
+
+// WriteSummary writes the summary of the difference in the format.
+func WriteSummary(w io.Writer, s geolocation.DiffSummary, format Format) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summaryRecord(s)) //nolint:wrapcheck
+	case FormatText:
+		_, err := fmt.Fprintf(w, "Old IPs: %d\nNew IPs: %d\nAdded: %d\nRemoved: %d\nChanged: %d\nUnchanged: %d\n"+
+			"Relocated: %d\nCountry changed: %d\nCity changed: %d\nMax distance (km): %.1f\nMean distance (km): %.1f\n",
+			s.Old, s.New, s.Added, s.Removed, s.Changed, s.Unchanged, s.Relocated, s.CountryChanged, s.CityChanged,
+			s.MaxDistanceKm, s.MeanDistanceKm)
+		if err != nil {
+			return fmt.Errorf("failed to write summary: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported summary format: %s", format)
+}
+
+type summaryRecord struct {
+	Old            int     `json:"old"`
+	New            int     `json:"new"`
+	Added          int     `json:"added"`
+	Removed        int     `json:"removed"`
+	Changed        int     `json:"changed"`
+	Unchanged      int     `json:"unchanged"`
+	Relocated      int     `json:"relocated"`
+	CountryChanged int     `json:"country_changed"`
+	CityChanged    int     `json:"city_changed"`
+	MaxDistanceKm  float64 `json:"max_distance_km"`
+	MeanDistanceKm float64 `json:"mean_distance_km"`
+}
@@ -0,0 +1,66 @@
+package diff_report
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestJSONLWriter(t *testing.T) {
+	t.Parallel()
+	paris := geolocation.IPLocation{IP: netip.AddrFrom4([4]byte{10, 0, 0, 2}), CountryCode: "FR", CountryName: "France",
+		City: "Paris", Coordinate: geolocation.Coordinate{Lat: 48.9, Lon: 2.4}}
+	moved := paris
+	moved.Coordinate = geolocation.Coordinate{Lat: 48.85, Lon: 2.35}
+
+	var out bytes.Buffer
+	w := NewJSONLWriter(&out)
+	require.NoError(t, w.Write(geolocation.IPLocationDiff{Kind: geolocation.DiffAdded, New: &paris}))
+	require.NoError(t, w.Write(geolocation.IPLocationDiff{Kind: geolocation.DiffChanged, Old: &paris, New: &moved,
+		DistanceKm: 6.7, Relocated: true}))
+	require.NoError(t, w.Write(geolocation.IPLocationDiff{Kind: geolocation.DiffRemoved, Old: &paris}))
+	assert.Empty(t, out.String(), "buffered until flush")
+	require.NoError(t, w.Flush())
+
+	const loc = `{"country_code":"FR","country":"France","city":"Paris","latitude":48.9,"longitude":2.4}`
+	const movedLoc = `{"country_code":"FR","country":"France","city":"Paris","latitude":48.85,"longitude":2.35}`
+	assert.Equal(t, `{"kind":"added","ip_address":"10.0.0.2","new":`+loc+"}\n"+
+		`{"kind":"changed","ip_address":"10.0.0.2","old":`+loc+`,"new":`+movedLoc+`,"distance_km":6.7,"relocated":true}`+
+		"\n"+`{"kind":"removed","ip_address":"10.0.0.2","old":`+loc+"}\n", out.String())
+}
+
+var summary = geolocation.DiffSummary{Old: 4, New: 4, Added: 1, Removed: 1, Changed: 2, Unchanged: 1, Relocated: 1,
+	CityChanged: 1, MaxDistanceKm: 6.7, MeanDistanceKm: 6.7}
+
+func TestWriteSummary_Text(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	require.NoError(t, WriteSummary(&out, summary, FormatText))
+	assert.Equal(t, `Old IPs: 4
+New IPs: 4
+Added: 1
+Removed: 1
+Changed: 2
+Unchanged: 1
+Relocated: 1
+Country changed: 0
+City changed: 1
+Max distance (km): 6.7
+Mean distance (km): 6.7
+`, out.String())
+}
+
+func TestWriteSummary_JSON(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	require.NoError(t, WriteSummary(&out, summary, FormatJSON))
+	assert.JSONEq(t, `{"old":4,"new":4,"added":1,"removed":1,"changed":2,"unchanged":1,"relocated":1,
+		"country_changed":0,"city_changed":1,"max_distance_km":6.7,"mean_distance_km":6.7}`, out.String())
+
+	require.Error(t, WriteSummary(&out, summary, "xml"))
+}
@@ -1,24 +1,103 @@
 package flags
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/internal/tracing"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/storage"
 )
 
-// Postgres configuration.
+// Postgres configuration. A full DSN or a service of the connection service file replaces host, port, db, user
+// and password, TLS settings apply to the service and the host.
 type Postgres struct {
 	PostgresHost string `long:"postgres-host" description:"host with PG" default:"localhost" env:"POSTGRES_HOST"`
 	PostgresPort string `long:"postgres-port" description:"port where PG is listening" default:"5432" env:"POSTGRES_PORT"`
 	PostgresDB   string `long:"postgres-db" description:"name of the database to connect to" default:"geolocation" env:"POSTGRES_DB"` // nolint:lll
 	PostgresUser string `long:"postgres-user" description:"PG user" default:"postgres" env:"POSTGRES_USER"`
 	PostgresPass string `long:"postgres-pass" description:"PG password" default:"NA" env:"POSTGRES_PASS"`
+
+	PostgresDSN     string `long:"postgres-dsn" description:"connection string (URL or key=value) of the primary, written to by imports, used as is" env:"POSTGRES_DSN"` // nolint:lll
+	PostgresService string `long:"postgres-service" description:"service of the connection service file (PGSERVICEFILE) to connect to" env:"PGSERVICE"`                  // nolint:lll
+
+	PostgresSSLMode     string `long:"postgres-sslmode" description:"TLS mode, prefer if not set" choice:"disable" choice:"allow" choice:"prefer" choice:"require" choice:"verify-ca" choice:"verify-full" env:"POSTGRES_SSLMODE"` // nolint:lll
+	PostgresSSLRootCert string `long:"postgres-sslrootcert" description:"file of CA certificates verifying the server" env:"POSTGRES_SSLROOTCERT"`                                                                                 // nolint:lll
+	PostgresSSLCert     string `long:"postgres-sslcert" description:"file of the client certificate" env:"POSTGRES_SSLCERT"`                                                                                                       // nolint:lll
+	PostgresSSLKey      string `long:"postgres-sslkey" description:"file of the client certificate key" env:"POSTGRES_SSLKEY"`                                                                                                     // nolint:lll
+
+	PostgresMaxConns         int32         `long:"postgres-max-conns" description:"max size of the connection pool, the greater of 4 and the number of CPUs if 0" env:"POSTGRES_MAX_CONNS"`    // nolint:lll
+	PostgresMinConns         int32         `long:"postgres-min-conns" description:"min size of the connection pool" env:"POSTGRES_MIN_CONNS"`                                                  // nolint:lll
+	PostgresMaxConnLifetime  time.Duration `long:"postgres-max-conn-lifetime" description:"connections are closed once older, 1h if 0" env:"POSTGRES_MAX_CONN_LIFETIME"`                       // nolint:lll
+	PostgresStatementTimeout time.Duration `long:"postgres-statement-timeout" description:"queries running longer are canceled, no timeout if 0" env:"POSTGRES_STATEMENT_TIMEOUT"`             // nolint:lll
+	PostgresQueryTimeout     time.Duration `long:"postgres-query-timeout" description:"timeout of lookups and queries of datasets, no timeout if 0" default:"5s" env:"POSTGRES_QUERY_TIMEOUT"` // nolint:lll
+}
+
+// ReadReplicas configuration of the server, the primary is configured by the Postgres group.
+type ReadReplicas struct {
+	PostgresReadDSNs           []string      `long:"postgres-read-dsn" description:"connection string of a read replica, lookups and exports are balanced between healthy ones, may be repeated" env:"POSTGRES_READ_DSNS" env-delim:","` // nolint:lll
+	PostgresReadHealthInterval time.Duration `long:"postgres-read-health-interval" description:"how often read replicas are pinged" default:"10s" env:"POSTGRES_READ_HEALTH_INTERVAL"`                                                   // nolint:lll
+}
+
+// Storage backend configuration, the Postgres group configures the postgres backend.
+type Storage struct {
+	StorageBackend string `long:"storage" description:"storage backend, bolt is an embedded file for small deployments and tests" default:"postgres" choice:"postgres" choice:"bolt" env:"STORAGE"` // nolint:lll
+	BoltPath       string `long:"bolt-path" description:"file of the bolt storage" default:"iplocation.db" env:"BOLT_PATH"`                                                                         // nolint:lll
+}
+
+const (
+	StoragePostgres = "postgres"
+	StorageBolt     = "bolt"
+)
+
+// Retention policy of datasets, see geolocation.RetentionPolicy.
+type Retention struct {
+	RetentionKeepLast      int           `long:"retention-keep-last" description:"keep datasets of the last activated versions, the active dataset and newer ones are always kept unless left by failed imports, no rule if 0" env:"RETENTION_KEEP_LAST"` // nolint:lll
+	RetentionKeepNewerThan time.Duration `long:"retention-keep-newer-than" description:"keep datasets created within the duration, no rule if 0" env:"RETENTION_KEEP_NEWER_THAN"`                                                                         // nolint:lll
+}
+
+// Logging configuration.
+type Logging struct {
+	LogLevel     string `long:"log-level" description:"minimal level of logged records" default:"info" choice:"debug" choice:"info" choice:"warn" choice:"error" env:"LOG_LEVEL"` // nolint:lll
+	LogRedactIPs bool   `long:"log-redact-ips" description:"mask host part of client IPs in logs" env:"LOG_REDACT_IPS"`
+}
+
+// Tracing configuration.
+type Tracing struct {
+	TracingEndpoint    string  `long:"tracing-endpoint" description:"OTLP gRPC endpoint (host:port) to export spans to, tracing is disabled if empty" env:"TRACING_ENDPOINT"` // nolint:lll
+	TracingInsecure    bool    `long:"tracing-insecure" description:"connect to the OTLP endpoint without TLS" env:"TRACING_INSECURE"`                                        // nolint:lll
+	TracingSampleRatio float64 `long:"tracing-sample-ratio" description:"ratio of sampled traces started by the service" default:"1" env:"TRACING_SAMPLE_RATIO"`              // nolint:lll
+}
+
+// QualityGates configuration of imports, see geolocation.QualityGates.
+type QualityGates struct {
+	MinRecords            int     `long:"min-records" description:"fail without activating the dataset if fewer records are imported, no limit if 0" default:"0" env:"MIN_RECORDS"`                                                       // nolint:lll
+	MaxRejectRatio        float64 `long:"max-reject-ratio" description:"fail without activating the dataset if the share of non-valid records is higher, no limit if 0" default:"0" env:"MAX_REJECT_RATIO"`                               // nolint:lll
+	MaxAmbiguousRatio     float64 `long:"max-ambiguous-ratio" description:"fail without activating the dataset if the share of IPs with several locations is higher, no limit if 0" default:"0" env:"MAX_AMBIGUOUS_RATIO"`                // nolint:lll
+	MaxCountryDrift       float64 `long:"max-country-drift" description:"fail without activating the dataset if shares of countries differ from the active dataset more (0..1), no limit if 0" default:"0" env:"MAX_COUNTRY_DRIFT"`       // nolint:lll
+	MaxMisplacedRatio     float64 `long:"max-misplaced-ratio" description:"fail without activating the dataset if the share of locations far from their country centroid is higher, no limit if 0" default:"0" env:"MAX_MISPLACED_RATIO"` // nolint:lll
+	MaxCentroidDistanceKm float64 `long:"max-centroid-distance-km" description:"locations farther from their country centroid are misplaced" default:"2000" env:"MAX_CENTROID_DISTANCE_KM"`                                               // nolint:lll
+	CountryCentroids      string  `long:"country-centroids" description:"CSV file with country_code,latitude,longitude columns, required by --max-misplaced-ratio" env:"COUNTRY_CENTROIDS"`                                               // nolint:lll
 }
 
 // Parse command line arguments to annotated struct.
 func Parse(cfg interface{}) {
+	parse(cfg)
+}
+
+// ParseCommand parses command line arguments of a tool with commands, and returns the name of the given command.
+func ParseCommand(cfg interface{}) string {
+	return parse(cfg).Active.Name
+}
+
+func parse(cfg interface{}) *flags.Parser {
 	parser := flags.NewParser(cfg, flags.Default)
 	if _, err := parser.Parse(); err != nil {
 
@@ -35,12 +114,84 @@ func Parse(cfg interface{}) {
 		}
 		os.Exit(1)
 	}
+	return parser
 }
 
+// PostgresConnectionString returns the DSN if given, otherwise a connection string of the service or the host.
 func (p *Postgres) PostgresConnectionString() string {
-	return fmt.Sprintf(
+	if p.PostgresDSN != "" {
+		return p.PostgresDSN
+	}
+	tls := url.Values{}
+	for key, value := range map[string]string{
+		"sslmode":     p.PostgresSSLMode,
+		"sslrootcert": p.PostgresSSLRootCert,
+		"sslcert":     p.PostgresSSLCert,
+		"sslkey":      p.PostgresSSLKey,
+	} {
+		if value != "" {
+			tls.Set(key, value)
+		}
+	}
+	if p.PostgresService != "" {
+		settings := []string{"service=" + quoteConnValue(p.PostgresService)}
+		for _, key := range []string{"sslmode", "sslrootcert", "sslcert", "sslkey"} {
+			if tls.Has(key) {
+				settings = append(settings, key+"="+quoteConnValue(tls.Get(key)))
+			}
+		}
+		return strings.Join(settings, " ")
+	}
+	connString := fmt.Sprintf(
 		"postgres://%s:%s@%s:%s/%s",
 		url.QueryEscape(p.PostgresUser), url.QueryEscape(p.PostgresPass),
 		p.PostgresHost, p.PostgresPort, p.PostgresDB,
 	)
+	if len(tls) > 0 {
+		connString += "?" + tls.Encode()
+	}
+	return connString
+}
+
+// quoteConnValue quotes a value of a key=value connection string.
+func quoteConnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+}
+
+func (p *Postgres) PoolOptions() storage.PoolOptions {
+	return storage.PoolOptions{
+		MaxConns:         p.PostgresMaxConns,
+		MinConns:         p.PostgresMinConns,
+		MaxConnLifetime:  p.PostgresMaxConnLifetime,
+		StatementTimeout: p.PostgresStatementTimeout,
+	}
+}
+
+func (l *Logging) LoggingOptions() logging.Options {
+	return logging.Options{Level: l.LogLevel, RedactIPs: l.LogRedactIPs}
+}
+
+// QualityGatesOptions returns the gates without country centroids, which are loaded by the caller from the
+// CountryCentroids file if the misplaced locations are checked.
+func (q *QualityGates) QualityGatesOptions() (geolocation.QualityGates, error) {
+	gates := geolocation.QualityGates{
+		MinRecords:            q.MinRecords,
+		MaxNonValidRatio:      q.MaxRejectRatio,
+		MaxAmbiguousRatio:     q.MaxAmbiguousRatio,
+		MaxCountryDrift:       q.MaxCountryDrift,
+		MaxMisplacedRatio:     q.MaxMisplacedRatio,
+		MaxCentroidDistanceKm: q.MaxCentroidDistanceKm,
+	}
+	if q.MaxMisplacedRatio > 0 && q.CountryCentroids == "" {
+		return geolocation.QualityGates{}, errors.New("--country-centroids is required by --max-misplaced-ratio")
+	}
+	return gates, nil
+}
+
+func (r *Retention) RetentionPolicy() geolocation.RetentionPolicy {
+	return geolocation.RetentionPolicy{KeepLast: r.RetentionKeepLast, KeepNewerThan: r.RetentionKeepNewerThan}
+}
+
+func (t *Tracing) TracingOptions() tracing.Options {
+	return tracing.Options{Endpoint: t.TracingEndpoint, Insecure: t.TracingInsecure, SampleRatio: t.TracingSampleRatio}
 }
@@ -0,0 +1,139 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Check - readiness check of a dependency, returns an error if the service can't serve requests because of it.
+type Check func(ctx context.Context) error
+
+// ErrDraining is reported when the server is shutting down.
+var ErrDraining = errors.New("server is shutting down")
+
+// errUnavailable is reported instead of errors of the checks, as probes are not authenticated. The errors are logged.
+const errUnavailable = "unavailable"
+
+// Checker serves liveness and readiness probes.
+type Checker struct {
+	names    []string
+	checks   []Check
+	timeout  time.Duration
+	draining atomic.Bool
+	logger   *slog.Logger
+}
+
+// NewChecker creates checker, running every readiness check with the timeout. Failed checks are logged.
+func NewChecker(timeout time.Duration, logger *slog.Logger) *Checker {
+	return &Checker{timeout: timeout, logger: logger}
+}
+
+// Add registers readiness check, the name is used in the response.
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks = append(c.checks, check)
+}
+
+// StartDraining makes the service not ready, so load balancers stop sending requests before shutdown.
+func (c *Checker) StartDraining() {
+	c.draining.Store(true)
+}
+
+// CheckResult - result of a single check.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report - result of all the readiness checks.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Ready runs all the checks concurrently.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks)+1)}
+	if c.draining.Load() {
+		report.Status = StatusFail
+		report.Checks["draining"] = CheckResult{Status: StatusFail, Error: ErrDraining.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	errs := make([]error, len(c.checks))
+	wg := sync.WaitGroup{}
+	for i := range c.checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.checks[i](ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			report.Status = StatusFail
+			c.logger.WarnContext(ctx, "readiness check failed", "check", c.names[i], "error", err)
+			report.Checks[c.names[i]] = CheckResult{Status: StatusFail, Error: errUnavailable}
+		} else {
+			report.Checks[c.names[i]] = CheckResult{Status: StatusOK}
+		}
+	}
+	return report
+}
+
+// LivenessHandler reports the process is alive, it doesn't check dependencies
+// to avoid restarts because of them.
+func (c *Checker) LivenessHandler(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+// ReadinessHandler reports results of all the checks, 503 if any of them failed.
+func (c *Checker) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	report := c.Ready(r.Context())
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
+
+func writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		panic(err) // Exceptional situation - response structure must be marshalable.
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+// ActiveDatasetCheck fails if there is no active dataset or it is empty.
+func ActiveDatasetCheck(fetcher geolocation.DatasetFetcher) Check {
+	return func(ctx context.Context) error {
+		dataset, err := fetcher.FetchDataset(ctx, 0)
+		if err != nil {
+			return fmt.Errorf("could not fetch active dataset: %w", err)
+		}
+		if dataset.Records == 0 {
+			return fmt.Errorf("active dataset %d is empty", dataset.Version)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func okCheck(context.Context) error { return nil }
+
+func TestChecker_LivenessHandler(t *testing.T) {
+	t.Parallel()
+	checker := NewChecker(time.Second, logging.Discard())
+	checker.Add("database", func(context.Context) error { return errors.New("connection refused") })
+
+	w := httptest.NewRecorder()
+	checker.LivenessHandler(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestChecker_ReadinessHandler(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		dbErr    error
+		draining bool
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "ready",
+			wantCode: http.StatusOK,
+			wantBody: `{"status":"ok","checks":{"database":{"status":"ok"},"migrations":{"status":"ok"}}}`,
+		},
+		{
+			name:     "check failed",
+			dbErr:    errors.New("connection refused"),
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `{"status":"fail","checks":{"database":{"status":"fail","error":"unavailable"},` +
+				`"migrations":{"status":"ok"}}}`,
+		},
+		{
+			name:     "draining",
+			draining: true,
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `{"status":"fail","checks":{"database":{"status":"ok"},"migrations":{"status":"ok"},` +
+				`"draining":{"status":"fail","error":"server is shutting down"}}}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			checker := NewChecker(time.Second, logging.Discard())
+			checker.Add("database", func(context.Context) error { return tt.dbErr })
+			checker.Add("migrations", okCheck)
+			if tt.draining {
+				checker.StartDraining()
+			}
+
+			w := httptest.NewRecorder()
+			checker.ReadinessHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestChecker_Ready_Timeout(t *testing.T) {
+	t.Parallel()
+	checker := NewChecker(10*time.Millisecond, logging.Discard())
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := checker.Ready(context.Background())
+	require.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, "unavailable", report.Checks["slow"].Error)
+}
+
+func TestActiveDatasetCheck(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		dataset geolocation.Dataset
+		err     error
+		wantErr bool
+	}{
+		{name: "loaded", dataset: geolocation.Dataset{Version: 2, Active: true, Records: 10}},
+		{name: "empty", dataset: geolocation.Dataset{Version: 2, Active: true}, wantErr: true},
+		{name: "no active dataset", err: geolocation.ErrDatasetNotFound, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			check := ActiveDatasetCheck(&datasetFetcherStub{dataset: tt.dataset, err: tt.err})
+			err := check(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+type datasetFetcherStub struct {
+	dataset geolocation.Dataset
+	err     error
+}
+
+func (d *datasetFetcherStub) FetchDataset(_ context.Context, version int) (geolocation.Dataset, error) {
+	if version != 0 {
+		return geolocation.Dataset{}, errors.New("active dataset expected")
+	}
+	return d.dataset, d.err
+}
@@ -0,0 +1,30 @@
+package import_jobs
+
+import (
+	"sync"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// progress keeps the last reported statistics of the running import, to save them by the heartbeat.
+type progress struct {
+	mu    sync.Mutex
+	stats geolocation.ImportStatistics
+}
+
+// report is geolocation.ImportOptions.Progress callback.
+func (p *progress) report(reported geolocation.ImportProgress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats = reported.Statistics // A copy already.
+}
+
+// snapshot returns statistics of the stored batches so far.
+func (p *progress) snapshot() geolocation.ImportStatistics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.NonValidReasons = nil
+	stats.Add(geolocation.ImportStatistics{NonValidReasons: p.stats.NonValidReasons}) // Deep copy.
+	return stats
+}
@@ -0,0 +1,27 @@
+package import_jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+	p := &progress{}
+	assert.Equal(t, geolocation.ImportStatistics{}, p.snapshot())
+
+	reasons := map[geolocation.NonValidReason]int{geolocation.NonValidIP: 1}
+	p.report(geolocation.ImportProgress{Statistics: geolocation.ImportStatistics{
+		Imported: 2, Duplicated: 1, NonValid: 1, DatasetVersion: 1, NonValidReasons: reasons,
+	}})
+	snapshot := p.snapshot()
+	assert.Equal(t, geolocation.ImportStatistics{
+		Imported: 2, Duplicated: 1, NonValid: 1, DatasetVersion: 1,
+		NonValidReasons: map[geolocation.NonValidReason]int{geolocation.NonValidIP: 1},
+	}, snapshot)
+	snapshot.NonValidReasons[geolocation.NonValidIP]++
+	assert.Equal(t, 1, p.snapshot().NonValidReasons[geolocation.NonValidIP], "snapshots are copies")
+}
@@ -0,0 +1,343 @@
+package import_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dronnix/search-accomodation/internal/import_report"
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Errors of submitted jobs and uploads, caused by the client.
+var (
+	ErrInvalidSource = errors.New("invalid source")
+	ErrInvalidFormat = errors.New("invalid format")
+)
+
+// uploadsDir is the subdirectory of the data directory uploaded files are kept in.
+const uploadsDir = "uploads"
+
+// Options of Runner.
+type Options struct {
+	// DataDir keeps uploaded and downloaded files, local sources must be inside it.
+	DataDir string
+	// PollInterval - how often other servers' and abandoned jobs are looked for.
+	PollInterval time.Duration
+	// HeartbeatInterval - how often the progress is saved and cancellation is checked.
+	HeartbeatInterval time.Duration
+	// StaleAfter - a running job not updated for this long has lost its runner, and is run again.
+	StaleAfter time.Duration
+	// HTTPClient downloads URL sources, the request context limits the download.
+	HTTPClient *http.Client
+	// Gates are checked before activation of imported datasets, Profiler is required by some of them.
+	Gates    geolocation.QualityGates
+	Profiler geolocation.DatasetProfiler
+	// Remover removes datasets of failed imports, they are left behind if nil.
+	Remover geolocation.DatasetRemover
+}
+
+// Runner runs import jobs one by one in the background. Jobs are kept in the storage, so they survive restarts:
+// interrupted jobs are put back to pending, and jobs of crashed servers are run again once stale.
+type Runner struct {
+	jobs   geolocation.ImportJobStorer
+	storer geolocation.IPLocationStorer
+	opts   Options
+	logger *slog.Logger
+	wake   chan struct{}
+}
+
+func NewRunner(
+	jobs geolocation.ImportJobStorer,
+	storer geolocation.IPLocationStorer,
+	opts Options,
+	logger *slog.Logger,
+) *Runner {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	return &Runner{jobs: jobs, storer: storer, opts: opts, logger: logger, wake: make(chan struct{}, 1)}
+}
+
+// Submit creates a pending job to import the source: a path relative to the data directory, or an http(s) URL.
+// The format is detected by the extension if empty or auto.
+func (r *Runner) Submit(ctx context.Context, source, format string) (geolocation.ImportJob, error) {
+	f, err := r.checkSource(source, iplocation_importer.Format(format))
+	if err != nil {
+		return geolocation.ImportJob{}, err
+	}
+	job, err := r.jobs.CreateImportJob(ctx, geolocation.ImportJob{Source: source, Format: string(f)})
+	if err != nil {
+		return geolocation.ImportJob{}, fmt.Errorf("could not create import job: %w", err)
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default: // The runner is going to look for jobs anyway.
+	}
+	return job, nil
+}
+
+// checkSource returns the format of the source if it can be imported.
+func (r *Runner) checkSource(source string, format iplocation_importer.Format) (iplocation_importer.Format, error) {
+	name := source
+	if isURL(source) {
+		u, err := url.Parse(source)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("%w: malformed URL: %s", ErrInvalidSource, source)
+		}
+		name = path.Base(u.Path)
+	} else {
+		if !filepath.IsLocal(source) {
+			return "", fmt.Errorf("%w: path must be inside the data directory: %s", ErrInvalidSource, source)
+		}
+		if _, err := os.Stat(filepath.Join(r.opts.DataDir, source)); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidSource, err) //nolint:errorlint
+		}
+	}
+
+	switch format {
+	case "", iplocation_importer.FormatAuto:
+		detected, err := iplocation_importer.DetectFormat(name)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidFormat, err) //nolint:errorlint
+		}
+		return detected, nil
+	case iplocation_importer.FormatCSV, iplocation_importer.FormatJSONL, iplocation_importer.FormatParquet:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidFormat, format)
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+var validUploadName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`).MatchString
+
+// Upload saves the file to the data directory, and returns it as a source to Submit. Existing file is replaced.
+func (r *Runner) Upload(name string, body io.Reader) (string, error) {
+	if !validUploadName(name) {
+		return "", fmt.Errorf("%w: invalid file name: %s", ErrInvalidSource, name)
+	}
+	dir := filepath.Join(r.opts.DataDir, uploadsDir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("could not create uploads directory: %w", err)
+	}
+	if err := writeFile(filepath.Join(dir, name), body); err != nil {
+		return "", err
+	}
+	return path.Join(uploadsDir, name), nil
+}
+
+// writeFile writes to a temporary file first, so a failed write doesn't leave a partial file behind.
+func writeFile(name string, body io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // Fails after the rename only.
+	if _, err = io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("could not write file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("could not write file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("could not rename file: %w", err)
+	}
+	return nil
+}
+
+// Cancel cancels the job, a running job is stopped within the heartbeat interval.
+func (r *Runner) Cancel(ctx context.Context, id int) (geolocation.ImportJob, error) {
+	return r.jobs.CancelImportJob(ctx, id) //nolint:wrapcheck
+}
+
+// Run runs jobs until the context is canceled. The running job is put back to pending then.
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		r.runPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// runPending runs jobs until there is nothing to run.
+func (r *Runner) runPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := r.jobs.ClaimImportJob(ctx, r.opts.StaleAfter)
+		if errors.Is(err, geolocation.ErrImportJobNotFound) {
+			return
+		}
+		if err != nil {
+			r.logger.ErrorContext(ctx, "could not claim import job", "error", err)
+			return
+		}
+		r.runJob(ctx, job)
+	}
+}
+
+func (r *Runner) runJob(ctx context.Context, job geolocation.ImportJob) {
+	logger := r.logger.With("import_job_id", job.ID)
+	if job.CancelRequested { // Canceled while its previous runner had gone.
+		job.State = geolocation.ImportJobCanceled
+		r.finishJob(ctx, logger, job)
+		return
+	}
+	logger.InfoContext(ctx, "import job started", "source", job.Source, "format", job.Format)
+
+	jobCtx, cancelJob := context.WithCancel(ctx)
+	defer cancelJob()
+	tracker := &progress{}
+	var cancelRequested atomic.Bool
+	heartbeatDone := make(chan struct{})
+	heartbeatCtx, stopHeartbeat := context.WithCancel(jobCtx)
+	go func() {
+		defer close(heartbeatDone)
+		r.heartbeat(heartbeatCtx, logger, job, tracker, func() {
+			cancelRequested.Store(true)
+			cancelJob()
+		})
+	}()
+
+	stats, err := r.importSource(jobCtx, job, tracker)
+	stopHeartbeat()
+	<-heartbeatDone
+
+	switch {
+	case err == nil:
+		job.State, job.Statistics = geolocation.ImportJobSucceeded, stats
+	case cancelRequested.Load():
+		job.State, job.Statistics = geolocation.ImportJobCanceled, tracker.snapshot()
+	case ctx.Err() != nil: // The server is shutting down, run the job again after restart.
+		job.State, job.Statistics = geolocation.ImportJobPending, geolocation.ImportStatistics{}
+	case errors.Is(err, geolocation.ErrQualityGateFailed): // All the batches are stored, keep the gate results.
+		job.State, job.Statistics, job.Error = geolocation.ImportJobFailed, stats, err.Error()
+	default:
+		job.State, job.Statistics, job.Error = geolocation.ImportJobFailed, tracker.snapshot(), err.Error()
+	}
+	r.finishJob(ctx, logger, job)
+}
+
+// heartbeat saves the progress periodically, and calls cancel once the job cancellation is requested.
+func (r *Runner) heartbeat(
+	ctx context.Context,
+	logger *slog.Logger,
+	job geolocation.ImportJob,
+	tracker *progress,
+	cancel func(),
+) {
+	ticker := time.NewTicker(r.opts.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		job.Statistics = tracker.snapshot()
+		updated, err := r.jobs.UpdateImportJob(ctx, job)
+		if err != nil {
+			logger.WarnContext(ctx, "could not save import job progress", "error", err)
+			continue
+		}
+		if updated.CancelRequested {
+			logger.InfoContext(ctx, "import job cancellation requested")
+			cancel()
+			return
+		}
+	}
+}
+
+func (r *Runner) finishJob(ctx context.Context, logger *slog.Logger, job geolocation.ImportJob) {
+	// Saved even if the server is shutting down.
+	if _, err := r.jobs.UpdateImportJob(context.WithoutCancel(ctx), job); err != nil {
+		logger.ErrorContext(ctx, "could not save import job", "state", job.State, "error", err)
+		return
+	}
+	logger.InfoContext(ctx, "import job finished", "state", job.State, "error", job.Error,
+		"dataset_version", job.Statistics.DatasetVersion, "imported", job.Statistics.Imported)
+}
+
+// importSource downloads the source if needed, and imports it to a new dataset.
+func (r *Runner) importSource(
+	ctx context.Context,
+	job geolocation.ImportJob,
+	tracker *progress,
+) (geolocation.ImportStatistics, error) {
+	name := filepath.Join(r.opts.DataDir, job.Source)
+	if isURL(job.Source) {
+		var err error
+		if name, err = r.download(ctx, job.Source); err != nil {
+			return geolocation.ImportStatistics{}, err
+		}
+		defer os.Remove(name)
+	}
+
+	importer, closer, err := iplocation_importer.OpenFile(name, iplocation_importer.Format(job.Format))
+	if err != nil {
+		return geolocation.ImportStatistics{}, fmt.Errorf("could not open source: %w", err)
+	}
+	defer closer.Close()
+	input, err := import_report.DescribeInput(name, job.Format)
+	if err != nil {
+		return geolocation.ImportStatistics{}, fmt.Errorf("could not read source: %w", err)
+	}
+	source := geolocation.Provenance{
+		SourceID:    "sha256:" + input.SHA256,
+		SourceName:  job.Source,
+		ImportRunID: fmt.Sprintf("job-%d", job.ID),
+	}
+	return geolocation.ImportIPLocations(ctx, importer, r.storer, //nolint:wrapcheck
+		geolocation.ImportOptions{Progress: tracker.report, Gates: r.opts.Gates, Profiler: r.opts.Profiler,
+			Remover: r.opts.Remover, Source: source})
+}
+
+// download saves the URL to a temporary file in the data directory, as importers need random access to Parquet.
+func (r *Runner) download(ctx context.Context, source string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %w", err)
+	}
+	resp, err := r.opts.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not download source: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not download source: %s", resp.Status)
+	}
+
+	f, err := os.CreateTemp(r.opts.DataDir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("could not create file: %w", err)
+	}
+	_, err = io.Copy(f, resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("could not download source: %w", err)
+	}
+	return f.Name(), nil
+}
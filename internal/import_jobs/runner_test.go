@@ -0,0 +1,305 @@
+package import_jobs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+const testCSV = "ip_address,country_code,country,city,latitude,longitude,mystery_value\n" +
+	"8.8.8.8,UK,United Kingdom,London,51.5,-0.1,42\n" +
+	"8.8.8.8,UK,United Kingdom,London,51.5,-0.1,42\n" +
+	"8.8.8.X,UK,United Kingdom,London,51.5,-0.1,42\n" +
+	"9.9.9.9,US,United States,New York,40.7,-74.0,42\n"
+
+func newTestRunner(t *testing.T, storer geolocation.IPLocationStorer) (*Runner, *jobsFake) {
+	t.Helper()
+	jobs := &jobsFake{}
+	runner := NewRunner(jobs, storer, Options{
+		DataDir:           t.TempDir(),
+		PollInterval:      time.Hour,
+		HeartbeatInterval: time.Millisecond,
+		StaleAfter:        time.Minute,
+	}, logging.Discard())
+	return runner, jobs
+}
+
+func TestRunner_Submit(t *testing.T) {
+	t.Parallel()
+	runner, _ := newTestRunner(t, nil)
+	ctx := context.Background()
+	source, err := runner.Upload("data.csv", strings.NewReader(testCSV))
+	require.NoError(t, err)
+	assert.Equal(t, "uploads/data.csv", source)
+
+	job, err := runner.Submit(ctx, source, "")
+	require.NoError(t, err)
+	assert.Equal(t, "csv", job.Format)
+	assert.Equal(t, geolocation.ImportJobPending, job.State)
+
+	job, err = runner.Submit(ctx, "https://example.com/dumps/latest.parquet?token=1", "auto")
+	require.NoError(t, err)
+	assert.Equal(t, "parquet", job.Format)
+
+	tests := []struct {
+		source, format string
+		wantErr        error
+	}{
+		{source: "../etc/passwd", format: "csv", wantErr: ErrInvalidSource},
+		{source: "/etc/passwd", format: "csv", wantErr: ErrInvalidSource},
+		{source: "uploads/missing.csv", wantErr: ErrInvalidSource},
+		{source: "https://", format: "csv", wantErr: ErrInvalidSource},
+		{source: source, format: "xml", wantErr: ErrInvalidFormat},
+		{source: "https://example.com/dump", wantErr: ErrInvalidFormat},
+	}
+	for _, tt := range tests {
+		_, err = runner.Submit(ctx, tt.source, tt.format)
+		require.ErrorIs(t, err, tt.wantErr, tt.source)
+	}
+}
+
+func TestRunner_Upload_InvalidName(t *testing.T) {
+	t.Parallel()
+	runner, _ := newTestRunner(t, nil)
+	for _, name := range []string{"", "../data.csv", ".hidden", "a/b.csv"} {
+		_, err := runner.Upload(name, strings.NewReader(testCSV))
+		require.ErrorIs(t, err, ErrInvalidSource, name)
+	}
+}
+
+func TestRunner_runPending(t *testing.T) {
+	t.Parallel()
+	storer := &storerFake{}
+	runner, jobs := newTestRunner(t, storer)
+	ctx := context.Background()
+	source, err := runner.Upload("data.csv", strings.NewReader(testCSV))
+	require.NoError(t, err)
+	first, err := runner.Submit(ctx, source, "csv")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(runner.opts.DataDir, "broken.csv"), []byte("a,b\n"), 0o600))
+	second, err := runner.Submit(ctx, "broken.csv", "csv")
+	require.NoError(t, err)
+
+	runner.runPending(ctx)
+
+	job := jobs.get(first.ID)
+	assert.Equal(t, geolocation.ImportJobSucceeded, job.State)
+	assert.Equal(t, 2, job.Statistics.Imported)
+	assert.Equal(t, 1, job.Statistics.Duplicated)
+	assert.Equal(t, 1, job.Statistics.NonValid)
+	assert.Equal(t, 1, job.Statistics.DatasetVersion)
+	assert.Equal(t, []int{1}, storer.activated)
+
+	job = jobs.get(second.ID)
+	assert.Equal(t, geolocation.ImportJobFailed, job.State)
+	assert.NotEmpty(t, job.Error)
+}
+
+func TestRunner_runPending_QualityGateFailed(t *testing.T) {
+	t.Parallel()
+	storer := &storerFake{}
+	runner, jobs := newTestRunner(t, storer)
+	runner.opts.Gates = geolocation.QualityGates{MinRecords: 3}
+	ctx := context.Background()
+	source, err := runner.Upload("data.csv", strings.NewReader(testCSV))
+	require.NoError(t, err)
+	submitted, err := runner.Submit(ctx, source, "csv")
+	require.NoError(t, err)
+
+	runner.runPending(ctx)
+
+	job := jobs.get(submitted.ID)
+	assert.Equal(t, geolocation.ImportJobFailed, job.State)
+	assert.Contains(t, job.Error, "min_records: 2 not >= 3")
+	assert.Equal(t, 1, job.Statistics.DatasetVersion)
+	assert.Equal(t, []geolocation.QualityGateResult{
+		{Gate: geolocation.GateMinRecords, Value: 2, Threshold: 3},
+	}, job.Statistics.QualityGates)
+	assert.Empty(t, storer.activated)
+}
+
+func TestRunner_runPending_Download(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dump.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testCSV))
+	}))
+	defer server.Close()
+	runner, jobs := newTestRunner(t, &storerFake{})
+	ctx := context.Background()
+	found, err := runner.Submit(ctx, server.URL+"/dump.csv", "")
+	require.NoError(t, err)
+	missing, err := runner.Submit(ctx, server.URL+"/missing.csv", "")
+	require.NoError(t, err)
+
+	runner.runPending(ctx)
+
+	assert.Equal(t, geolocation.ImportJobSucceeded, jobs.get(found.ID).State)
+	assert.Equal(t, geolocation.ImportJobFailed, jobs.get(missing.ID).State)
+	assert.Contains(t, jobs.get(missing.ID).Error, "404")
+	entries, err := os.ReadDir(runner.opts.DataDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "downloads are removed")
+}
+
+func TestRunner_runPending_Cancel(t *testing.T) {
+	t.Parallel()
+	storer := &storerFake{block: true}
+	runner, jobs := newTestRunner(t, storer)
+	ctx := context.Background()
+	source, err := runner.Upload("data.csv", strings.NewReader(testCSV))
+	require.NoError(t, err)
+	submitted, err := runner.Submit(ctx, source, "csv")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		runner.runPending(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return jobs.get(submitted.ID).State == geolocation.ImportJobRunning },
+		time.Second, time.Millisecond)
+	_, err = runner.Cancel(ctx, submitted.ID)
+	require.NoError(t, err)
+	<-done
+
+	job := jobs.get(submitted.ID)
+	assert.Equal(t, geolocation.ImportJobCanceled, job.State)
+	assert.Equal(t, 1, job.Statistics.DatasetVersion)
+	assert.Empty(t, storer.activated)
+}
+
+func TestRunner_runPending_Shutdown(t *testing.T) {
+	t.Parallel()
+	runner, jobs := newTestRunner(t, &storerFake{block: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	source, err := runner.Upload("data.csv", strings.NewReader(testCSV))
+	require.NoError(t, err)
+	submitted, err := runner.Submit(ctx, source, "csv")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		runner.runPending(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return jobs.get(submitted.ID).State == geolocation.ImportJobRunning },
+		time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, geolocation.ImportJobPending, jobs.get(submitted.ID).State, "the job is run again after restart")
+}
+
+// jobsFake keeps jobs in memory, staleness is not supported.
+type jobsFake struct {
+	mu   sync.Mutex
+	jobs []geolocation.ImportJob
+}
+
+func (f *jobsFake) get(id int) geolocation.ImportJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jobs[id-1]
+}
+
+func (f *jobsFake) CreateImportJob(_ context.Context, job geolocation.ImportJob) (geolocation.ImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job.ID, job.State, job.CreatedAt = len(f.jobs)+1, geolocation.ImportJobPending, time.Now()
+	f.jobs = append(f.jobs, job)
+	return job, nil
+}
+
+func (f *jobsFake) FetchImportJob(_ context.Context, id int) (geolocation.ImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id < 1 || id > len(f.jobs) {
+		return geolocation.ImportJob{}, geolocation.ErrImportJobNotFound
+	}
+	return f.jobs[id-1], nil
+}
+
+func (f *jobsFake) ListImportJobs(context.Context, int) ([]geolocation.ImportJob, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *jobsFake) ClaimImportJob(context.Context, time.Duration) (geolocation.ImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.jobs {
+		if f.jobs[i].State == geolocation.ImportJobPending {
+			f.jobs[i].State = geolocation.ImportJobRunning
+			return f.jobs[i], nil
+		}
+	}
+	return geolocation.ImportJob{}, geolocation.ErrImportJobNotFound
+}
+
+func (f *jobsFake) UpdateImportJob(_ context.Context, job geolocation.ImportJob) (geolocation.ImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stored := &f.jobs[job.ID-1]
+	stored.State, stored.Statistics, stored.Error = job.State, job.Statistics, job.Error
+	return *stored, nil
+}
+
+func (f *jobsFake) CancelImportJob(_ context.Context, id int) (geolocation.ImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job := &f.jobs[id-1]
+	if job.State.Finished() {
+		return geolocation.ImportJob{}, geolocation.ErrImportJobFinished
+	}
+	job.CancelRequested = true
+	if job.State == geolocation.ImportJobPending {
+		job.State = geolocation.ImportJobCanceled
+	}
+	return *job, nil
+}
+
+// storerFake blocks storing until the context is canceled if block is set.
+type storerFake struct {
+	block     bool
+	mu        sync.Mutex
+	datasets  int
+	activated []int
+}
+
+func (s *storerFake) CreateDataset(context.Context) (geolocation.Dataset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.datasets++
+	return geolocation.Dataset{Version: s.datasets}, nil
+}
+
+func (s *storerFake) StoreIPLocations(ctx context.Context, _ int, _ []geolocation.IPLocation) error {
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (s *storerFake) ActivateDataset(_ context.Context, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activated = append(s.activated, version)
+	return nil
+}
@@ -0,0 +1,146 @@
+package import_progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Mode - how the import progress is rendered.
+type Mode string
+
+const (
+	ModeAuto Mode = "auto" // ModeBar on a terminal, ModeLog otherwise.
+	ModeBar  Mode = "bar"
+	ModeLog  Mode = "log"
+	ModeJSON Mode = "json"
+	ModeNone Mode = "none"
+)
+
+// ResolveMode replaces ModeAuto by ModeBar if f is a terminal, by ModeLog otherwise.
+func ResolveMode(mode Mode, f *os.File) Mode {
+	if mode != ModeAuto {
+		return mode
+	}
+	if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return ModeBar
+	}
+	return ModeLog
+}
+
+// NewReporter returns geolocation.ImportOptions.Progress callback rendering the progress in the resolved mode:
+// a progress bar redrawn in w, log records at most once per interval (and the final one), or a JSON object per
+// line written to w on every call.
+func NewReporter(
+	mode Mode,
+	w io.Writer,
+	interval time.Duration,
+	logger *slog.Logger,
+) (func(geolocation.ImportProgress), error) {
+	switch mode {
+	case ModeBar:
+		return func(p geolocation.ImportProgress) { writeBar(w, p) }, nil
+	case ModeLog:
+		var logged time.Time
+		return func(p geolocation.ImportProgress) {
+			if now := time.Now(); p.Done || now.Sub(logged) >= interval {
+				logged = now
+				logProgress(logger, p)
+			}
+		}, nil
+	case ModeJSON:
+		encoder := json.NewEncoder(w)
+		return func(p geolocation.ImportProgress) {
+			if err := encoder.Encode(newJSONProgress(p)); err != nil {
+				logger.Warn("could not write progress", "error", err)
+			}
+		}, nil
+	case ModeNone:
+		return nil, nil
+	case ModeAuto:
+	}
+	return nil, fmt.Errorf("unsupported progress mode: %s", mode)
+}
+
+const barWidth = 30
+
+// writeBar redraws the line, the bar is shown only if the processed fraction is known.
+func writeBar(w io.Writer, p geolocation.ImportProgress) {
+	s := p.Statistics
+	var line strings.Builder
+	line.WriteString("\r\x1b[K") // Erase the previous line.
+	if p.Processed > 0 {
+		done := int(p.Processed * barWidth)
+		fmt.Fprintf(&line, "[%s%s] %5.1f%% | ",
+			strings.Repeat("#", done), strings.Repeat("-", barWidth-done), p.Processed*100)
+	}
+	fmt.Fprintf(&line, "%d imported, %d non-valid, %d duplicated | %.0f rows/s | %s elapsed",
+		s.Imported, s.NonValid, s.Duplicated, p.RowsPerSecond, s.TimeSpent.Round(time.Second))
+	if p.ETA > 0 {
+		fmt.Fprintf(&line, " | ETA %s", p.ETA.Round(time.Second))
+	}
+	if p.Done {
+		line.WriteString("\n")
+	}
+	_, _ = io.WriteString(w, line.String()) // Progress is not worth failing the import.
+}
+
+func logProgress(logger *slog.Logger, p geolocation.ImportProgress) {
+	s := p.Statistics
+	attrs := []any{"dataset_version", s.DatasetVersion, "imported", s.Imported, "non_valid", s.NonValid,
+		"duplicated", s.Duplicated, "elapsed", s.TimeSpent, "rows_per_second", int(p.RowsPerSecond)}
+	if p.Processed > 0 {
+		attrs = append(attrs, "processed", fmt.Sprintf("%.1f%%", p.Processed*100))
+	}
+	if p.ETA > 0 {
+		attrs = append(attrs, "eta", p.ETA.Round(time.Second))
+	}
+	logger.Info("import progress", attrs...)
+}
+
+// jsonProgress - machine-readable progress, durations are in seconds.
+type jsonProgress struct {
+	DatasetVersion  int            `json:"dataset_version"`
+	Imported        int            `json:"imported"`
+	NonValid        int            `json:"non_valid"`
+	NonValidReasons map[string]int `json:"non_valid_reasons,omitempty"`
+	Duplicated      int            `json:"duplicated"`
+	ElapsedSeconds  float64        `json:"elapsed_seconds"`
+	RowsPerSecond   float64        `json:"rows_per_second"`
+	Processed       *float64       `json:"processed,omitempty"`   // Fraction of the source, missing if unknown.
+	ETASeconds      *float64       `json:"eta_seconds,omitempty"` // Missing if unknown.
+	Done            bool           `json:"done"`
+}
+
+func newJSONProgress(p geolocation.ImportProgress) jsonProgress {
+	s := p.Statistics
+	progress := jsonProgress{
+		DatasetVersion: s.DatasetVersion,
+		Imported:       s.Imported,
+		NonValid:       s.NonValid,
+		Duplicated:     s.Duplicated,
+		ElapsedSeconds: s.TimeSpent.Seconds(),
+		RowsPerSecond:  p.RowsPerSecond,
+		Done:           p.Done,
+	}
+	for reason, n := range s.NonValidReasons {
+		if progress.NonValidReasons == nil {
+			progress.NonValidReasons = make(map[string]int, len(s.NonValidReasons))
+		}
+		progress.NonValidReasons[string(reason)] = n
+	}
+	if p.Processed > 0 {
+		progress.Processed = &p.Processed
+	}
+	if p.ETA > 0 {
+		eta := p.ETA.Seconds()
+		progress.ETASeconds = &eta
+	}
+	return progress
+}
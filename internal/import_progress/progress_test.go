@@ -0,0 +1,107 @@
+package import_progress
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+var (
+	running = geolocation.ImportProgress{
+		Statistics: geolocation.ImportStatistics{Imported: 100, NonValid: 10, Duplicated: 5, DatasetVersion: 3,
+			TimeSpent: 2 * time.Second, NonValidReasons: map[geolocation.NonValidReason]int{geolocation.NonValidIP: 10}},
+		RowsPerSecond: 57.5,
+		Processed:     0.25,
+		ETA:           6 * time.Second,
+	}
+	done = geolocation.ImportProgress{
+		Statistics:    geolocation.ImportStatistics{Imported: 400, DatasetVersion: 3, TimeSpent: 8 * time.Second},
+		RowsPerSecond: 50,
+		Processed:     1,
+		Done:          true,
+	}
+)
+
+func TestResolveMode(t *testing.T) {
+	t.Parallel()
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, ModeLog, ResolveMode(ModeAuto, f))
+	assert.Equal(t, ModeJSON, ResolveMode(ModeJSON, f))
+}
+
+func TestNewReporter_Bar(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	report, err := NewReporter(ModeBar, &out, time.Minute, logging.Discard())
+	require.NoError(t, err)
+	report(running)
+	report(geolocation.ImportProgress{Statistics: geolocation.ImportStatistics{Imported: 1}})
+	report(done)
+	assert.Equal(t, "\r\x1b[K[#######-----------------------]  25.0% | 100 imported, 10 non-valid, 5 duplicated | "+
+		"58 rows/s | 2s elapsed | ETA 6s"+
+		"\r\x1b[K1 imported, 0 non-valid, 0 duplicated | 0 rows/s | 0s elapsed"+
+		"\r\x1b[K[##############################] 100.0% | 400 imported, 0 non-valid, 0 duplicated | "+
+		"50 rows/s | 8s elapsed\n", out.String())
+}
+
+func TestNewReporter_Log(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	report, err := NewReporter(ModeLog, nil, time.Hour, logger)
+	require.NoError(t, err)
+	report(running)
+	report(running) // Throttled.
+	report(done)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `level=INFO msg="import progress" dataset_version=3 imported=100 non_valid=10 duplicated=5 `+
+		`elapsed=2s rows_per_second=57 processed=25.0% eta=6s`, lines[0])
+	assert.Contains(t, lines[1], "imported=400")
+}
+
+func TestNewReporter_JSON(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	report, err := NewReporter(ModeJSON, &out, time.Hour, logging.Discard())
+	require.NoError(t, err)
+	report(running)
+	report(geolocation.ImportProgress{})
+	report(done)
+	assert.Equal(t, `{"dataset_version":3,"imported":100,"non_valid":10,"non_valid_reasons":{"invalid_ip":10},`+
+		`"duplicated":5,"elapsed_seconds":2,"rows_per_second":57.5,"processed":0.25,"eta_seconds":6,"done":false}`+"\n"+
+		`{"dataset_version":0,"imported":0,"non_valid":0,"duplicated":0,"elapsed_seconds":0,"rows_per_second":0,`+
+		`"done":false}`+"\n"+
+		`{"dataset_version":3,"imported":400,"non_valid":0,"duplicated":0,"elapsed_seconds":8,"rows_per_second":50,`+
+		`"processed":1,"done":true}`+"\n", out.String())
+}
+
+func TestNewReporter_Modes(t *testing.T) {
+	t.Parallel()
+	report, err := NewReporter(ModeNone, nil, time.Hour, logging.Discard())
+	require.NoError(t, err)
+	assert.Nil(t, report)
+	_, err = NewReporter(ModeAuto, nil, time.Hour, logging.Discard())
+	require.Error(t, err, "must be resolved")
+	_, err = NewReporter("xml", nil, time.Hour, logging.Discard())
+	require.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package import_report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Format of the report.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Status - outcome of the import.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusRejected  Status = "rejected" // Quality gates failed, the dataset is not activated.
+	StatusFailed    Status = "failed"
+)
+
+// Input - metadata of the imported file.
+type Input struct {
+	Path      string `json:"path" yaml:"path"`
+	Format    string `json:"format" yaml:"format"`
+	SizeBytes int64  `json:"size_bytes" yaml:"size_bytes"`
+	SHA256    string `json:"sha256" yaml:"sha256"`
+}
+
+// Report - result of an import run.
+type Report struct {
+	Input           Input          `json:"input" yaml:"input"`
+	Status          Status         `json:"status" yaml:"status"`
+	ExitCode        int            `json:"exit_code" yaml:"exit_code"`
+	Error           string         `json:"error,omitempty" yaml:"error,omitempty"`
+	StartedAt       time.Time      `json:"started_at" yaml:"started_at"`
+	FinishedAt      time.Time      `json:"finished_at" yaml:"finished_at"`
+	DurationSeconds float64        `json:"duration_seconds" yaml:"duration_seconds"`
+	DatasetVersion  int            `json:"dataset_version,omitempty" yaml:"dataset_version,omitempty"`
+	Total           int            `json:"total" yaml:"total"`
+	Imported        int            `json:"imported" yaml:"imported"`
+	NonValid        int            `json:"non_valid" yaml:"non_valid"`
+	NonValidReasons map[string]int `json:"non_valid_reasons,omitempty" yaml:"non_valid_reasons,omitempty"`
+	NonValidRatio   float64        `json:"non_valid_ratio" yaml:"non_valid_ratio"`
+	Duplicated      int            `json:"duplicated" yaml:"duplicated"`
+	RowsPerSecond   float64        `json:"rows_per_second" yaml:"rows_per_second"`
+	QualityGates    []QualityGate  `json:"quality_gates,omitempty" yaml:"quality_gates,omitempty"`
+	// DatasetRecords - records of the dataset counted by the database, missing if the dataset is not activated.
+	DatasetRecords *int `json:"dataset_records,omitempty" yaml:"dataset_records,omitempty"`
+}
+
+// QualityGate - result of a quality gate checked before activation of the dataset.
+type QualityGate struct {
+	Gate      string  `json:"gate" yaml:"gate"`
+	Value     float64 `json:"value" yaml:"value"`
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	Passed    bool    `json:"passed" yaml:"passed"`
+}
+
+// DescribeInput returns metadata of the file, its checksum takes an extra read of the whole file.
+func DescribeInput(path, format string) (Input, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Input{}, fmt.Errorf("could not open file: %w", err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return Input{}, fmt.Errorf("could not read file: %w", err)
+	}
+	return Input{Path: path, Format: format, SizeBytes: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// New creates the report of the import finished now, importErr is the error returned by the import.
+func New(input Input, startedAt time.Time, stats geolocation.ImportStatistics, importErr error) Report {
+	finishedAt := time.Now()
+	r := Report{
+		Input:           input,
+		Status:          StatusSucceeded,
+		StartedAt:       startedAt.UTC(),
+		FinishedAt:      finishedAt.UTC(),
+		DurationSeconds: finishedAt.Sub(startedAt).Seconds(),
+		DatasetVersion:  stats.DatasetVersion,
+		Total:           stats.Total(),
+		Imported:        stats.Imported,
+		NonValid:        stats.NonValid,
+		NonValidRatio:   stats.NonValidRatio(),
+		Duplicated:      stats.Duplicated,
+	}
+	if r.DurationSeconds > 0 {
+		r.RowsPerSecond = float64(r.Total) / r.DurationSeconds
+	}
+	for reason, n := range stats.NonValidReasons {
+		if r.NonValidReasons == nil {
+			r.NonValidReasons = make(map[string]int, len(stats.NonValidReasons))
+		}
+		r.NonValidReasons[string(reason)] = n
+	}
+	for _, g := range stats.QualityGates {
+		r.QualityGates = append(r.QualityGates, QualityGate(g))
+	}
+	switch {
+	case errors.Is(importErr, geolocation.ErrQualityGateFailed):
+		r.Status, r.Error = StatusRejected, importErr.Error()
+	case importErr != nil:
+		r.Status, r.Error = StatusFailed, importErr.Error()
+	}
+	return r
+}
+
+// Write writes the report in the format. Text starts with the lines printed by the importer before the report
+// was introduced, so scripts parsing them keep working.
+func Write(w io.Writer, r Report, format Format) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r) //nolint:wrapcheck
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(r); err != nil {
+			return err //nolint:wrapcheck
+		}
+		return encoder.Close() //nolint:wrapcheck
+	case FormatText:
+		return writeText(w, r)
+	}
+	return fmt.Errorf("unsupported report format: %s", format)
+}
+
+func writeText(w io.Writer, r Report) error {
+	lines := []string{
+		fmt.Sprintf("Time spent(sec): %d", int(r.DurationSeconds)),
+		fmt.Sprintf("Total records found: %d", r.Total),
+		fmt.Sprintf("Non-valid records: %d", r.NonValid),
+		fmt.Sprintf("Duplicated records: %d", r.Duplicated),
+		fmt.Sprintf("Imported records: %d", r.Imported),
+		fmt.Sprintf("Dataset version: %d", r.DatasetVersion),
+		fmt.Sprintf("Status: %s (exit code %d)", r.Status, r.ExitCode),
+	}
+	if r.Error != "" {
+		lines = append(lines, "Error: "+r.Error)
+	}
+	lines = append(lines,
+		fmt.Sprintf("Input: %s (%s, %d bytes)", r.Input.Path, r.Input.Format, r.Input.SizeBytes),
+		"Input SHA-256: "+r.Input.SHA256,
+		"Started at: "+r.StartedAt.Format(time.RFC3339),
+		"Finished at: "+r.FinishedAt.Format(time.RFC3339),
+		fmt.Sprintf("Rows per second: %.0f", r.RowsPerSecond),
+		fmt.Sprintf("Non-valid ratio: %.4f", r.NonValidRatio),
+	)
+	reasons := make([]string, 0, len(r.NonValidReasons))
+	for reason := range r.NonValidReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		lines = append(lines, fmt.Sprintf("Non-valid records (%s): %d", reason, r.NonValidReasons[reason]))
+	}
+	for _, g := range r.QualityGates {
+		lines = append(lines, "Quality gate "+geolocation.QualityGateResult(g).String())
+	}
+	if r.DatasetRecords != nil {
+		lines = append(lines, fmt.Sprintf("Dataset records: %d", *r.DatasetRecords))
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("could not write report: %w", err)
+		}
+	}
+	return nil
+}
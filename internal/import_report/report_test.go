@@ -0,0 +1,129 @@
+package import_report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestDescribeInput(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "dump.csv")
+	require.NoError(t, os.WriteFile(path, []byte("abc"), 0o600))
+	input, err := DescribeInput(path, "csv")
+	require.NoError(t, err)
+	assert.Equal(t, Input{Path: path, Format: "csv", SizeBytes: 3,
+		SHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}, input)
+
+	_, err = DescribeInput(filepath.Join(t.TempDir(), "missing.csv"), "csv")
+	require.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	stats := geolocation.ImportStatistics{Imported: 6, NonValid: 3, Duplicated: 1, DatasetVersion: 2,
+		NonValidReasons: map[geolocation.NonValidReason]int{geolocation.NonValidIP: 3}}
+	started := time.Now().Add(-2 * time.Second)
+
+	r := New(Input{Path: "dump.csv"}, started, stats, nil)
+	assert.Equal(t, StatusSucceeded, r.Status)
+	assert.Empty(t, r.Error)
+	assert.Equal(t, 10, r.Total)
+	assert.InDelta(t, 0.3, r.NonValidRatio, 1e-9)
+	assert.InDelta(t, 5, r.RowsPerSecond, 0.1)
+	assert.Equal(t, map[string]int{"invalid_ip": 3}, r.NonValidReasons)
+
+	stats.QualityGates = []geolocation.QualityGateResult{
+		{Gate: geolocation.GateMaxNonValidRatio, Value: 0.3, Threshold: 0.1},
+	}
+	r = New(Input{}, started, stats, fmt.Errorf("%w: max_non_valid_ratio: 0.3 not <= 0.1",
+		geolocation.ErrQualityGateFailed))
+	assert.Equal(t, StatusRejected, r.Status)
+	assert.Equal(t, "quality gate failed: max_non_valid_ratio: 0.3 not <= 0.1", r.Error)
+	assert.Equal(t, []QualityGate{{Gate: "max_non_valid_ratio", Value: 0.3, Threshold: 0.1}}, r.QualityGates)
+
+	r = New(Input{}, started, geolocation.ImportStatistics{}, errors.New("no db"))
+	assert.Equal(t, StatusFailed, r.Status)
+	assert.Zero(t, r.NonValidRatio)
+}
+
+var report = Report{
+	Input:           Input{Path: "dump.csv", Format: "csv", SizeBytes: 3, SHA256: "ba78"},
+	Status:          StatusSucceeded,
+	StartedAt:       time.Date(2022, 7, 17, 8, 27, 24, 0, time.UTC),
+	FinishedAt:      time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC),
+	DurationSeconds: 20.5,
+	DatasetVersion:  2,
+	Total:           10,
+	Imported:        6,
+	NonValid:        3,
+	NonValidReasons: map[string]int{"invalid_ip": 2, "invalid_city": 1},
+	NonValidRatio:   0.3,
+	Duplicated:      1,
+	RowsPerSecond:   0.5,
+	QualityGates: []QualityGate{
+		{Gate: "min_records", Value: 6, Threshold: 5, Passed: true},
+		{Gate: "max_non_valid_ratio", Value: 0.3, Threshold: 0.5, Passed: true},
+	},
+	DatasetRecords: func() *int { n := 6; return &n }(),
+}
+
+func TestWrite_Text(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, report, FormatText))
+	assert.Equal(t, `Time spent(sec): 20
+Total records found: 10
+Non-valid records: 3
+Duplicated records: 1
+Imported records: 6
+Dataset version: 2
+Status: succeeded (exit code 0)
+Input: dump.csv (csv, 3 bytes)
+Input SHA-256: ba78
+Started at: 2022-07-17T08:27:24Z
+Finished at: 2022-07-17T08:27:44Z
+Rows per second: 0
+Non-valid ratio: 0.3000
+Non-valid records (invalid_city): 1
+Non-valid records (invalid_ip): 2
+Quality gate min_records: 6 >= 5
+Quality gate max_non_valid_ratio: 0.3 <= 0.5
+Dataset records: 6
+`, out.String())
+}
+
+func TestWrite_JSON(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, report, FormatJSON))
+	assert.JSONEq(t, `{"input":{"path":"dump.csv","format":"csv","size_bytes":3,"sha256":"ba78"},
+		"status":"succeeded","exit_code":0,"started_at":"2022-07-17T08:27:24Z","finished_at":"2022-07-17T08:27:44Z",
+		"duration_seconds":20.5,"dataset_version":2,"total":10,"imported":6,"non_valid":3,
+		"non_valid_reasons":{"invalid_city":1,"invalid_ip":2},"non_valid_ratio":0.3,"duplicated":1,
+		"rows_per_second":0.5,"quality_gates":[{"gate":"min_records","value":6,"threshold":5,"passed":true},
+		{"gate":"max_non_valid_ratio","value":0.3,"threshold":0.5,"passed":true}],"dataset_records":6}`, out.String())
+}
+
+func TestWrite_YAML(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	failed := report
+	failed.Status, failed.ExitCode, failed.Error, failed.DatasetRecords = StatusFailed, 1, "no db", nil
+	require.NoError(t, Write(&out, failed, FormatYAML))
+	assert.Contains(t, out.String(), "input:\n  path: dump.csv\n")
+	assert.Contains(t, out.String(), "status: failed\nexit_code: 1\nerror: no db\n")
+	assert.Contains(t, out.String(), "started_at: 2022-07-17T08:27:24Z\n")
+	assert.NotContains(t, out.String(), "dataset_records")
+
+	require.Error(t, Write(&out, report, "xml"))
+}
@@ -0,0 +1,45 @@
+package iplocation_api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// locationETag changes when either the location record or the dataset it is fetched from changes.
+// The representation with provenance has an ETag of its own.
+func locationETag(location geolocation.IPLocation, withProvenance bool) string {
+	if withProvenance {
+		return fmt.Sprintf(`"%d-%x-provenance"`, location.DatasetVersion, location.MD5())
+	}
+	return fmt.Sprintf(`"%d-%x"`, location.DatasetVersion, location.MD5())
+}
+
+// etagMatches implements weak comparison of If-None-Match header value against the current ETag (RFC 9110).
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheControl allows clients to keep responses for maxAge, revalidation by ETag is required if zero. Shared caches
+// must not keep them, as lookups need an API key and count against its quota.
+func cacheControl(maxAge time.Duration) string {
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+}
+
+// setCacheHeaders sets validators of the location, must be called before the status is written.
+func (s *IPLocationServer) setCacheHeaders(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl(s.cacheMaxAge))
+}
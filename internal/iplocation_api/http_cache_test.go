@@ -0,0 +1,51 @@
+package iplocation_api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_locationETag(t *testing.T) {
+	t.Parallel()
+	location := locations[0]
+	location.DatasetVersion = 1
+	etag := locationETag(location, false)
+	assert.Equal(t, etag, locationETag(location, false))
+	assert.NotEqual(t, etag, locationETag(location, true))
+
+	newVersion := location
+	newVersion.DatasetVersion = 2
+	assert.NotEqual(t, etag, locationETag(newVersion, false))
+
+	moved := location
+	moved.City = "Leeds"
+	assert.NotEqual(t, etag, locationETag(moved, false))
+}
+
+func Test_etagMatches(t *testing.T) {
+	t.Parallel()
+	const etag = `"1-abc"`
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{ifNoneMatch: `"1-abc"`, want: true},
+		{ifNoneMatch: `W/"1-abc"`, want: true},
+		{ifNoneMatch: `"2-abc", "1-abc"`, want: true},
+		{ifNoneMatch: `*`, want: true},
+		{ifNoneMatch: `"2-abc"`, want: false},
+		{ifNoneMatch: `1-abc`, want: false},
+		{ifNoneMatch: ``, want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, etagMatches(tt.ifNoneMatch, etag), tt.ifNoneMatch)
+	}
+}
+
+func Test_cacheControl(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "no-cache", cacheControl(0))
+	assert.Equal(t, "private, max-age=300", cacheControl(5*time.Minute))
+}
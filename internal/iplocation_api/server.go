@@ -1,50 +1,155 @@
 package iplocation_api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"net"
+	"io"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/dronnix/search-accomodation/api"
+	"github.com/dronnix/search-accomodation/internal/iplocation_exporter"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/internal/metrics"
 	"github.com/dronnix/search-accomodation/model/geolocation"
 )
 
 // IPLocationServer is handler-implementation for auto-generated API stub.
 type IPLocationServer struct {
 	fetcher geolocation.IPLocationFetcher
+	lister  geolocation.IPLocationLister
+	metrics *metrics.Server
+	logger  *slog.Logger
+	// cacheMaxAge - how long clients and CDNs may use a location without revalidation.
+	cacheMaxAge time.Duration
 }
 
-func NewIpLocationServer(fetcher geolocation.IPLocationFetcher) *IPLocationServer {
-	return &IPLocationServer{fetcher: fetcher}
+func NewIpLocationServer(
+	fetcher geolocation.IPLocationFetcher,
+	lister geolocation.IPLocationLister,
+	m *metrics.Server,
+	logger *slog.Logger,
+	cacheMaxAge time.Duration,
+) *IPLocationServer {
+	return &IPLocationServer{fetcher: fetcher, lister: lister, metrics: m, logger: logger, cacheMaxAge: cacheMaxAge}
 }
 
 // GetV1Iplocation is handler-implementation for auto-generated API stub.
 func (s *IPLocationServer) GetV1Iplocation(w http.ResponseWriter, r *http.Request, params api.GetV1IplocationParams) {
-	ip := net.ParseIP(params.Ip)
-	if ip == nil {
+	ip, err := geolocation.ParseAddr(params.Ip)
+	if err != nil {
+		s.metrics.ObserveLookup(metrics.LookupInvalid)
 		s.sendResponse(http.StatusBadRequest, w, api.Error{ErrorDetails: "Invalid IP address"})
 		return
 	}
+	withProvenance := params.Include != nil && *params.Include == includeProvenance
+	if params.Include != nil && !withProvenance {
+		s.metrics.ObserveLookup(metrics.LookupInvalid)
+		s.sendResponse(http.StatusBadRequest, w, api.Error{ErrorDetails: "Unsupported include"})
+		return
+	}
 
 	location, err := geolocation.PredictIPLocation(r.Context(), ip, s.fetcher)
+	s.metrics.ObserveLookup(metrics.LookupOutcomeOf(err))
 	if err != nil {
 		if errors.Is(err, geolocation.ErrIPLocationNotFound) || errors.Is(err, geolocation.ErrIPLocationAmbiguous) {
 			s.sendResponse(http.StatusNoContent, w, nil)
 		} else {
-			// TODO: Log error, don't expose it to the user.
-			s.sendResponse(http.StatusServiceUnavailable, w, api.Error{ErrorDetails: err.Error()})
+			s.logger.ErrorContext(r.Context(), "could not predict IP location", logging.IP(params.Ip), "error", err)
+			s.sendUnavailable(r.Context(), w)
 		}
 		return
 	}
 
-	s.sendResponse(http.StatusOK, w, api.IpLocation{
+	etag := locationETag(location, withProvenance)
+	s.setCacheHeaders(w, etag)
+	if params.IfNoneMatch != nil && etagMatches(*params.IfNoneMatch, etag) {
+		s.sendResponse(http.StatusNotModified, w, nil)
+		return
+	}
+	response := api.IpLocation{
 		City:        location.City,
 		Country:     location.CountryName,
 		CountryCode: location.CountryCode,
 		Latitude:    location.Lat,
 		Longitude:   location.Lon,
-	})
+	}
+	if withProvenance {
+		response.Provenance = provenanceResponse(location)
+	}
+	s.sendResponse(http.StatusOK, w, response)
+}
+
+// includeProvenance - value of include parameter adding provenance to the location.
+const includeProvenance api.GetV1IplocationParamsInclude = "provenance"
+
+func provenanceResponse(location geolocation.IPLocation) *api.Provenance {
+	p := &api.Provenance{
+		DatasetVersion: location.DatasetVersion,
+		ImportRunId:    location.Provenance.ImportRunID,
+		Line:           location.Provenance.Line,
+		SourceId:       location.Provenance.SourceID,
+		SourceName:     location.Provenance.SourceName,
+	}
+	if !location.Provenance.ObservedAt.IsZero() {
+		observedAt := location.Provenance.ObservedAt.UTC()
+		p.ObservedAt = &observedAt
+	}
+	return p
+}
+
+// GetV1Export is handler-implementation for auto-generated API stub.
+func (s *IPLocationServer) GetV1Export(w http.ResponseWriter, r *http.Request, params api.GetV1ExportParams) {
+	format, filter := iplocation_exporter.FormatCSV, geolocation.ExportFilter{}
+	if params.Format != nil {
+		format = iplocation_exporter.Format(*params.Format)
+	}
+	if params.CountryCode != nil {
+		filter.CountryCode = *params.CountryCode
+	}
+	if params.DatasetVersion != nil {
+		filter.DatasetVersion = *params.DatasetVersion
+	}
+	if filter.DatasetVersion < 0 {
+		s.sendResponse(http.StatusBadRequest, w, api.Error{ErrorDetails: "Invalid dataset version"})
+		return
+	}
+
+	out := &trackingWriter{w: w}
+	exporter, err := iplocation_exporter.NewExporter(format, out)
+	if err != nil {
+		s.sendResponse(http.StatusBadRequest, w, api.Error{ErrorDetails: "Unsupported format"})
+		return
+	}
+
+	// Export takes much longer than usual requests, so the server write timeout is not applicable.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", format.ContentType())
+	_, err = geolocation.ExportIPLocations(r.Context(), filter, s.lister, exporter)
+	if err == nil {
+		return
+	}
+	s.logger.ErrorContext(r.Context(), "could not export IP locations", "error", err)
+	if out.written {
+		panic(http.ErrAbortHandler) // The status is sent already, abort to let the client know the body is incomplete.
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if errors.Is(err, geolocation.ErrDatasetNotFound) {
+		s.sendResponse(http.StatusNotFound, w, api.Error{ErrorDetails: "Dataset not found"})
+		return
+	}
+	s.sendUnavailable(r.Context(), w)
+}
+
+// sendUnavailable responds with a generic error, the details are logged under the request ID.
+func (s *IPLocationServer) sendUnavailable(ctx context.Context, w http.ResponseWriter) {
+	body := api.Error{ErrorDetails: "Service temporarily unavailable"}
+	if id := logging.RequestID(ctx); id != "" {
+		body.RequestId = &id
+	}
+	s.sendResponse(http.StatusServiceUnavailable, w, body)
 }
 
 func (s *IPLocationServer) sendResponse(code int, w http.ResponseWriter, data interface{}) {
@@ -54,6 +159,19 @@ func (s *IPLocationServer) sendResponse(code int, w http.ResponseWriter, data in
 		if err != nil {
 			panic(err) // Exceptional situation - response structure must be marshalable.
 		}
-		_, _ = w.Write(body) // TODO:Log error.
+		if _, err = w.Write(body); err != nil {
+			s.logger.Debug("could not write response", "error", err) // Usually the client has gone.
+		}
 	}
 }
+
+// trackingWriter remembers if anything has been written, so the response status is sent.
+type trackingWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p) //nolint:wrapcheck
+}
@@ -2,24 +2,26 @@ package iplocation_api
 
 import (
 	"context"
+	"errors"
 	"io"
-	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
+	"time"
 
-	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/dronnix/search-accomodation/api"
+	"github.com/dronnix/search-accomodation/internal/logging"
 	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
 )
 
 func Test_ipLocationServer_GetV1Iplocation_OK(t *testing.T) {
 	t.Parallel()
-	fetcher := new(fetcherMock)
-	fetcher.On("FetchLocationsByIP", mock.Anything, mock.Anything).Return(locations[:1], nil).Once()
-	server := NewIpLocationServer(fetcher)
+	fetcher := geolocationtest.NewMemoryStorageWith(locations)
+	server := NewIpLocationServer(fetcher, nil, nil, logging.Discard(), 0)
 	req := httptest.NewRequest(http.MethodGet, "/v1/iplocation?ip=1.2.3.4", nil)
 	w := httptest.NewRecorder()
 	handler := api.Handler(server)
@@ -32,11 +34,93 @@ func Test_ipLocationServer_GetV1Iplocation_OK(t *testing.T) {
 	require.Equal(t, expected, string(body))
 }
 
+func Test_ipLocationServer_GetV1Iplocation_IPv4Mapped(t *testing.T) {
+	t.Parallel()
+	var fetched netip.Addr
+	fetcher := geolocationtest.FetcherFunc(func(_ context.Context, ip netip.Addr) ([]geolocation.IPLocation, error) {
+		fetched = ip
+		return locations[:1], nil
+	})
+	handler := api.Handler(NewIpLocationServer(fetcher, nil, nil, logging.Discard(), 0))
+	req := httptest.NewRequest(http.MethodGet, "/v1/iplocation?ip=::ffff:1.2.3.4", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, netip.AddrFrom4([4]byte{1, 2, 3, 4}), fetched)
+}
+
+func Test_ipLocationServer_GetV1Iplocation_ConditionalGet(t *testing.T) {
+	t.Parallel()
+	fetcher := geolocationtest.NewMemoryStorageWith(locations)
+	handler := api.Handler(NewIpLocationServer(fetcher, nil, nil, logging.Discard(), time.Hour))
+	get := func(ifNoneMatch string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/v1/iplocation?ip=1.2.3.4", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	res := get("")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	require.Regexp(t, `^"1-[0-9a-f]{32}"$`, etag)
+	require.Equal(t, "private, max-age=3600", res.Header.Get("Cache-Control"))
+
+	res = get(`"0-0123", ` + etag)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusNotModified, res.StatusCode)
+	require.Equal(t, etag, res.Header.Get("ETag"))
+	body, _ := io.ReadAll(res.Body)
+	require.Empty(t, body)
+
+	res = get(`"0-0123"`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func Test_ipLocationServer_GetV1Iplocation_Provenance(t *testing.T) {
+	t.Parallel()
+	located := locations[0]
+	located.Provenance = geolocation.Provenance{SourceID: "sha256:9f86d0", SourceName: "data_dump.csv",
+		ImportRunID: "run-1", Line: 2, ObservedAt: time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC)}
+	fetcher := geolocationtest.NewMemoryStorageWith([]geolocation.IPLocation{located, locations[1]})
+	handler := api.Handler(NewIpLocationServer(fetcher, nil, nil, logging.Discard(), 0))
+	get := func(query string) (*http.Response, string) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/iplocation?"+query, nil))
+		res := w.Result()
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	res, body := get("ip=1.2.3.4&include=provenance")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	const expected = `{"city":"London","country":"United Kingdom","country_code":"UK","latitude":51.5,"longitude":-0.1,` +
+		`"provenance":{"dataset_version":1,"import_run_id":"run-1","line":2,"observed_at":"2022-07-17T08:27:44Z",` +
+		`"source_id":"sha256:9f86d0","source_name":"data_dump.csv"}}`
+	require.Equal(t, expected, body)
+
+	res, body = get("ip=1.2.3.5&include=provenance") // Imported without provenance.
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Contains(t, body, `"provenance":{"dataset_version":1,"import_run_id":"","line":0,"source_id":"",`)
+
+	res, _ = get("ip=1.2.3.4&include=history")
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
 func Test_ipLocationServer_GetV1Iplocation_Ambiguous(t *testing.T) {
 	t.Parallel()
-	fetcher := new(fetcherMock)
-	fetcher.On("FetchLocationsByIP", mock.Anything, mock.Anything).Return(locations, nil).Once()
-	server := NewIpLocationServer(fetcher)
+	ambiguous := locations[1]
+	ambiguous.IP = locations[0].IP
+	fetcher := geolocationtest.NewMemoryStorageWith([]geolocation.IPLocation{locations[0], ambiguous})
+	server := NewIpLocationServer(fetcher, nil, nil, logging.Discard(), 0)
 	req := httptest.NewRequest(http.MethodGet, "/v1/iplocation?ip=1.2.3.4", nil)
 	w := httptest.NewRecorder()
 	handler := api.Handler(server)
@@ -46,18 +130,91 @@ func Test_ipLocationServer_GetV1Iplocation_Ambiguous(t *testing.T) {
 	require.Equal(t, http.StatusNoContent, res.StatusCode)
 }
 
-type fetcherMock struct {
-	mock.Mock
+func Test_ipLocationServer_GetV1Iplocation_Unavailable(t *testing.T) {
+	t.Parallel()
+	fetcher := geolocationtest.NewMemoryStorageWith(locations)
+	fetcher.SetFaults(geolocationtest.Faults{Fetch: errors.New("connection refused")})
+	server := NewIpLocationServer(fetcher, nil, nil, logging.Discard(), 0)
+	req := httptest.NewRequest(http.MethodGet, "/v1/iplocation?ip=1.2.3.4", nil)
+	req.Header.Set(logging.RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	logging.HTTPMiddleware(logging.Discard())(api.Handler(server)).ServeHTTP(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	require.Equal(t, "req-1", res.Header.Get(logging.RequestIDHeader))
+	body, _ := io.ReadAll(res.Body)
+	require.Equal(t, `{"errorDetails":"Service temporarily unavailable","requestId":"req-1"}`, string(body))
+}
+
+func Test_ipLocationServer_GetV1Export_CSV(t *testing.T) {
+	t.Parallel()
+	lister := &geolocationtest.ListerFake{Batches: [][]geolocation.IPLocation{locations[:1], locations[1:]}}
+	server := NewIpLocationServer(nil, lister, nil, logging.Discard(), 0)
+	req := httptest.NewRequest(http.MethodGet, "/v1/export?country_code=UK&dataset_version=2", nil)
+	w := httptest.NewRecorder()
+	api.Handler(server).ServeHTTP(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/csv", res.Header.Get("Content-Type"))
+	require.Equal(t, geolocation.ExportFilter{CountryCode: "UK", DatasetVersion: 2}, lister.Filter)
+	body, _ := io.ReadAll(res.Body)
+	const expected = "ip_address,country_code,country,city,latitude,longitude,mystery_value\n" +
+		"1.2.3.4,UK,United Kingdom,London,51.5,-0.1,42\n" +
+		"1.2.3.5,UK,United Kingdom,London,51.5,-0.1,42\n"
+	require.Equal(t, expected, string(body))
+}
+
+func Test_ipLocationServer_GetV1Export_JSONL(t *testing.T) {
+	t.Parallel()
+	lister := &geolocationtest.ListerFake{Batches: [][]geolocation.IPLocation{locations[:1]}}
+	server := NewIpLocationServer(nil, lister, nil, logging.Discard(), 0)
+	req := httptest.NewRequest(http.MethodGet, "/v1/export?format=jsonl", nil)
+	w := httptest.NewRecorder()
+	api.Handler(server).ServeHTTP(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(res.Body)
+	const expected = `{"ip_address":"1.2.3.4","country_code":"UK","country":"United Kingdom","city":"London",` +
+		`"latitude":51.5,"longitude":-0.1,"mystery_value":42}` + "\n"
+	require.Equal(t, expected, string(body))
 }
 
-func (f *fetcherMock) FetchLocationsByIP(ctx context.Context, ip net.IP) ([]geolocation.IPLocation, error) {
-	args := f.Called(ctx, ip)
-	return args.Get(0).([]geolocation.IPLocation), args.Error(1) //nolint:wrapcheck
+func Test_ipLocationServer_GetV1Export_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		url      string
+		err      error
+		wantCode int
+	}{
+		{name: "unsupported format", url: "/v1/export?format=xml", wantCode: http.StatusBadRequest},
+		{name: "negative version", url: "/v1/export?dataset_version=-1", wantCode: http.StatusBadRequest},
+		{name: "not found", url: "/v1/export", err: geolocation.ErrDatasetNotFound, wantCode: http.StatusNotFound},
+		{name: "db error", url: "/v1/export", err: errors.New("no db"), wantCode: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := NewIpLocationServer(nil, &geolocationtest.ListerFake{Err: tt.err}, nil, logging.Discard(), 0)
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			api.Handler(server).ServeHTTP(w, req)
+			res := w.Result()
+			defer res.Body.Close()
+			require.Equal(t, tt.wantCode, res.StatusCode)
+			require.NotEqual(t, "text/csv", res.Header.Get("Content-Type"))
+		})
+	}
 }
 
 var locations = []geolocation.IPLocation{
 	{
-		IP:          net.IPv4(1, 2, 3, 4),
+		IP:          netip.AddrFrom4([4]byte{1, 2, 3, 4}),
 		CountryCode: "UK",
 		CountryName: "United Kingdom",
 		City:        "London",
@@ -68,7 +225,7 @@ var locations = []geolocation.IPLocation{
 		MysteryValue: 42,
 	},
 	{
-		IP:          net.IPv4(1, 2, 3, 5),
+		IP:          netip.AddrFrom4([4]byte{1, 2, 3, 5}),
 		CountryCode: "UK",
 		CountryName: "United Kingdom",
 		City:        "London",
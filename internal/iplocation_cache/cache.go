@@ -0,0 +1,197 @@
+package iplocation_cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Options of the cache.
+type Options struct {
+	Size        int           // Maximum number of cached IP addresses.
+	TTL         time.Duration // How long found locations are cached.
+	NegativeTTL time.Duration // How long IP addresses without locations are cached, they are not cached if 0.
+}
+
+// Stats - cumulative statistics of the cache.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+// Cache is read-through LRU cache implementing geolocation.IPLocationFetcher on top of another fetcher.
+// Concurrent misses of the same IP address are collapsed into a single fetch.
+// Returned slices are shared between callers, so they must not be modified.
+type Cache struct {
+	fetcher geolocation.IPLocationFetcher
+	opts    Options
+	now     func() time.Time
+	group   singleflight.Group
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List // Front is the most recently used.
+	generation uint64     // Incremented on invalidation, so fetches started before it are not cached.
+	stats      Stats
+}
+
+var _ geolocation.IPLocationFetcher = (*Cache)(nil)
+
+type entry struct {
+	key       string
+	locations []geolocation.IPLocation
+	expiresAt time.Time
+}
+
+func New(fetcher geolocation.IPLocationFetcher, opts Options) *Cache {
+	return &Cache{
+		fetcher: fetcher,
+		opts:    opts,
+		now:     time.Now,
+		entries: make(map[string]*list.Element, opts.Size),
+		lru:     list.New(),
+	}
+}
+
+// FetchLocationsByIP - see geolocation.IPLocationFetcher interface specification.
+func (c *Cache) FetchLocationsByIP(ctx context.Context, ip netip.Addr) ([]geolocation.IPLocation, error) {
+	key := ip.String()
+	locations, generation, ok := c.get(key)
+	if ok {
+		return locations, nil
+	}
+
+	// The fetch is shared, so it must not be canceled by the first caller only.
+	sharedCtx := context.WithoutCancel(ctx)
+	v, err, _ := c.group.Do(strconv.FormatUint(generation, 10)+"/"+key, func() (interface{}, error) {
+		fetched, err := c.fetcher.FetchLocationsByIP(sharedCtx, ip)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // The cache is transparent.
+		}
+		c.put(key, fetched, generation)
+		return fetched, nil
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // The cache is transparent.
+	}
+	return v.([]geolocation.IPLocation), nil
+}
+
+// Invalidate drops all the cached locations.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[string]*list.Element, c.opts.Size)
+	c.lru.Init()
+}
+
+// Stats returns cumulative statistics of the cache.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	return stats
+}
+
+// WatchDataset invalidates the cache when another dataset is activated, polling the active one with the interval.
+// Blocks until the context is done.
+func (c *Cache) WatchDataset(
+	ctx context.Context,
+	fetcher geolocation.DatasetFetcher,
+	interval time.Duration,
+	logger *slog.Logger,
+) {
+	version, err := activeDatasetVersion(ctx, fetcher)
+	if err != nil {
+		logger.WarnContext(ctx, "could not fetch active dataset", "error", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		active, err := activeDatasetVersion(ctx, fetcher)
+		if err != nil {
+			logger.WarnContext(ctx, "could not fetch active dataset", "error", err)
+			continue
+		}
+		if active != version {
+			c.Invalidate()
+			logger.InfoContext(ctx, "cache invalidated", "dataset_version", active)
+			version = active
+		}
+	}
+}
+
+// activeDatasetVersion returns 0 if there is no active dataset.
+func activeDatasetVersion(ctx context.Context, fetcher geolocation.DatasetFetcher) (int, error) {
+	dataset, err := fetcher.FetchDataset(ctx, 0)
+	if errors.Is(err, geolocation.ErrDatasetNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+	return dataset.Version, nil
+}
+
+func (c *Cache) get(key string) (locations []geolocation.IPLocation, generation uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, found := c.entries[key]; found {
+		e := el.Value.(*entry)
+		if c.now().Before(e.expiresAt) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			return e.locations, c.generation, true
+		}
+		c.remove(el)
+	}
+	c.stats.Misses++
+	return nil, c.generation, false
+}
+
+func (c *Cache) put(key string, locations []geolocation.IPLocation, generation uint64) {
+	ttl := c.opts.TTL
+	if len(locations) == 0 {
+		ttl = c.opts.NegativeTTL
+	}
+	if ttl <= 0 || c.opts.Size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return // Fetched before invalidation, may be stale.
+	}
+	if el, found := c.entries[key]; found {
+		c.remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(&entry{key: key, locations: locations, expiresAt: c.now().Add(ttl)})
+	for c.lru.Len() > c.opts.Size {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
@@ -0,0 +1,213 @@
+package iplocation_cache
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+var london = []geolocation.IPLocation{{IP: netip.AddrFrom4([4]byte{1, 2, 3, 4}), CountryCode: "UK", City: "London"}}
+
+// fetcherStub returns london for 1.2.3.4 and nothing for other IPs, counting the calls.
+type fetcherStub struct {
+	calls   atomic.Int32
+	err     error
+	release chan struct{} // Blocks fetches until closed if not nil.
+}
+
+func (f *fetcherStub) FetchLocationsByIP(_ context.Context, ip netip.Addr) ([]geolocation.IPLocation, error) {
+	f.calls.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	if ip == netip.AddrFrom4([4]byte{1, 2, 3, 4}) {
+		return london, nil
+	}
+	return []geolocation.IPLocation{}, nil
+}
+
+func newTestCache(fetcher geolocation.IPLocationFetcher, size int) (*Cache, *time.Time) {
+	now := time.Date(2022, 7, 17, 0, 0, 0, 0, time.UTC)
+	c := New(fetcher, Options{Size: size, TTL: time.Minute, NegativeTTL: time.Second})
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCache_FetchLocationsByIP(t *testing.T) {
+	t.Parallel()
+	fetcher := &fetcherStub{}
+	c, now := newTestCache(fetcher, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		locations, err := c.FetchLocationsByIP(ctx, netip.AddrFrom4([4]byte{1, 2, 3, 4})) // 16-byte form is the same key.
+		require.NoError(t, err)
+		assert.Equal(t, london, locations)
+	}
+	assert.Equal(t, int32(1), fetcher.calls.Load())
+	assert.Equal(t, Stats{Hits: 2, Misses: 1, Size: 1}, c.Stats())
+
+	*now = now.Add(time.Minute)
+	_, err := c.FetchLocationsByIP(ctx, netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetcher.calls.Load(), "expired entry must be fetched again")
+}
+
+func TestCache_NegativeTTL(t *testing.T) {
+	t.Parallel()
+	fetcher := &fetcherStub{}
+	c, now := newTestCache(fetcher, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		locations, err := c.FetchLocationsByIP(ctx, netip.AddrFrom4([4]byte{5, 6, 7, 8}))
+		require.NoError(t, err)
+		assert.Empty(t, locations)
+	}
+	assert.Equal(t, int32(1), fetcher.calls.Load())
+
+	*now = now.Add(time.Second)
+	_, err := c.FetchLocationsByIP(ctx, netip.AddrFrom4([4]byte{5, 6, 7, 8}))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetcher.calls.Load())
+}
+
+func TestCache_ErrorsNotCached(t *testing.T) {
+	t.Parallel()
+	fetcher := &fetcherStub{err: errors.New("connection refused")}
+	c, _ := newTestCache(fetcher, 10)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.FetchLocationsByIP(context.Background(), netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+		require.EqualError(t, err, "connection refused")
+	}
+	assert.Equal(t, int32(2), fetcher.calls.Load())
+	assert.Equal(t, 0, c.Stats().Size)
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+	fetcher := &fetcherStub{}
+	c, _ := newTestCache(fetcher, 2)
+	ctx := context.Background()
+	fetch := func(ip netip.Addr) {
+		_, err := c.FetchLocationsByIP(ctx, ip)
+		require.NoError(t, err)
+	}
+
+	fetch(netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+	fetch(netip.AddrFrom4([4]byte{1, 1, 1, 1}))
+	fetch(netip.AddrFrom4([4]byte{1, 2, 3, 4})) // Now 1.1.1.1 is the least recently used.
+	fetch(netip.AddrFrom4([4]byte{2, 2, 2, 2}))
+	assert.Equal(t, int32(3), fetcher.calls.Load())
+
+	fetch(netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+	assert.Equal(t, int32(3), fetcher.calls.Load())
+	fetch(netip.AddrFrom4([4]byte{1, 1, 1, 1}))
+	assert.Equal(t, int32(4), fetcher.calls.Load())
+	assert.Equal(t, uint64(2), c.Stats().Evictions)
+	assert.Equal(t, 2, c.Stats().Size)
+}
+
+func TestCache_CollapsesConcurrentMisses(t *testing.T) {
+	t.Parallel()
+	fetcher := &fetcherStub{release: make(chan struct{})}
+	c, _ := newTestCache(fetcher, 10)
+
+	const callers = 10
+	wg := sync.WaitGroup{}
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			locations, err := c.FetchLocationsByIP(context.Background(), netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+			assert.NoError(t, err)
+			assert.Equal(t, london, locations)
+		}()
+	}
+	require.Eventually(t, func() bool { return c.Stats().Misses == callers }, time.Second, time.Millisecond)
+	close(fetcher.release)
+	wg.Wait()
+	assert.Equal(t, int32(1), fetcher.calls.Load())
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	t.Parallel()
+	fetcher := &fetcherStub{release: make(chan struct{})}
+	c, _ := newTestCache(fetcher, 10)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := c.FetchLocationsByIP(context.Background(), netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+		assert.NoError(t, err)
+	}()
+	require.Eventually(t, func() bool { return fetcher.calls.Load() == 1 }, time.Second, time.Millisecond)
+	c.Invalidate() // A new dataset is activated while the previous one is fetched.
+	close(fetcher.release)
+	<-done
+	assert.Equal(t, 0, c.Stats().Size, "location fetched before invalidation must not be cached")
+
+	_, err := c.FetchLocationsByIP(context.Background(), netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.Stats().Size)
+	c.Invalidate()
+	assert.Equal(t, 0, c.Stats().Size)
+}
+
+func TestCache_Disabled(t *testing.T) {
+	t.Parallel()
+	fetcher := &fetcherStub{}
+	c := New(fetcher, Options{Size: 10, TTL: time.Minute}) // No negative caching.
+
+	for i := 0; i < 2; i++ {
+		_, err := c.FetchLocationsByIP(context.Background(), netip.AddrFrom4([4]byte{5, 6, 7, 8}))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), fetcher.calls.Load())
+}
+
+type datasetFetcherStub struct {
+	version atomic.Int64
+	calls   atomic.Int32
+}
+
+func (d *datasetFetcherStub) FetchDataset(_ context.Context, _ int) (geolocation.Dataset, error) {
+	d.calls.Add(1)
+	v := int(d.version.Load())
+	if v == 0 {
+		return geolocation.Dataset{}, geolocation.ErrDatasetNotFound
+	}
+	return geolocation.Dataset{Version: v, Active: true}, nil
+}
+
+func TestCache_WatchDataset(t *testing.T) {
+	t.Parallel()
+	c, _ := newTestCache(&fetcherStub{}, 10)
+	datasets := &datasetFetcherStub{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.WatchDataset(ctx, datasets, time.Millisecond, logging.Discard())
+
+	_, err := c.FetchLocationsByIP(ctx, netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+	require.NoError(t, err)
+	require.Equal(t, 1, c.Stats().Size)
+	require.Eventually(t, func() bool { return datasets.calls.Load() > 1 }, time.Second, time.Millisecond)
+	require.Equal(t, 1, c.Stats().Size, "the same dataset is active")
+
+	datasets.version.Store(1)
+	require.Eventually(t, func() bool { return c.Stats().Size == 0 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,63 @@
+package iplocation_exporter
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// CSVExporter exports ip locations to CSV with the same structure iplocation_importer.CSVImporter reads:
+// ip_address,country_code,country,city,latitude,longitude,mystery_value
+type CSVExporter struct {
+	csvWriter *csv.Writer
+}
+
+var _ geolocation.IPLocationExporter = (*CSVExporter)(nil)
+
+// NewCSVExporter creates a CSVExporter to writer. The header is written with the first flush.
+func NewCSVExporter(w io.Writer) *CSVExporter {
+	csvWriter := csv.NewWriter(w)
+	_ = csvWriter.Write(csvHeader) // Buffered, errors are reported by Flush.
+	return &CSVExporter{csvWriter: csvWriter}
+}
+
+// ExportBatch writes and flushes the batch, so it can be streamed.
+func (c *CSVExporter) ExportBatch(ctx context.Context, locations []geolocation.IPLocation) error {
+	rec := make([]string, len(csvHeader))
+	for i := range locations {
+		if i%1024 == 0 && ctx.Err() != nil {
+			return ctx.Err() //nolint:wrapcheck
+		}
+		loc := &locations[i]
+		rec[0] = loc.IP.String()
+		rec[1] = loc.CountryCode
+		rec[2] = loc.CountryName
+		rec[3] = loc.City
+		rec[4] = formatFloat(loc.Lat)
+		rec[5] = formatFloat(loc.Lon)
+		rec[6] = strconv.FormatUint(loc.MysteryValue, 10)
+		if err := c.csvWriter.Write(rec); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	return c.Flush()
+}
+
+func (c *CSVExporter) Flush() error {
+	c.csvWriter.Flush()
+	if err := c.csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to flush csv: %w", err)
+	}
+	return nil
+}
+
+var csvHeader = []string{"ip_address", "country_code", "country", "city", "latitude", "longitude", "mystery_value"}
+
+// formatFloat formats with the minimal precision to parse the same value back.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,70 @@
+package iplocation_exporter_test
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/iplocation_exporter"
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestCSVExporter_RoundTrip(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	exporter := iplocation_exporter.NewCSVExporter(buf)
+	require.NoError(t, exporter.ExportBatch(context.Background(), locations[:1]))
+	require.NoError(t, exporter.ExportBatch(context.Background(), locations[1:]))
+	require.NoError(t, exporter.Flush())
+
+	importer, err := iplocation_importer.NewCSVImporter(buf)
+	require.NoError(t, err)
+	imported, stats, err := importer.ImportNextBatch(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.ImportStatistics{Imported: len(locations)}, stats)
+	assert.Equal(t, fromLine(locations, 2), imported) // After the header.
+}
+
+// fromLine returns the locations as imported from consecutive lines of a file, starting with the line.
+func fromLine(locations []geolocation.IPLocation, line int) []geolocation.IPLocation {
+	res := make([]geolocation.IPLocation, len(locations))
+	for i := range locations {
+		res[i] = locations[i]
+		res[i].Provenance.Line = line + i
+	}
+	return res
+}
+
+func TestCSVExporter_Empty(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	require.NoError(t, iplocation_exporter.NewCSVExporter(buf).Flush())
+	assert.Equal(t, "ip_address,country_code,country,city,latitude,longitude,mystery_value\n", buf.String())
+
+	_, err := iplocation_importer.NewCSVImporter(buf)
+	require.NoError(t, err)
+}
+
+var locations = []geolocation.IPLocation{
+	{
+		IP:           netip.AddrFrom4([4]byte{200, 106, 141, 15}),
+		CountryCode:  "SI",
+		CountryName:  "Nepal",
+		City:         "DuBuquemouth",
+		Coordinate:   geolocation.Coordinate{Lat: -84.87503094689836, Lon: 7.206435933364332},
+		MysteryValue: 7823011346,
+	},
+	{
+		IP:           netip.MustParseAddr("2001:db8::68"),
+		CountryCode:  "NZ",
+		CountryName:  "New Zealand",
+		City:         "Auckland, \"City of Sails\"",
+		Coordinate:   geolocation.Coordinate{Lat: -36.8, Lon: 174.7},
+		MysteryValue: 0,
+	},
+}
@@ -0,0 +1,35 @@
+package iplocation_exporter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Format of the exported data.
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	if f == FormatJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// NewExporter creates an exporter of the given format to writer.
+func NewExporter(format Format, w io.Writer) (geolocation.IPLocationExporter, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVExporter(w), nil
+	case FormatJSONL:
+		return NewJSONLExporter(w), nil
+	}
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
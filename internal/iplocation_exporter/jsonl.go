@@ -0,0 +1,65 @@
+package iplocation_exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// JSONLExporter exports ip locations to JSON Lines with the same fields as CSV, one object per line.
+type JSONLExporter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+var _ geolocation.IPLocationExporter = (*JSONLExporter)(nil)
+
+// NewJSONLExporter creates a JSONLExporter to writer.
+func NewJSONLExporter(w io.Writer) *JSONLExporter {
+	writer := bufio.NewWriter(w)
+	return &JSONLExporter{writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+// ExportBatch writes and flushes the batch, so it can be streamed.
+func (j *JSONLExporter) ExportBatch(ctx context.Context, locations []geolocation.IPLocation) error {
+	for i := range locations {
+		if i%1024 == 0 && ctx.Err() != nil {
+			return ctx.Err() //nolint:wrapcheck
+		}
+		loc := &locations[i]
+		err := j.encoder.Encode(jsonlRecord{
+			IP:           loc.IP.String(),
+			CountryCode:  loc.CountryCode,
+			CountryName:  loc.CountryName,
+			City:         loc.City,
+			Latitude:     loc.Lat,
+			Longitude:    loc.Lon,
+			MysteryValue: loc.MysteryValue,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	return j.Flush()
+}
+
+func (j *JSONLExporter) Flush() error {
+	if err := j.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush jsonl: %w", err)
+	}
+	return nil
+}
+
+type jsonlRecord struct {
+	IP           string  `json:"ip_address"`
+	CountryCode  string  `json:"country_code"`
+	CountryName  string  `json:"country"`
+	City         string  `json:"city"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	MysteryValue uint64  `json:"mystery_value"`
+}
@@ -0,0 +1,37 @@
+package iplocation_exporter_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/iplocation_exporter"
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestJSONLExporter_RoundTrip(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	exporter := iplocation_exporter.NewJSONLExporter(buf)
+	require.NoError(t, exporter.ExportBatch(context.Background(), locations))
+	require.NoError(t, exporter.Flush())
+
+	imported, stats, err := iplocation_importer.NewJSONLImporter(buf).ImportNextBatch(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.ImportStatistics{Imported: len(locations)}, stats)
+	assert.Equal(t, fromLine(locations, 1), imported)
+}
+
+func TestJSONLExporter_Format(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	exporter := iplocation_exporter.NewJSONLExporter(buf)
+	require.NoError(t, exporter.ExportBatch(context.Background(), locations[:1]))
+	const expected = `{"ip_address":"200.106.141.15","country_code":"SI","country":"Nepal","city":"DuBuquemouth",` +
+		`"latitude":-84.87503094689836,"longitude":7.206435933364332,"mystery_value":7823011346}` + "\n"
+	assert.Equal(t, expected, buf.String())
+}
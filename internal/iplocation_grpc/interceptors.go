@@ -0,0 +1,82 @@
+package iplocation_grpc
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+)
+
+// requestIDMetadata is the metadata key to pass request ID, the same as HTTP header.
+const requestIDMetadata = "x-request-id"
+
+// LoggingUnaryInterceptor assigns request ID to every call and logs it when done.
+func LoggingUnaryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx = withRequestID(ctx)
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, err, start)
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor assigns request ID to every stream and logs it when done.
+func LoggingStreamInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := withRequestID(ss.Context())
+		err := handler(srv, &requestIDStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, logger, info.FullMethod, err, start)
+		return err
+	}
+}
+
+// withRequestID takes request ID from the incoming metadata or generates one, and sends it back in the header.
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(requestIDMetadata); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+	if !logging.ValidRequestID(id) {
+		id = logging.NewRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id)) // Fails only if called outside of a call.
+	return logging.WithRequestID(ctx, id)
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, err error, start time.Time) {
+	remote := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+		if host, _, splitErr := net.SplitHostPort(remote); splitErr == nil {
+			remote = host
+		}
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "grpc call",
+		slog.String("method", method),
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("duration", time.Since(start)),
+		logging.IP(remote),
+	)
+}
+
+// requestIDStream overrides the stream context to pass request ID to handlers.
+type requestIDStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIDStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,129 @@
+package iplocation_grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/dronnix/search-accomodation/api/geolocationpb"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/internal/metrics"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// IPLocationServer is implementation of the gRPC GeolocationService,
+// on top of the same model as iplocation_api.IPLocationServer.
+type IPLocationServer struct {
+	geolocationpb.UnimplementedGeolocationServiceServer
+	fetcher        geolocation.IPLocationFetcher
+	datasetFetcher geolocation.DatasetFetcher
+	metrics        *metrics.Server
+	logger         *slog.Logger
+}
+
+func NewIPLocationServer(
+	fetcher geolocation.IPLocationFetcher,
+	datasetFetcher geolocation.DatasetFetcher,
+	m *metrics.Server,
+	logger *slog.Logger,
+) *IPLocationServer {
+	return &IPLocationServer{fetcher: fetcher, datasetFetcher: datasetFetcher, metrics: m, logger: logger}
+}
+
+// Lookup - see GeolocationService specification.
+func (s *IPLocationServer) Lookup(
+	ctx context.Context,
+	req *geolocationpb.LookupRequest,
+) (*geolocationpb.LookupResponse, error) {
+	return s.lookup(ctx, req.GetIp())
+}
+
+// BatchLookup - see GeolocationService specification.
+func (s *IPLocationServer) BatchLookup(stream geolocationpb.GeolocationService_BatchLookupServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err //nolint:wrapcheck // Already a gRPC status.
+		}
+		resp, err := s.lookup(stream.Context(), req.GetIp())
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(resp); err != nil {
+			return err //nolint:wrapcheck // Already a gRPC status.
+		}
+	}
+}
+
+// GetDataset - see GeolocationService specification.
+func (s *IPLocationServer) GetDataset(
+	ctx context.Context,
+	req *geolocationpb.GetDatasetRequest,
+) (*geolocationpb.Dataset, error) {
+	if req.GetVersion() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid dataset version")
+	}
+	dataset, err := s.datasetFetcher.FetchDataset(ctx, int(req.GetVersion()))
+	if errors.Is(err, geolocation.ErrDatasetNotFound) {
+		return nil, status.Error(codes.NotFound, "dataset not found")
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "could not fetch dataset", "version", req.GetVersion(), "error", err)
+		return nil, errUnavailable(ctx)
+	}
+	resp := &geolocationpb.Dataset{
+		Version:   int64(dataset.Version),
+		CreatedAt: timestamppb.New(dataset.CreatedAt),
+		Active:    dataset.Active,
+		Records:   int64(dataset.Records),
+	}
+	if !dataset.ActivatedAt.IsZero() {
+		resp.ActivatedAt = timestamppb.New(dataset.ActivatedAt)
+	}
+	return resp, nil
+}
+
+func (s *IPLocationServer) lookup(ctx context.Context, rawIP string) (*geolocationpb.LookupResponse, error) {
+	resp := &geolocationpb.LookupResponse{Ip: rawIP}
+	ip, err := geolocation.ParseAddr(rawIP)
+	if err != nil {
+		resp.Result = geolocationpb.LookupResponse_RESULT_INVALID_IP
+		s.metrics.ObserveLookup(metrics.LookupInvalid)
+		return resp, nil
+	}
+
+	location, err := geolocation.PredictIPLocation(ctx, ip, s.fetcher)
+	s.metrics.ObserveLookup(metrics.LookupOutcomeOf(err))
+	switch {
+	case errors.Is(err, geolocation.ErrIPLocationNotFound):
+		resp.Result = geolocationpb.LookupResponse_RESULT_NOT_FOUND
+	case errors.Is(err, geolocation.ErrIPLocationAmbiguous):
+		resp.Result = geolocationpb.LookupResponse_RESULT_AMBIGUOUS
+	case err != nil:
+		s.logger.ErrorContext(ctx, "could not predict IP location", logging.IP(rawIP), "error", err)
+		return nil, errUnavailable(ctx)
+	default:
+		resp.Result = geolocationpb.LookupResponse_RESULT_FOUND
+		resp.Location = &geolocationpb.IPLocation{
+			CountryCode: location.CountryCode,
+			Country:     location.CountryName,
+			City:        location.City,
+			Latitude:    location.Lat,
+			Longitude:   location.Lon,
+		}
+	}
+	return resp, nil
+}
+
+// errUnavailable is a generic error, the details are logged under the request ID.
+func errUnavailable(ctx context.Context) error {
+	return status.Errorf(codes.Unavailable, "service temporarily unavailable, request ID: %s", logging.RequestID(ctx))
+}
@@ -0,0 +1,163 @@
+package iplocation_grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/dronnix/search-accomodation/api/geolocationpb"
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func setupClient(t *testing.T, server *IPLocationServer) geolocationpb.GeolocationServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	logger := logging.Discard()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(LoggingUnaryInterceptor(logger)),
+		grpc.ChainStreamInterceptor(LoggingStreamInterceptor(logger)),
+	)
+	geolocationpb.RegisterGeolocationServiceServer(grpcServer, server)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return geolocationpb.NewGeolocationServiceClient(conn)
+}
+
+func TestIPLocationServer_Lookup(t *testing.T) {
+	t.Parallel()
+	fetcher := geolocationtest.NewMemoryStorageWith(locations[:1])
+	client := setupClient(t, NewIPLocationServer(fetcher, nil, nil, logging.Discard()))
+
+	resp, err := client.Lookup(context.Background(), &geolocationpb.LookupRequest{Ip: "1.2.3.4"})
+	require.NoError(t, err)
+	assert.Equal(t, geolocationpb.LookupResponse_RESULT_FOUND, resp.GetResult())
+	assert.Equal(t, "1.2.3.4", resp.GetIp())
+	assert.Equal(t, "London", resp.GetLocation().GetCity())
+	assert.Equal(t, "UK", resp.GetLocation().GetCountryCode())
+	assert.Equal(t, -0.1, resp.GetLocation().GetLongitude())
+}
+
+func TestIPLocationServer_Lookup_Unavailable(t *testing.T) {
+	t.Parallel()
+	fetcher := geolocationtest.NewMemoryStorage()
+	fetcher.SetFaults(geolocationtest.Faults{Fetch: errors.New("no db")})
+	client := setupClient(t, NewIPLocationServer(fetcher, nil, nil, logging.Discard()))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+	var header metadata.MD
+	_, err := client.Lookup(ctx, &geolocationpb.LookupRequest{Ip: "1.2.3.4"}, grpc.Header(&header))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "service temporarily unavailable, request ID: req-1", status.Convert(err).Message())
+	assert.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+}
+
+func TestIPLocationServer_BatchLookup(t *testing.T) {
+	t.Parallel()
+	ambiguous := geolocationtest.WithVersion(locations, 0)
+	for i := range ambiguous {
+		ambiguous[i].IP = netip.MustParseAddr("1.2.3.5")
+	}
+	fetcher := geolocationtest.NewMemoryStorageWith(append(ambiguous, locations[0]))
+	client := setupClient(t, NewIPLocationServer(fetcher, nil, nil, logging.Discard()))
+
+	stream, err := client.BatchLookup(context.Background())
+	require.NoError(t, err)
+	ips := []string{"1.2.3.4", "1.2.3.5", "1.2.3.6", "1.2.3.X"}
+	for _, ip := range ips {
+		require.NoError(t, stream.Send(&geolocationpb.LookupRequest{Ip: ip}))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	want := []geolocationpb.LookupResponse_Result{
+		geolocationpb.LookupResponse_RESULT_FOUND,
+		geolocationpb.LookupResponse_RESULT_AMBIGUOUS,
+		geolocationpb.LookupResponse_RESULT_NOT_FOUND,
+		geolocationpb.LookupResponse_RESULT_INVALID_IP,
+	}
+	for i := range ips {
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, ips[i], resp.GetIp())
+		assert.Equal(t, want[i], resp.GetResult())
+	}
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestIPLocationServer_GetDataset(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	datasetFetcher := geolocationtest.NewMemoryStorageWith(locations)
+	_, err := datasetFetcher.CreateDataset(ctx)
+	require.NoError(t, err)
+	active, err := datasetFetcher.FetchDataset(ctx, 1)
+	require.NoError(t, err)
+	client := setupClient(t, NewIPLocationServer(nil, datasetFetcher, nil, logging.Discard()))
+
+	dataset, err := client.GetDataset(ctx, &geolocationpb.GetDatasetRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), dataset.GetVersion())
+	assert.True(t, dataset.GetActive())
+	assert.Equal(t, int64(len(locations)), dataset.GetRecords())
+	assert.Equal(t, active.CreatedAt, dataset.GetCreatedAt().AsTime())
+	assert.Equal(t, active.ActivatedAt, dataset.GetActivatedAt().AsTime())
+
+	dataset, err = client.GetDataset(ctx, &geolocationpb.GetDatasetRequest{Version: 2})
+	require.NoError(t, err)
+	assert.False(t, dataset.GetActive())
+	assert.Nil(t, dataset.GetActivatedAt())
+
+	_, err = client.GetDataset(ctx, &geolocationpb.GetDatasetRequest{Version: 42})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetDataset(ctx, &geolocationpb.GetDatasetRequest{Version: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+var locations = []geolocation.IPLocation{
+	{
+		IP:          netip.AddrFrom4([4]byte{1, 2, 3, 4}),
+		CountryCode: "UK",
+		CountryName: "United Kingdom",
+		City:        "London",
+		Coordinate: geolocation.Coordinate{
+			Lat: 51.5,
+			Lon: -0.1,
+		},
+		MysteryValue: 42,
+	},
+	{
+		IP:          netip.AddrFrom4([4]byte{1, 2, 3, 4}),
+		CountryCode: "UK",
+		CountryName: "United Kingdom",
+		City:        "Leeds",
+		Coordinate: geolocation.Coordinate{
+			Lat: 53.8,
+			Lon: -1.5,
+		},
+		MysteryValue: 42,
+	},
+}
@@ -0,0 +1,60 @@
+package iplocation_importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// LoadCountryCentroids reads centroids of countries for quality gates from CSV file with following structure:
+// country_code,latitude,longitude
+func LoadCountryCentroids(path string) (map[string]geolocation.Coordinate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open country centroids: %w", err)
+	}
+	defer f.Close()
+	return ReadCountryCentroids(f)
+}
+
+// LoadQualityGates loads the country centroids of the gates from the file if the misplaced locations are checked.
+func LoadQualityGates(gates geolocation.QualityGates, centroidsPath string) (geolocation.QualityGates, error) {
+	if gates.MaxMisplacedRatio <= 0 {
+		return gates, nil
+	}
+	centroids, err := LoadCountryCentroids(centroidsPath)
+	if err != nil {
+		return geolocation.QualityGates{}, err
+	}
+	gates.CountryCentroids = centroids
+	return gates, nil
+}
+
+// ReadCountryCentroids reads centroids of countries in LoadCountryCentroids format from reader.
+func ReadCountryCentroids(r io.Reader) (map[string]geolocation.Coordinate, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = 3
+	if _, err := csvReader.Read(); err != nil {
+		return nil, fmt.Errorf("failed to read country centroids header: %w", err)
+	}
+	centroids := map[string]geolocation.Coordinate{}
+	for {
+		rec, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return centroids, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read country centroids: %w", err)
+		}
+		coord, err := geolocation.NewCoordinateFromStrings(rec[1], rec[2])
+		if err != nil {
+			return nil, fmt.Errorf("centroid of %q is invalid: %w", rec[0], err)
+		}
+		centroids[strings.ToUpper(rec[0])] = coord
+	}
+}
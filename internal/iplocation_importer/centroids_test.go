@@ -0,0 +1,65 @@
+package iplocation_importer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestLoadCountryCentroids(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "centroids.csv")
+	require.NoError(t, os.WriteFile(path, []byte("country_code,latitude,longitude\nUK,54,-2.5\nnz,-41.3,174.8\n"), 0o600))
+
+	centroids, err := iplocation_importer.LoadCountryCentroids(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]geolocation.Coordinate{
+		"UK": {Lat: 54, Lon: -2.5},
+		"NZ": {Lat: -41.3, Lon: 174.8},
+	}, centroids)
+
+	_, err = iplocation_importer.LoadCountryCentroids(filepath.Join(t.TempDir(), "missing.csv"))
+	require.Error(t, err)
+}
+
+func TestLoadQualityGates(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "centroids.csv")
+	require.NoError(t, os.WriteFile(path, []byte("country_code,latitude,longitude\nUK,54,-2.5\n"), 0o600))
+
+	gates, err := iplocation_importer.LoadQualityGates(geolocation.QualityGates{MaxMisplacedRatio: 0.1}, path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]geolocation.Coordinate{"UK": {Lat: 54, Lon: -2.5}}, gates.CountryCentroids)
+
+	gates, err = iplocation_importer.LoadQualityGates(geolocation.QualityGates{MinRecords: 1}, "missing.csv")
+	require.NoError(t, err, "centroids are not needed")
+	assert.Nil(t, gates.CountryCentroids)
+
+	_, err = iplocation_importer.LoadQualityGates(geolocation.QualityGates{MaxMisplacedRatio: 0.1}, "missing.csv")
+	require.Error(t, err)
+}
+
+func TestReadCountryCentroids_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		"empty":         "",
+		"missing field": "country_code,latitude,longitude\nUK,54\n",
+		"bad latitude":  "country_code,latitude,longitude\nUK,north,-2.5\n",
+		"out of bounds": "country_code,latitude,longitude\nUK,95,-2.5\n",
+	}
+	for name, data := range tests {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := iplocation_importer.ReadCountryCentroids(strings.NewReader(data))
+			require.Error(t, err)
+		})
+	}
+}
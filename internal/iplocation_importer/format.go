@@ -0,0 +1,103 @@
+package iplocation_importer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Format of the source data.
+type Format string
+
+const (
+	FormatAuto    Format = "auto"
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// DetectFormat detects the source data format by file extension.
+func DetectFormat(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".parquet", ".pq":
+		return FormatParquet, nil
+	}
+	return "", fmt.Errorf("unable to detect format of %q, specify it explicitly", path)
+}
+
+// OpenFile opens the file and creates an importer of the format, detected by extension if FormatAuto.
+// The header/schema of the file is checked. The returned closer closes the file.
+// The importer implements geolocation.ImportSizer.
+func OpenFile(path string, format Format) (geolocation.IPLocationImporter, io.Closer, error) {
+	if format == FormatAuto {
+		var err error
+		if format, err = DetectFormat(path); err != nil {
+			return nil, nil, err
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open file: %w", err)
+	}
+	importer, err := newImporter(f, format)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return importer, f, nil
+}
+
+func newImporter(f *os.File, format Format) (geolocation.IPLocationImporter, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat file: %w", err)
+	}
+	counter := &countingReader{r: f, size: info.Size()}
+	switch format {
+	case FormatCSV:
+		importer, err := NewCSVImporter(counter)
+		if err != nil {
+			return nil, err
+		}
+		return &sizedImporter{IPLocationImporter: importer, ImportSizer: counter}, nil
+	case FormatJSONL:
+		return &sizedImporter{IPLocationImporter: NewJSONLImporter(counter), ImportSizer: counter}, nil
+	case FormatParquet:
+		return NewParquetImporter(f, info.Size())
+	case FormatAuto:
+	}
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
+
+type sizedImporter struct {
+	geolocation.IPLocationImporter
+	geolocation.ImportSizer
+}
+
+// countingReader estimates the processed fraction of a stream by bytes read, including the buffered ones.
+type countingReader struct {
+	r    io.Reader
+	read int64
+	size int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	return n, err //nolint:wrapcheck
+}
+
+func (c *countingReader) Processed() float64 {
+	if c.size <= 0 {
+		return 0
+	}
+	return float64(c.read) / float64(c.size)
+}
@@ -0,0 +1,73 @@
+package iplocation_importer_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		path    string
+		want    iplocation_importer.Format
+		wantErr bool
+	}{
+		{path: "data/data_dump.csv", want: iplocation_importer.FormatCSV},
+		{path: "dump.JSONL", want: iplocation_importer.FormatJSONL},
+		{path: "dump.ndjson", want: iplocation_importer.FormatJSONL},
+		{path: "/tmp/dump.parquet", want: iplocation_importer.FormatParquet},
+		{path: "dump.txt", wantErr: true},
+		{path: "dump", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+			got, err := iplocation_importer.DetectFormat(tt.path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "dump.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(
+		"ip_address,country_code,country,city,latitude,longitude,mystery_value\n"+
+			"8.8.8.8,UK,United Kingdom,London,51.5,-0.1,42\n"), 0o600))
+
+	importer, closer, err := iplocation_importer.OpenFile(csvPath, iplocation_importer.FormatAuto)
+	require.NoError(t, err)
+	defer closer.Close()
+	sizer, ok := importer.(geolocation.ImportSizer)
+	require.True(t, ok)
+	locations, stats, err := importer.ImportNextBatch(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, locations, 1)
+	assert.Equal(t, 1, stats.Imported)
+	assert.Equal(t, 1.0, sizer.Processed())
+	_, _, err = importer.ImportNextBatch(context.Background(), 10)
+	require.ErrorIs(t, err, io.EOF)
+
+	_, _, err = iplocation_importer.OpenFile(csvPath, iplocation_importer.Format("xml"))
+	require.Error(t, err)
+	_, _, err = iplocation_importer.OpenFile(filepath.Join(dir, "missing.csv"), iplocation_importer.FormatCSV)
+	require.Error(t, err)
+	_, _, err = iplocation_importer.OpenFile(filepath.Join(dir, "dump.txt"), iplocation_importer.FormatAuto)
+	require.Error(t, err)
+}
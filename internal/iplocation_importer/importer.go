@@ -34,6 +34,31 @@ func NewCSVImporter(r io.Reader) (*CSVImporter, error) {
 func (c *CSVImporter) ImportNextBatch(
 	ctx context.Context,
 	size int,
+) ([]geolocation.IPLocation, geolocation.ImportStatistics, error) {
+	return importBatch(ctx, size, c.next)
+}
+
+func (c *CSVImporter) next() (geolocation.IPLocation, error) {
+	rec, err := c.csvReader.Read()
+	if err != nil {
+		return geolocation.IPLocation{}, err //nolint:wrapcheck
+	}
+	if len(rec) != 7 {
+		return geolocation.IPLocation{}, fmt.Errorf("%w: record must contain 7 columns", errNonValidRecord)
+	}
+	location, err := newIPLocation(rec[0], rec[1], rec[2], rec[3], rec[4], rec[5], rec[6])
+	location.Provenance.Line, _ = c.csvReader.FieldPos(0)
+	return location, err
+}
+
+// errNonValidRecord is returned by record readers for records that must be skipped and counted as non-valid.
+var errNonValidRecord = errors.New("non-valid record")
+
+// importBatch reads up to size records using next, which returns io.EOF when no more data is available.
+func importBatch(
+	ctx context.Context,
+	size int,
+	next func() (geolocation.IPLocation, error),
 ) ([]geolocation.IPLocation, geolocation.ImportStatistics, error) {
 	ipLocations := make([]geolocation.IPLocation, 0, size)
 	stats := geolocation.ImportStatistics{}
@@ -43,8 +68,12 @@ func (c *CSVImporter) ImportNextBatch(
 			return ipLocations, stats, ctx.Err() //nolint: wrapcheck
 		default:
 		}
-		rec, err := c.csvReader.Read()
+		location, err := next()
 		if err != nil {
+			if errors.Is(err, errNonValidRecord) {
+				stats.AddNonValid(err)
+				continue
+			}
 			if errors.Is(err, io.EOF) {
 				if i == 0 {
 					return nil, geolocation.ImportStatistics{}, io.EOF
@@ -53,18 +82,21 @@ func (c *CSVImporter) ImportNextBatch(
 			}
 			return nil, geolocation.ImportStatistics{}, fmt.Errorf("failed to read record: %w", err)
 		}
-		if len(rec) != 7 {
-			stats.NonValid++
-			continue
-		}
-
-		location, err := geolocation.NewIPLocationFromStrings(rec[0], rec[1], rec[2], rec[3], rec[4], rec[5], rec[6])
-		if err != nil {
-			stats.NonValid++
-			continue
-		}
 		ipLocations = append(ipLocations, location)
 		stats.Imported++
 	}
 	return ipLocations, stats, nil
 }
+
+// newIPLocation validates string fields of a record, the same way for all the formats.
+func newIPLocation(ip, countryCode, countryName, city, latitude, longitude, mystery string) (
+	geolocation.IPLocation,
+	error,
+) {
+	location, err := geolocation.NewIPLocationFromStrings(ip, countryCode, countryName, city, latitude, longitude,
+		mystery)
+	if err != nil {
+		return geolocation.IPLocation{}, fmt.Errorf("%w: %w", errNonValidRecord, err)
+	}
+	return location, nil
+}
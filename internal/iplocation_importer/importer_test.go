@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
-	"net"
+	"net/netip"
 	"os"
 	"strings"
 	"testing"
@@ -69,12 +69,13 @@ func Test_csvImporter_ImportNextBatch(t *testing.T) {
 			sizeArg: 2,
 			wantLocation: []geolocation.IPLocation{
 				{
-					IP:           net.IPv4(200, 106, 141, 15),
+					IP:           netip.AddrFrom4([4]byte{200, 106, 141, 15}),
 					CountryCode:  "SI",
 					CountryName:  "Nepal",
 					City:         "DuBuquemouth",
 					Coordinate:   geolocation.Coordinate{Lat: -84.87503094689836, Lon: 7.206435933364332},
 					MysteryValue: 7823011346,
+					Provenance:   geolocation.Provenance{Line: 2},
 				},
 			},
 			wantStats:       geolocation.ImportStatistics{Imported: 1, NonValid: 0},
@@ -87,15 +88,20 @@ func Test_csvImporter_ImportNextBatch(t *testing.T) {
 			sizeArg: 7,
 			wantLocation: []geolocation.IPLocation{
 				{ // SI,Nepal,DuBuquemouth,-84.87503094689836,7.206435933364332,7823011346
-					IP:           net.IPv4(200, 106, 141, 15),
+					IP:           netip.AddrFrom4([4]byte{200, 106, 141, 15}),
 					CountryCode:  "SI",
 					CountryName:  "Nepal",
 					City:         "DuBuquemouth",
 					Coordinate:   geolocation.Coordinate{Lat: -84.87503094689836, Lon: 7.206435933364332},
 					MysteryValue: 7823011346,
+					Provenance:   geolocation.Provenance{Line: 2},
 				},
 			},
-			wantStats:       geolocation.ImportStatistics{Imported: 1, NonValid: 1},
+			wantStats: geolocation.ImportStatistics{
+				Imported:        1,
+				NonValid:        1,
+				NonValidReasons: map[geolocation.NonValidReason]int{geolocation.NonValidIP: 1},
+			},
 			wantCreationErr: false,
 			wantImportErr:   false,
 		},
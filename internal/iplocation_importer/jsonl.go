@@ -0,0 +1,97 @@
+package iplocation_importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// JSONLImporter imports ip locations from JSON Lines (NDJSON) files, one object per line, with the same fields as CSV:
+// {"ip_address":"1.2.3.4","country_code":"SI","country":"Nepal","city":"DuBuquemouth",...}
+// Numeric fields can be given either as JSON numbers or as strings.
+type JSONLImporter struct {
+	reader *bufio.Reader
+	line   int // Number of the last line read.
+}
+
+// NewJSONLImporter creates a JSONLImporter from reader
+func NewJSONLImporter(r io.Reader) *JSONLImporter {
+	return &JSONLImporter{reader: bufio.NewReader(r)}
+}
+
+func (j *JSONLImporter) ImportNextBatch(
+	ctx context.Context,
+	size int,
+) ([]geolocation.IPLocation, geolocation.ImportStatistics, error) {
+	return importBatch(ctx, size, j.next)
+}
+
+func (j *JSONLImporter) next() (geolocation.IPLocation, error) {
+	line, err := j.readLine()
+	if err != nil {
+		return geolocation.IPLocation{}, err
+	}
+	rec := jsonlRecord{}
+	if err = json.Unmarshal(line, &rec); err != nil {
+		return geolocation.IPLocation{}, fmt.Errorf("%w: %v", errNonValidRecord, err) //nolint:errorlint
+	}
+	location, err := newIPLocation(string(rec.IP), string(rec.CountryCode), string(rec.CountryName),
+		string(rec.City), string(rec.Latitude), string(rec.Longitude), string(rec.MysteryValue))
+	location.Provenance.Line = j.line
+	return location, err
+}
+
+// readLine returns the next non-blank line.
+func (j *JSONLImporter) readLine() ([]byte, error) {
+	for {
+		line, err := j.reader.ReadBytes('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+			return nil, err //nolint:wrapcheck
+		}
+		j.line++
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return line, nil
+		}
+	}
+}
+
+type jsonlRecord struct {
+	IP           jsonlValue `json:"ip_address"`
+	CountryCode  jsonlValue `json:"country_code"`
+	CountryName  jsonlValue `json:"country"`
+	City         jsonlValue `json:"city"`
+	Latitude     jsonlValue `json:"latitude"`
+	Longitude    jsonlValue `json:"longitude"`
+	MysteryValue jsonlValue `json:"mystery_value"`
+}
+
+// jsonlValue keeps a textual representation of a JSON string or number, so values are validated
+// by geolocation.NewIPLocationFromStrings exactly as for CSV.
+type jsonlValue string
+
+func (v *jsonlValue) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*v = ""
+		return nil
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err //nolint:wrapcheck
+		}
+		*v = jsonlValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("value must be a string or a number: %s", data)
+	}
+	*v = jsonlValue(n)
+	return nil
+}
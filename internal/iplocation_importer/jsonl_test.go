@@ -0,0 +1,101 @@
+package iplocation_importer_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func Test_jsonlImporter_ImportNextBatch(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		jsonlData    string
+		sizeArg      int
+		wantLocation []geolocation.IPLocation
+		wantStats    geolocation.ImportStatistics
+	}{
+		{
+			name:         "typed values",
+			jsonlData:    validJSONLRecord,
+			sizeArg:      2,
+			wantLocation: []geolocation.IPLocation{atLine(validLocation, 1)},
+			wantStats:    geolocation.ImportStatistics{Imported: 1},
+		},
+		{
+			name:         "string values",
+			jsonlData:    validJSONLStringsRecord,
+			sizeArg:      2,
+			wantLocation: []geolocation.IPLocation{atLine(validLocation, 1)},
+			wantStats:    geolocation.ImportStatistics{Imported: 1},
+		},
+		{
+			name:         "blank lines and last line without newline",
+			jsonlData:    "\n" + validJSONLRecord + "\n  \n" + strings.TrimSpace(validJSONLStringsRecord),
+			sizeArg:      7,
+			wantLocation: []geolocation.IPLocation{atLine(validLocation, 2), atLine(validLocation, 5)},
+			wantStats:    geolocation.ImportStatistics{Imported: 2},
+		},
+		{
+			name:         "invalid records",
+			jsonlData:    validJSONLRecord + "{not a json}\n" + invalidJSONLRecord + `{"ip_address":true}` + "\n",
+			sizeArg:      7,
+			wantLocation: []geolocation.IPLocation{atLine(validLocation, 1)},
+			wantStats: geolocation.ImportStatistics{
+				Imported: 1,
+				NonValid: 3,
+				NonValidReasons: map[geolocation.NonValidReason]int{
+					geolocation.NonValidMalformed: 2,
+					geolocation.NonValidIP:        1,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			importer := iplocation_importer.NewJSONLImporter(strings.NewReader(tt.jsonlData))
+
+			loc, stats, err := importer.ImportNextBatch(context.Background(), tt.sizeArg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLocation, loc)
+			assert.Equal(t, tt.wantStats, stats)
+
+			_, _, err = importer.ImportNextBatch(context.Background(), tt.sizeArg)
+			require.True(t, errors.Is(err, io.EOF))
+		})
+	}
+}
+
+var validLocation = geolocation.IPLocation{
+	IP:           netip.AddrFrom4([4]byte{200, 106, 141, 15}),
+	CountryCode:  "SI",
+	CountryName:  "Nepal",
+	City:         "DuBuquemouth",
+	Coordinate:   geolocation.Coordinate{Lat: -84.87503094689836, Lon: 7.206435933364332},
+	MysteryValue: 7823011346,
+}
+
+// atLine returns the location read from the line of a file.
+func atLine(location geolocation.IPLocation, line int) geolocation.IPLocation {
+	location.Provenance.Line = line
+	return location
+}
+
+const validJSONLRecord = `{"ip_address":"200.106.141.15","country_code":"SI","country":"Nepal","city":"DuBuquemouth",` +
+	`"latitude":-84.87503094689836,"longitude":7.206435933364332,"mystery_value":7823011346}` + "\n"
+const validJSONLStringsRecord = `{"ip_address":"200.106.141.15","country_code":"SI","country":"Nepal",` +
+	`"city":"DuBuquemouth","latitude":"-84.87503094689836","longitude":"7.206435933364332",` +
+	`"mystery_value":"7823011346"}` + "\n"
+const invalidJSONLRecord = `{"ip_address":"XXX.103.7.140","country_code":"CZ","country":"Nicaragua",` +
+	`"city":"New Neva","latitude":-68.31023296602508,"longitude":-37.62435199624531,"mystery_value":7301823115}` + "\n"
@@ -0,0 +1,136 @@
+package iplocation_importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// ParquetImporter imports ip locations from Parquet files.
+// Columns are looked up by name in the file schema (the same names as in CSV header), so their order,
+// physical types and optionality may differ between producers.
+type ParquetImporter struct {
+	reader  *parquet.Reader
+	columns [parquetFieldsCount]int // Leaf column index for each of parquetFields.
+	rows    []parquet.Row
+	pos     int
+	read    int64 // Rows read from the file.
+	total   int64
+}
+
+// NewParquetImporter creates a ParquetImporter from reader of size bytes and infers columns from the file schema.
+func NewParquetImporter(r io.ReaderAt, size int64) (*ParquetImporter, error) {
+	f, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+	columns, err := inferParquetColumns(f.Schema())
+	if err != nil {
+		return nil, fmt.Errorf("failed to infer parquet schema: %w", err)
+	}
+	return &ParquetImporter{reader: parquet.NewReader(f), columns: columns, total: f.NumRows()}, nil
+}
+
+// Processed - see geolocation.ImportSizer interface specification.
+func (p *ParquetImporter) Processed() float64 {
+	if p.total == 0 {
+		return 0
+	}
+	return float64(p.read) / float64(p.total)
+}
+
+func (p *ParquetImporter) ImportNextBatch(
+	ctx context.Context,
+	size int,
+) ([]geolocation.IPLocation, geolocation.ImportStatistics, error) {
+	return importBatch(ctx, size, p.next)
+}
+
+func (p *ParquetImporter) next() (geolocation.IPLocation, error) {
+	if p.pos == len(p.rows) {
+		if err := p.readRows(); err != nil {
+			return geolocation.IPLocation{}, err
+		}
+	}
+	row := p.rows[p.pos]
+	p.pos++
+
+	var fields [parquetFieldsCount]string
+	for _, v := range row {
+		for i, col := range p.columns {
+			if v.Column() == col {
+				fields[i] = parquetValueString(v)
+			}
+		}
+	}
+	location, err := newIPLocation(fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6])
+	location.Provenance.Line = int(p.read) - len(p.rows) + p.pos // Number of the row in the file.
+	return location, err
+}
+
+func (p *ParquetImporter) readRows() error {
+	const rowsBufferSize = 1024
+	if p.rows == nil {
+		p.rows = make([]parquet.Row, rowsBufferSize)
+	}
+	p.rows = p.rows[:cap(p.rows)]
+	n, err := p.reader.ReadRows(p.rows)
+	p.rows, p.pos = p.rows[:n], 0
+	p.read += int64(n)
+	if n > 0 {
+		return nil
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	return err //nolint:wrapcheck
+}
+
+// parquetFields lists accepted column names for each field in the CSV order.
+var parquetFields = [...][]string{
+	{"ip_address", "ip"},
+	{"country_code"},
+	{"country", "country_name"},
+	{"city"},
+	{"latitude", "lat"},
+	{"longitude", "lon", "lng"},
+	{"mystery_value"},
+}
+
+const parquetFieldsCount = len(parquetFields)
+
+func inferParquetColumns(schema *parquet.Schema) ([parquetFieldsCount]int, error) {
+	var columns [parquetFieldsCount]int
+	for i, names := range parquetFields {
+		found := false
+		for _, name := range names {
+			leaf, ok := schema.Lookup(name)
+			if !ok || !leaf.Node.Leaf() {
+				continue
+			}
+			columns[i], found = leaf.ColumnIndex, true
+			break
+		}
+		if !found {
+			return columns, fmt.Errorf("column %q not found in schema: %s", names[0], schema)
+		}
+	}
+	return columns, nil
+}
+
+func parquetValueString(v parquet.Value) string {
+	switch v.Kind() {
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'g', -1, 32)
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'g', -1, 64) // parquet.Value.String() rounds doubles to float32.
+	case parquet.Boolean, parquet.Int32, parquet.Int64, parquet.Int96, parquet.ByteArray, parquet.FixedLenByteArray:
+		return v.String()
+	}
+	return "" // Null.
+}
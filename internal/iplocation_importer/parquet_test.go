@@ -0,0 +1,97 @@
+package iplocation_importer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/iplocation_importer"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+type parquetRecord struct {
+	MysteryValue int64   `parquet:"mystery_value"`
+	IP           string  `parquet:"ip_address"`
+	CountryCode  string  `parquet:"country_code"`
+	CountryName  *string `parquet:"country_name,optional"`
+	City         string  `parquet:"city"`
+	Latitude     float64 `parquet:"lat"`
+	Longitude    float64 `parquet:"lon"`
+}
+
+func writeParquet[T any](t *testing.T, rows []T) *bytes.Reader {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := parquet.NewGenericWriter[T](buf)
+	_, err := w.Write(rows)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func Test_parquetImporter_ImportNextBatch(t *testing.T) {
+	t.Parallel()
+	nepal := "Nepal"
+	valid := parquetRecord{
+		MysteryValue: 7823011346,
+		IP:           "200.106.141.15",
+		CountryCode:  "SI",
+		CountryName:  &nepal,
+		City:         "DuBuquemouth",
+		Latitude:     -84.87503094689836,
+		Longitude:    7.206435933364332,
+	}
+	invalidIP, noCountry := valid, valid
+	invalidIP.IP = "XXX.103.7.140"
+	noCountry.CountryName = nil
+	r := writeParquet(t, []parquetRecord{valid, invalidIP, noCountry, valid})
+
+	importer, err := iplocation_importer.NewParquetImporter(r, r.Size())
+	require.NoError(t, err)
+	assert.Zero(t, importer.Processed())
+
+	loc, stats, err := importer.ImportNextBatch(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []geolocation.IPLocation{atLine(validLocation, 1)}, loc)
+	assert.Equal(t, 1.0, importer.Processed(), "rows are read ahead")
+	assert.Equal(t, geolocation.ImportStatistics{
+		Imported: 1,
+		NonValid: 2,
+		NonValidReasons: map[geolocation.NonValidReason]int{
+			geolocation.NonValidIP:          1,
+			geolocation.NonValidCountryName: 1,
+		},
+	}, stats)
+
+	loc, stats, err = importer.ImportNextBatch(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []geolocation.IPLocation{atLine(validLocation, 4)}, loc)
+	assert.Equal(t, geolocation.ImportStatistics{Imported: 1}, stats)
+
+	_, _, err = importer.ImportNextBatch(context.Background(), 3)
+	require.True(t, errors.Is(err, io.EOF))
+}
+
+func TestNewParquetImporter_MissingColumn(t *testing.T) {
+	t.Parallel()
+	type record struct {
+		IP   string `parquet:"ip_address"`
+		City string `parquet:"city"`
+	}
+	r := writeParquet(t, []record{{IP: "1.2.3.4", City: "London"}})
+	_, err := iplocation_importer.NewParquetImporter(r, r.Size())
+	require.Error(t, err)
+}
+
+func TestNewParquetImporter_NotParquet(t *testing.T) {
+	t.Parallel()
+	r := bytes.NewReader([]byte(validHeader + validRecord))
+	_, err := iplocation_importer.NewParquetImporter(r, r.Size())
+	require.Error(t, err)
+}
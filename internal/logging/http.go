@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader is used to pass request ID by clients and return it in responses.
+const RequestIDHeader = "X-Request-Id"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ValidRequestID tells if request ID passed by a client can be used, otherwise new one is generated.
+func ValidRequestID(id string) bool {
+	return validRequestID.MatchString(id)
+}
+
+// HTTPMiddleware assigns request ID to every request and logs it when done.
+// Query is not logged as it may contain IP addresses of users.
+func HTTPMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(RequestIDHeader)
+			if !ValidRequestID(id) {
+				id = NewRequestID()
+			}
+			ctx := WithRequestID(r.Context(), id)
+			w.Header().Set(RequestIDHeader, id)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			code := ww.Status()
+			if code == 0 {
+				code = http.StatusOK
+			}
+			remote, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				remote = r.RemoteAddr
+			}
+			logger.LogAttrs(ctx, slog.LevelInfo, "http request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", code),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				IP(remote),
+			)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidRequestID(t *testing.T) {
+	assert.True(t, ValidRequestID("abc-123_X.y"))
+	assert.False(t, ValidRequestID(""))
+	assert.False(t, ValidRequestID("with space"))
+	assert.False(t, ValidRequestID("line\nbreak"))
+	assert.False(t, ValidRequestID(strings.Repeat("a", 65)))
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		wantID    bool
+	}{
+		{name: "passed by client", requestID: "req-1", wantID: true},
+		{name: "generated", requestID: ""},
+		{name: "invalid replaced", requestID: "bad id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger, err := New(buf, Options{Level: "info", RedactIPs: true})
+			require.NoError(t, err)
+
+			var handlerID string
+			handler := HTTPMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerID = RequestID(r.Context())
+				w.WriteHeader(http.StatusTeapot)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/v1/iplocation?ip=8.8.8.8", nil)
+			req.RemoteAddr = "192.168.1.10:5555"
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			require.True(t, ValidRequestID(handlerID))
+			assert.Equal(t, handlerID, w.Header().Get(RequestIDHeader))
+			if tt.wantID {
+				assert.Equal(t, tt.requestID, handlerID)
+			}
+
+			record := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			assert.Equal(t, handlerID, record[RequestIDKey])
+			assert.Equal(t, "/v1/iplocation", record["path"])
+			assert.Equal(t, float64(http.StatusTeapot), record["status"])
+			assert.Equal(t, "192.168.1.0", record[IPKey])
+			assert.NotContains(t, buf.String(), "8.8.8.8")
+		})
+	}
+}
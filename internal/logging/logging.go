@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IPKey is the attribute key for IP addresses of users, such attributes are redacted if configured.
+const IPKey = "ip"
+
+// RequestIDKey is the attribute key for request IDs.
+const RequestIDKey = "request_id"
+
+// Options of the logger.
+type Options struct {
+	Level     string // debug, info, warn or error.
+	RedactIPs bool   // Mask the host part of IPKey attributes.
+}
+
+// New creates JSON logger, which adds request ID from the context to every record.
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if opts.RedactIPs {
+		handlerOpts.ReplaceAttr = redactIPAttr
+	}
+	return slog.New(&contextHandler{Handler: slog.NewJSONHandler(w, handlerOpts)}), nil
+}
+
+// Discard returns the logger which drops everything, useful for tests.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// IP returns the attribute for IP address of a user.
+func IP(ip string) slog.Attr {
+	return slog.String(IPKey, ip)
+}
+
+// RedactIP masks the host part of the address: the last octet of IPv4 and the last 80 bits of IPv6.
+func RedactIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "redacted"
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+func redactIPAttr(_ []string, a slog.Attr) slog.Attr {
+	if a.Key == IPKey {
+		return slog.String(IPKey, RedactIP(a.Value.String()))
+	}
+	return a
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns the context carrying request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns request ID from the context, empty if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID generates random request ID.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b) // Never fails on supported platforms.
+	return hex.EncodeToString(b)
+}
+
+// contextHandler adds request ID and trace context from the context to records.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r) //nolint:wrapcheck
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew_InvalidLevel(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, Options{Level: "verbose"})
+	require.Error(t, err)
+}
+
+func TestNew_Level(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := New(buf, Options{Level: "warn"})
+	require.NoError(t, err)
+	logger.Info("skipped")
+	assert.Empty(t, buf.String())
+	logger.Warn("logged")
+	assert.Contains(t, buf.String(), `"msg":"logged"`)
+}
+
+func TestNew_RequestID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := New(buf, Options{Level: "info"})
+	require.NoError(t, err)
+	logger.With("component", "test").InfoContext(WithRequestID(context.Background(), "req-1"), "done")
+
+	record := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "req-1", record[RequestIDKey])
+	assert.Equal(t, "test", record["component"])
+}
+
+func TestNew_TraceContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := New(buf, Options{Level: "info"})
+	require.NoError(t, err)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	})
+	logger.InfoContext(trace.ContextWithSpanContext(context.Background(), sc), "done")
+
+	record := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", record["trace_id"])
+	assert.Equal(t, "0102030405060708", record["span_id"])
+}
+
+func TestNew_RedactIPs(t *testing.T) {
+	for _, redact := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		logger, err := New(buf, Options{Level: "info", RedactIPs: redact})
+		require.NoError(t, err)
+		logger.Info("lookup", IP("1.2.3.4"))
+
+		record := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		if redact {
+			assert.Equal(t, "1.2.3.0", record[IPKey])
+		} else {
+			assert.Equal(t, "1.2.3.4", record[IPKey])
+		}
+	}
+}
+
+func TestRedactIP(t *testing.T) {
+	assert.Equal(t, "10.20.30.0", RedactIP("10.20.30.40"))
+	assert.Equal(t, "2001:db8:1::", RedactIP("2001:db8:1:2:3:4:5:6"))
+	assert.Equal(t, "redacted", RedactIP("not an ip"))
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Empty(t, RequestID(context.Background()))
+	assert.Equal(t, "req-1", RequestID(WithRequestID(context.Background(), "req-1")))
+
+	id := NewRequestID()
+	assert.Len(t, id, 16)
+	assert.True(t, ValidRequestID(id))
+	assert.NotEqual(t, id, NewRequestID())
+}
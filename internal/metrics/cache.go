@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dronnix/search-accomodation/internal/iplocation_cache"
+)
+
+// CacheStatser - source of IP location cache statistics.
+type CacheStatser interface {
+	Stats() iplocation_cache.Stats
+}
+
+// CacheCollector exports IP location cache statistics.
+type CacheCollector struct {
+	cache CacheStatser
+
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+	size      *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*CacheCollector)(nil)
+
+func NewCacheCollector(cache CacheStatser) *CacheCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", name), help, nil, nil)
+	}
+	return &CacheCollector{
+		cache:     cache,
+		hits:      desc("hits_total", "Number of lookups served from the cache."),
+		misses:    desc("misses_total", "Number of lookups fetched from the database."),
+		evictions: desc("evictions_total", "Number of entries evicted because the cache is full."),
+		size:      desc("entries", "Number of cached IP addresses."),
+	}
+}
+
+func (c *CacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *CacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/iplocation_cache"
+)
+
+type cacheStub iplocation_cache.Stats
+
+func (c cacheStub) Stats() iplocation_cache.Stats {
+	return iplocation_cache.Stats(c)
+}
+
+func TestCacheCollector(t *testing.T) {
+	collector := NewCacheCollector(cacheStub{Hits: 5, Misses: 2, Evictions: 1, Size: 3})
+	const expected = `
+# HELP iploc_cache_entries Number of cached IP addresses.
+# TYPE iploc_cache_entries gauge
+iploc_cache_entries 3
+# HELP iploc_cache_hits_total Number of lookups served from the cache.
+# TYPE iploc_cache_hits_total counter
+iploc_cache_hits_total 5
+# HELP iploc_cache_misses_total Number of lookups fetched from the database.
+# TYPE iploc_cache_misses_total counter
+iploc_cache_misses_total 2
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"iploc_cache_entries", "iploc_cache_hits_total", "iploc_cache_misses_total"))
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Importer - metrics of iploc-data-importer.
+type Importer struct {
+	parsed      prometheus.Counter
+	rejected    *prometheus.CounterVec
+	duplicated  prometheus.Counter
+	stored      prometheus.Counter
+	copySeconds prometheus.Counter
+	copyRate    prometheus.Gauge
+}
+
+// NewImporter creates iploc-data-importer metrics and registers them in reg.
+func NewImporter(reg prometheus.Registerer) *Importer {
+	m := &Importer{
+		parsed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "import_rows_parsed_total",
+			Help:      "Number of parsed source records, both valid and not.",
+		}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "import_rows_rejected_total",
+			Help:      "Number of non-valid source records by reason.",
+		}, []string{"reason"}),
+		duplicated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "import_rows_duplicated_total",
+			Help:      "Number of duplicated source records.",
+		}),
+		stored: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "import_rows_stored_total",
+			Help:      "Number of records stored to the database.",
+		}),
+		copySeconds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "import_copy_duration_seconds_total",
+			Help:      "Total time spent storing records to the database.",
+		}),
+		copyRate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "import_copy_rows_per_second",
+			Help:      "Throughput of storing records to the database.",
+		}),
+	}
+	reg.MustRegister(m.parsed, m.rejected, m.duplicated, m.stored, m.copySeconds, m.copyRate)
+	return m
+}
+
+// InstrumentImporter counts parsed and rejected records of every batch.
+func (m *Importer) InstrumentImporter(importer geolocation.IPLocationImporter) geolocation.IPLocationImporter {
+	return &instrumentedImporter{IPLocationImporter: importer, metrics: m}
+}
+
+// InstrumentStorer measures COPY throughput.
+func (m *Importer) InstrumentStorer(storer geolocation.IPLocationStorer) geolocation.IPLocationStorer {
+	return &instrumentedStorer{IPLocationStorer: storer, metrics: m}
+}
+
+// ObserveProgress returns the progress callback of an import, which counts duplicates of every stored batch.
+func (m *Importer) ObserveProgress() func(geolocation.ImportProgress) {
+	var duplicated int // Of the previous progress, which is cumulative.
+	return func(p geolocation.ImportProgress) {
+		if p.Statistics.Duplicated > duplicated {
+			m.duplicated.Add(float64(p.Statistics.Duplicated - duplicated))
+			duplicated = p.Statistics.Duplicated
+		}
+	}
+}
+
+type instrumentedImporter struct {
+	geolocation.IPLocationImporter
+	metrics *Importer
+}
+
+func (i *instrumentedImporter) ImportNextBatch(
+	ctx context.Context,
+	size int,
+) ([]geolocation.IPLocation, geolocation.ImportStatistics, error) {
+	locations, stats, err := i.IPLocationImporter.ImportNextBatch(ctx, size)
+	i.metrics.parsed.Add(float64(stats.Total()))
+	for reason, n := range stats.NonValidReasons {
+		i.metrics.rejected.WithLabelValues(string(reason)).Add(float64(n))
+	}
+	return locations, stats, err //nolint:wrapcheck
+}
+
+type instrumentedStorer struct {
+	geolocation.IPLocationStorer
+	metrics  *Importer
+	stored   int
+	duration time.Duration
+}
+
+func (s *instrumentedStorer) StoreIPLocations(
+	ctx context.Context,
+	datasetVersion int,
+	locations []geolocation.IPLocation,
+) error {
+	start := time.Now()
+	if err := s.IPLocationStorer.StoreIPLocations(ctx, datasetVersion, locations); err != nil {
+		return err //nolint:wrapcheck
+	}
+	elapsed := time.Since(start)
+	s.stored += len(locations)
+	s.duration += elapsed
+	s.metrics.stored.Add(float64(len(locations)))
+	s.metrics.copySeconds.Add(elapsed.Seconds())
+	if s.duration > 0 {
+		s.metrics.copyRate.Set(float64(s.stored) / s.duration.Seconds())
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+type importerStub struct {
+	stats geolocation.ImportStatistics
+}
+
+func (i *importerStub) ImportNextBatch(
+	_ context.Context,
+	_ int,
+) ([]geolocation.IPLocation, geolocation.ImportStatistics, error) {
+	return nil, i.stats, nil
+}
+
+type storerStub struct {
+	geolocation.IPLocationStorer
+	err error
+}
+
+func (s *storerStub) StoreIPLocations(_ context.Context, _ int, _ []geolocation.IPLocation) error {
+	return s.err
+}
+
+func TestImporter_InstrumentImporter(t *testing.T) {
+	m := NewImporter(prometheus.NewRegistry())
+	importer := m.InstrumentImporter(&importerStub{stats: geolocation.ImportStatistics{
+		Imported:        3,
+		NonValid:        2,
+		NonValidReasons: map[geolocation.NonValidReason]int{geolocation.NonValidIP: 1, geolocation.NonValidCity: 1},
+	}})
+
+	for i := 0; i < 2; i++ {
+		_, _, err := importer.ImportNextBatch(context.Background(), 10)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 10.0, testutil.ToFloat64(m.parsed))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.rejected.WithLabelValues("invalid_ip")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.rejected.WithLabelValues("invalid_city")))
+}
+
+func TestImporter_InstrumentStorer(t *testing.T) {
+	m := NewImporter(prometheus.NewRegistry())
+	locations := make([]geolocation.IPLocation, 5)
+
+	require.NoError(t, m.InstrumentStorer(&storerStub{}).StoreIPLocations(context.Background(), 1, locations))
+	require.Error(t, m.InstrumentStorer(&storerStub{err: errors.New("copy failed")}).
+		StoreIPLocations(context.Background(), 1, locations))
+
+	assert.Equal(t, 5.0, testutil.ToFloat64(m.stored))
+	assert.Greater(t, testutil.ToFloat64(m.copySeconds), 0.0)
+	assert.Greater(t, testutil.ToFloat64(m.copyRate), 0.0)
+}
+
+func TestImporter_ObserveProgress(t *testing.T) {
+	m := NewImporter(prometheus.NewRegistry())
+	observe := m.ObserveProgress()
+
+	for _, duplicated := range []int{0, 3, 3, 7} {
+		observe(geolocation.ImportProgress{Statistics: geolocation.ImportStatistics{Duplicated: duplicated}})
+	}
+
+	assert.Equal(t, 7.0, testutil.ToFloat64(m.duplicated))
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// LookupOutcome - outcome of IP location lookup.
+type LookupOutcome string
+
+const (
+	LookupFound     LookupOutcome = "found"
+	LookupNotFound  LookupOutcome = "not_found"
+	LookupAmbiguous LookupOutcome = "ambiguous"
+	LookupInvalid   LookupOutcome = "invalid"
+	LookupError     LookupOutcome = "error"
+)
+
+// LookupOutcomeOf returns the outcome of geolocation.PredictIPLocation by its error.
+func LookupOutcomeOf(err error) LookupOutcome {
+	switch {
+	case err == nil:
+		return LookupFound
+	case errors.Is(err, geolocation.ErrIPLocationNotFound):
+		return LookupNotFound
+	case errors.Is(err, geolocation.ErrIPLocationAmbiguous):
+		return LookupAmbiguous
+	}
+	return LookupError
+}
+
+// Server - metrics of iploc-server, shared by HTTP and gRPC APIs.
+// Nil *Server is valid and collects nothing, so metrics are optional.
+type Server struct {
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	grpcRequests *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+	lookups      *prometheus.CounterVec
+}
+
+// NewServer creates iploc-server metrics and registers them in reg.
+func NewServer(reg prometheus.Registerer) *Server {
+	m := &Server{
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "Number of gRPC calls by method and status code.",
+		}, []string{"method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "Duration of gRPC calls by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "lookups_total",
+			Help:      "Number of IP location lookups by outcome.",
+		}, []string{"outcome"}),
+	}
+	reg.MustRegister(m.httpRequests, m.httpDuration, m.grpcRequests, m.grpcDuration, m.lookups)
+	return m
+}
+
+const namespace = "iploc"
+
+// ObserveLookup counts the lookup outcome.
+func (m *Server) ObserveLookup(outcome LookupOutcome) {
+	if m == nil {
+		return
+	}
+	m.lookups.WithLabelValues(string(outcome)).Inc()
+}
+
+// HTTPMiddleware counts requests and measures their latency by chi route pattern.
+func (m *Server) HTTPMiddleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unknown" // Unmatched routes are not used as labels to keep cardinality bounded.
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		code := ww.Status()
+		if code == 0 {
+			code = http.StatusOK
+		}
+		labels := []string{route, r.Method, strconv.Itoa(code)}
+		m.httpRequests.WithLabelValues(labels...).Inc()
+		m.httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// UnaryInterceptor counts gRPC calls and measures their latency.
+func (m *Server) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeGRPC(info.FullMethod, err, start)
+		return resp, err
+	}
+}
+
+// StreamInterceptor counts gRPC streams and measures their duration.
+func (m *Server) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observeGRPC(info.FullMethod, err, start)
+		return err
+	}
+}
+
+func (m *Server) observeGRPC(method string, err error, start time.Time) {
+	if m == nil {
+		return
+	}
+	code := status.Code(err).String()
+	m.grpcRequests.WithLabelValues(method, code).Inc()
+	m.grpcDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestLookupOutcomeOf(t *testing.T) {
+	assert.Equal(t, LookupFound, LookupOutcomeOf(nil))
+	assert.Equal(t, LookupNotFound, LookupOutcomeOf(geolocation.ErrIPLocationNotFound))
+	assert.Equal(t, LookupAmbiguous, LookupOutcomeOf(geolocation.ErrIPLocationAmbiguous))
+	assert.Equal(t, LookupError, LookupOutcomeOf(errors.New("connection refused")))
+}
+
+func TestServer_ObserveLookup(t *testing.T) {
+	m := NewServer(prometheus.NewRegistry())
+	m.ObserveLookup(LookupFound)
+	m.ObserveLookup(LookupFound)
+	m.ObserveLookup(LookupInvalid)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.lookups.WithLabelValues("found")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.lookups.WithLabelValues("invalid")))
+}
+
+func TestServer_NilIsNoop(t *testing.T) {
+	var m *Server
+	m.ObserveLookup(LookupFound)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	assert.NotNil(t, m.HTTPMiddleware(handler))
+
+	_, err := m.UnaryInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	assert.NoError(t, err)
+}
+
+func TestServer_HTTPMiddleware(t *testing.T) {
+	m := NewServer(prometheus.NewRegistry())
+	router := chi.NewRouter()
+	router.Use(m.HTTPMiddleware)
+	router.Get("/v1/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.Get("/ok", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, path := range []string{"/v1/items/1", "/v1/items/2", "/ok", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/v1/items/{id}", "GET", "204")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/ok", "GET", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("unknown", "GET", "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestServer_GRPCInterceptors(t *testing.T) {
+	m := NewServer(prometheus.NewRegistry())
+	info := &grpc.UnaryServerInfo{FullMethod: "/geolocation.v1.GeolocationService/Lookup"}
+
+	_, err := m.UnaryInterceptor()(context.Background(), nil, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	require.NoError(t, err)
+	_, err = m.UnaryInterceptor()(context.Background(), nil, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.Unavailable, "db is down")
+		})
+	require.Error(t, err)
+	err = m.StreamInterceptor()(nil, nil, &grpc.StreamServerInfo{FullMethod: "/geolocation.v1.GeolocationService/BatchLookup"},
+		func(srv interface{}, stream grpc.ServerStream) error { return nil })
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "OK")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "Unavailable")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(
+		m.grpcRequests.WithLabelValues("/geolocation.v1.GeolocationService/BatchLookup", "OK")))
+}
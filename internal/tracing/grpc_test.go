@@ -0,0 +1,74 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dronnix/search-accomodation/internal/tracing"
+	"github.com/dronnix/search-accomodation/internal/tracing/tracingtest"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	exporter := tracingtest.Setup(t)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceParent))
+	info := &grpc.UnaryServerInfo{FullMethod: "/geolocation.v1.GeolocationService/Lookup"}
+	var handlerSpan trace.SpanContext
+
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return nil, status.Error(grpccodes.Unavailable, "db is down")
+	}
+	_, err := tracing.UnaryServerInterceptor()(ctx, nil, info, handler)
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "geolocation.v1.GeolocationService/Lookup", span.Name)
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", span.SpanContext.TraceID().String())
+	assert.Equal(t, span.SpanContext.SpanID(), handlerSpan.SpanID())
+	assert.Contains(t, span.Attributes, semconv.RPCService("geolocation.v1.GeolocationService"))
+	assert.Contains(t, span.Attributes, semconv.RPCMethod("Lookup"))
+	assert.Contains(t, span.Attributes, semconv.RPCGRPCStatusCodeKey.Int(int(grpccodes.Unavailable)))
+	assert.Equal(t, codes.Error, span.Status.Code)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	exporter := tracingtest.Setup(t)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceParent))
+	info := &grpc.StreamServerInfo{FullMethod: "/geolocation.v1.GeolocationService/BatchLookup"}
+	var handlerSpan trace.SpanContext
+
+	handler := func(_ interface{}, ss grpc.ServerStream) error {
+		handlerSpan = trace.SpanContextFromContext(ss.Context())
+		return nil
+	}
+	err := tracing.StreamServerInterceptor()(nil, &streamStub{ctx: ctx}, info, handler)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "geolocation.v1.GeolocationService/BatchLookup", spans[0].Name)
+	assert.Equal(t, spans[0].SpanContext.SpanID(), handlerSpan.SpanID())
+	assert.Equal(t, "0102030405060708", spans[0].Parent.SpanID().String())
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+}
+
+type streamStub struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *streamStub) Context() context.Context {
+	return s.ctx
+}
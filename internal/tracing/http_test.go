@@ -0,0 +1,62 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dronnix/search-accomodation/internal/tracing"
+	"github.com/dronnix/search-accomodation/internal/tracing/tracingtest"
+)
+
+const traceParent = "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01"
+
+func TestHTTPMiddleware(t *testing.T) {
+	exporter := tracingtest.Setup(t)
+	router := chi.NewRouter()
+	router.Use(tracing.HTTPMiddleware)
+	var handlerSpan trace.SpanContext
+	router.Get("/v1/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/items/42", nil)
+	req.Header.Set("traceparent", traceParent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /v1/items/{id}", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", span.SpanContext.TraceID().String())
+	assert.Equal(t, "0102030405060708", span.Parent.SpanID().String())
+	assert.True(t, span.Parent.IsRemote())
+	assert.Equal(t, span.SpanContext.SpanID(), handlerSpan.SpanID())
+	assert.Contains(t, span.Attributes, semconv.HTTPRoute("/v1/items/{id}"))
+	assert.Contains(t, span.Attributes, semconv.HTTPResponseStatusCode(http.StatusServiceUnavailable))
+	assert.Equal(t, codes.Error, span.Status.Code)
+}
+
+func TestHTTPMiddleware_NewTrace(t *testing.T) {
+	exporter := tracingtest.Setup(t)
+	router := chi.NewRouter()
+	router.Use(tracing.HTTPMiddleware)
+	router.Get("/ok", func(w http.ResponseWriter, r *http.Request) {})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent.IsValid())
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Contains(t, spans[0].Attributes, semconv.HTTPResponseStatusCode(http.StatusOK))
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Options of tracing.
+type Options struct {
+	Endpoint    string  // OTLP gRPC endpoint (host:port), tracing is disabled if empty.
+	Insecure    bool    // Don't use TLS to connect to the endpoint.
+	SampleRatio float64 // Ratio of sampled root spans, parent decision is used for others.
+}
+
+// Setup configures global tracer provider exporting spans by OTLP and W3C trace context propagation.
+// Returned function flushes not exported spans, it must be called before exit.
+func Setup(ctx context.Context, serviceName string, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(Propagator())
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create OTLP exporter: %w", err)
+	}
+	provider := NewTracerProvider(serviceName, sdktrace.NewBatchSpanProcessor(exporter),
+		sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio)))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewTracerProvider creates tracer provider of the service with the given span processor and sampler.
+func NewTracerProvider(
+	serviceName string,
+	processor sdktrace.SpanProcessor,
+	sampler sdktrace.Sampler,
+) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sampler),
+	)
+}
+
+// Propagator returns W3C trace context and baggage propagator.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
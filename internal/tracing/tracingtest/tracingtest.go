@@ -0,0 +1,25 @@
+// Package tracingtest provides in-memory span exporter for tests.
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/dronnix/search-accomodation/internal/tracing"
+)
+
+// Setup sets global tracer provider exporting all spans to the returned in-memory exporter and W3C propagation.
+// The provider is shut down when the test finishes. Tests using it must not be parallel.
+func Setup(t testing.TB) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider("test", sdktrace.NewSimpleSpanProcessor(exporter), sdktrace.AlwaysSample())
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(tracing.Propagator())
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return exporter
+}
@@ -0,0 +1,102 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrKeyNotFound = errors.New("api key not found")
+
+// secretPrefix makes leaked keys easy to recognize by secret scanners.
+const secretPrefix = "iploc_"
+
+// Limits - how many requests a client is allowed to make.
+type Limits struct {
+	RatePerSecond float64 // Sustained request rate, unlimited if zero.
+	Burst         int     // Requests allowed at once above the rate, at least one.
+	DailyQuota    int     // Requests per UTC day, unlimited if zero.
+}
+
+// Key - API key of a client. Only the hash of the secret is stored, the secret is shown once on creation.
+type Key struct {
+	ID        int
+	Name      string
+	Hash      string
+	CreatedAt time.Time
+	RevokedAt time.Time // Zero if the key has not been revoked.
+	Admin     bool      // Admin keys are allowed to manage datasets and imports.
+	Limits
+}
+
+// Revoked - revoked keys are known, but not allowed to be used.
+func (k Key) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// Fetcher - interface for fetching API keys to authenticate requests.
+type Fetcher interface {
+	// FetchAPIKey returns the key by the hash of its secret, ErrKeyNotFound if there is no such key.
+	FetchAPIKey(ctx context.Context, hash string) (Key, error)
+}
+
+// Manager - interface for managing API keys.
+type Manager interface {
+	// CreateAPIKey stores the key and returns it with ID and creation time set.
+	CreateAPIKey(ctx context.Context, key Key) (Key, error)
+	// RevokeAPIKey revokes the key, returns ErrKeyNotFound if there is no such key.
+	RevokeAPIKey(ctx context.Context, id int) error
+	// ListAPIKeys returns all the keys including revoked ones.
+	ListAPIKeys(ctx context.Context) ([]Key, error)
+}
+
+// CreateKey - generates a new secret and stores the key. The secret is returned, as it can't be restored from the hash.
+func CreateKey(
+	ctx context.Context,
+	name string,
+	admin bool,
+	limits Limits,
+	manager Manager,
+) (secret string, key Key, err error) {
+	if strings.TrimSpace(name) == "" {
+		return "", Key{}, errors.New("name of the key is empty")
+	}
+	if limits.RatePerSecond < 0 || limits.Burst < 0 || limits.DailyQuota < 0 {
+		return "", Key{}, fmt.Errorf("limits must not be negative: %+v", limits)
+	}
+	if limits.RatePerSecond > 0 && limits.Burst == 0 {
+		limits.Burst = 1
+	}
+
+	secret, err = GenerateSecret()
+	if err != nil {
+		return "", Key{}, err
+	}
+	key, err = manager.CreateAPIKey(ctx, Key{Name: name, Hash: Hash(secret), Admin: admin, Limits: limits})
+	if err != nil {
+		return "", Key{}, fmt.Errorf("could not store api key: %w", err)
+	}
+	return secret, key, nil
+}
+
+// GenerateSecret - returns a random secret with 256 bits of entropy.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate secret: %w", err)
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash - hash of the secret to store and look up keys by.
+// Secrets are random, so a fast unsalted hash is enough to make a leaked table useless.
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,76 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+func TestCreateKey(t *testing.T) {
+	t.Parallel()
+	manager := new(managerMock)
+	manager.On("CreateAPIKey", mock.Anything, mock.AnythingOfType("apikey.Key")).Return(
+		apikey.Key{ID: 7}, nil).Once()
+
+	secret, key, err := apikey.CreateKey(context.Background(), "partner", true, apikey.Limits{RatePerSecond: 10}, manager)
+	require.NoError(t, err)
+	assert.Equal(t, 7, key.ID)
+	assert.Regexp(t, `^iploc_[A-Za-z0-9_-]{43}$`, secret)
+
+	stored := manager.Calls[0].Arguments.Get(1).(apikey.Key)
+	assert.Equal(t, "partner", stored.Name)
+	assert.Equal(t, apikey.Hash(secret), stored.Hash)
+	assert.True(t, stored.Admin)
+	assert.NotContains(t, stored.Hash, secret)
+	assert.Equal(t, apikey.Limits{RatePerSecond: 10, Burst: 1}, stored.Limits)
+	manager.AssertExpectations(t)
+}
+
+func TestCreateKey_Errors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	_, _, err := apikey.CreateKey(ctx, " ", false, apikey.Limits{}, new(managerMock))
+	require.Error(t, err)
+	_, _, err = apikey.CreateKey(ctx, "partner", false, apikey.Limits{DailyQuota: -1}, new(managerMock))
+	require.Error(t, err)
+
+	manager := new(managerMock)
+	manager.On("CreateAPIKey", mock.Anything, mock.Anything).Return(apikey.Key{}, errors.New("no db")).Once()
+	_, _, err = apikey.CreateKey(ctx, "partner", false, apikey.Limits{}, manager)
+	require.Error(t, err)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	t.Parallel()
+	s1, err := apikey.GenerateSecret()
+	require.NoError(t, err)
+	s2, err := apikey.GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, s1, s2)
+	assert.NotEqual(t, apikey.Hash(s1), apikey.Hash(s2))
+	assert.Equal(t, apikey.Hash(s1), apikey.Hash(s1))
+}
+
+type managerMock struct {
+	mock.Mock
+}
+
+func (m *managerMock) CreateAPIKey(ctx context.Context, key apikey.Key) (apikey.Key, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(apikey.Key), args.Error(1) //nolint:wrapcheck
+}
+
+func (m *managerMock) RevokeAPIKey(ctx context.Context, id int) error {
+	return m.Called(ctx, id).Error(0) //nolint:wrapcheck
+}
+
+func (m *managerMock) ListAPIKeys(ctx context.Context) ([]apikey.Key, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]apikey.Key), args.Error(1) //nolint:wrapcheck
+}
@@ -2,6 +2,7 @@ package geolocation
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 )
 
@@ -39,3 +40,13 @@ func (c Coordinate) Validate() error {
 }
 
 const epsilon = 0.000001
+
+const earthRadiusKm = 6371.0088 // Mean radius.
+
+// DistanceKm - great-circle distance to other coordinate by haversine formula.
+func (c Coordinate) DistanceKm(other Coordinate) float64 {
+	const rad = math.Pi / 180
+	dLat, dLon := (other.Lat-c.Lat)*rad, (other.Lon-c.Lon)*rad
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(c.Lat*rad)*math.Cos(other.Lat*rad)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(min(h, 1)))
+}
@@ -1,6 +1,7 @@
 package geolocation_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/dronnix/search-accomodation/model/geolocation"
@@ -75,3 +76,19 @@ func TestCoordinates_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestCoordinate_DistanceKm(t *testing.T) {
+	t.Parallel()
+	london := geolocation.Coordinate{Lat: 51.5074, Lon: -0.1278}
+	paris := geolocation.Coordinate{Lat: 48.8566, Lon: 2.3522}
+	if d := london.DistanceKm(paris); math.Abs(d-343.5) > 1 {
+		t.Errorf("DistanceKm() = %f, want about 343.5", d)
+	}
+	if d := london.DistanceKm(london); d != 0 {
+		t.Errorf("DistanceKm() = %f, want 0", d)
+	}
+	north, south := geolocation.Coordinate{Lat: 90}, geolocation.Coordinate{Lat: -90}
+	if d := north.DistanceKm(south); math.Abs(d-20015.1) > 1 {
+		t.Errorf("DistanceKm() = %f, want about 20015.1", d)
+	}
+}
@@ -0,0 +1,80 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrDatasetNotFound = errors.New("dataset not found")
+
+// Dataset - a version of IP locations data produced by a single import.
+// Only one dataset is active (used to predict locations) at a time.
+type Dataset struct {
+	Version     int
+	CreatedAt   time.Time
+	ActivatedAt time.Time // Zero if the dataset has never been activated.
+	Active      bool
+	Records     int // Number of stored IP locations, counted on activation.
+}
+
+// DatasetFetcher - interface for fetching information about datasets.
+type DatasetFetcher interface {
+	// FetchDataset returns the dataset of given version, the active one if the version is zero.
+	// Returns ErrDatasetNotFound if there is no such dataset.
+	FetchDataset(ctx context.Context, version int) (Dataset, error)
+}
+
+// ErrDatasetNeverActivated - only datasets that have been fully imported and activated once can be activated again.
+var ErrDatasetNeverActivated = errors.New("dataset has never been activated")
+
+// DatasetManager - interface for switching the active dataset.
+type DatasetManager interface {
+	DatasetFetcher
+	// ListDatasets returns all the datasets ordered by version.
+	ListDatasets(ctx context.Context) ([]Dataset, error)
+	// ActivateDataset makes the dataset active, deactivating the previously active one.
+	// Returns ErrDatasetNotFound if there is no such dataset.
+	ActivateDataset(ctx context.Context, datasetVersion int) error
+}
+
+// ReactivateDataset - activates a dataset that has been active before, e.g. to switch to a newer one after rollback.
+// Returns ErrDatasetNotFound or ErrDatasetNeverActivated if the dataset can't be activated,
+// as datasets of failed imports are incomplete.
+func ReactivateDataset(ctx context.Context, version int, manager DatasetManager) (Dataset, error) {
+	dataset, err := manager.FetchDataset(ctx, version)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("failed to fetch dataset %d: %w", version, err)
+	}
+	if dataset.ActivatedAt.IsZero() {
+		return Dataset{}, fmt.Errorf("failed to activate dataset %d: %w", version, ErrDatasetNeverActivated)
+	}
+	if err = manager.ActivateDataset(ctx, version); err != nil {
+		return Dataset{}, fmt.Errorf("failed to activate dataset %d: %w", version, err)
+	}
+	return manager.FetchDataset(ctx, version) //nolint:wrapcheck
+}
+
+// RollbackDataset - activates the dataset that was active before the current one.
+// Returns ErrDatasetNotFound if no other dataset has ever been active.
+func RollbackDataset(ctx context.Context, manager DatasetManager) (Dataset, error) {
+	datasets, err := manager.ListDatasets(ctx)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("failed to list datasets: %w", err)
+	}
+	var previous *Dataset
+	for i := range datasets {
+		d := &datasets[i]
+		if d.Active || d.ActivatedAt.IsZero() {
+			continue
+		}
+		if previous == nil || d.ActivatedAt.After(previous.ActivatedAt) {
+			previous = d
+		}
+	}
+	if previous == nil {
+		return Dataset{}, fmt.Errorf("failed to find previous dataset: %w", ErrDatasetNotFound)
+	}
+	return ReactivateDataset(ctx, previous.Version, manager)
+}
@@ -0,0 +1,62 @@
+package geolocation_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestReactivateDataset(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	manager := geolocationtest.NewMemoryStorage()
+	geolocationtest.StoreDataset(ctx, t, manager, locations, true)
+	geolocationtest.StoreDataset(ctx, t, manager, locations, true)
+	neverActivated := geolocationtest.StoreDataset(ctx, t, manager, locations, false)
+	previous, err := manager.FetchDataset(ctx, 1)
+	require.NoError(t, err)
+
+	dataset, err := geolocation.ReactivateDataset(ctx, 1, manager)
+	require.NoError(t, err)
+	assert.True(t, dataset.Active)
+	assert.False(t, dataset.ActivatedAt.Before(previous.ActivatedAt))
+
+	_, err = geolocation.ReactivateDataset(ctx, neverActivated, manager)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNeverActivated)
+	_, err = geolocation.ReactivateDataset(ctx, neverActivated+1, manager)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+	active, err := manager.FetchDataset(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, active.Version)
+}
+
+func TestRollbackDataset(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	manager := geolocationtest.NewMemoryStorage()
+	for i := 0; i < 3; i++ {
+		geolocationtest.StoreDataset(ctx, t, manager, locations, true)
+	}
+	geolocationtest.StoreDataset(ctx, t, manager, locations, false) // Failed import.
+
+	dataset, err := geolocation.RollbackDataset(ctx, manager)
+	require.NoError(t, err)
+	assert.Equal(t, 2, dataset.Version)
+	assert.True(t, dataset.Active)
+}
+
+func TestRollbackDataset_NoPrevious(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	manager := geolocationtest.NewMemoryStorage()
+	geolocationtest.StoreDataset(ctx, t, manager, locations, true)
+	geolocationtest.StoreDataset(ctx, t, manager, locations, false)
+
+	_, err := geolocation.RollbackDataset(ctx, manager)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+}
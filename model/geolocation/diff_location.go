@@ -0,0 +1,191 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+)
+
+// IPLocationBatches - calls fn with batches of IP locations from some source until all of them are passed.
+// Stops and returns the error returned by fn, if any.
+type IPLocationBatches func(ctx context.Context, fn func([]IPLocation) error) error
+
+// DatasetBatches - batches of the stored dataset, the active one if the version is zero.
+func DatasetBatches(lister IPLocationLister, version int) IPLocationBatches {
+	const batchSize = 8192
+	return func(ctx context.Context, fn func([]IPLocation) error) error {
+		return lister.ListIPLocations(ctx, ExportFilter{DatasetVersion: version}, batchSize, fn) //nolint:wrapcheck
+	}
+}
+
+// ImporterBatches - batches of valid records of the importer, non-valid ones are skipped.
+func ImporterBatches(importer IPLocationImporter) IPLocationBatches {
+	const batchSize = 8192
+	return func(ctx context.Context, fn func([]IPLocation) error) error {
+		for {
+			locations, _, err := importer.ImportNextBatch(ctx, batchSize)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			if err = fn(locations); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// DiffKind - how an IP address differs between two sets of locations.
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffChanged DiffKind = "changed"
+)
+
+// IPLocationDiff - difference of an IP address location between the old and the new sets.
+type IPLocationDiff struct {
+	Kind DiffKind
+	Old  *IPLocation // Nil if added.
+	New  *IPLocation // Nil if removed.
+	// DistanceKm - how far the location moved, zero unless changed.
+	DistanceKm     float64
+	Relocated      bool // Moved farther than DiffOptions.MinDistanceKm.
+	CountryChanged bool
+	CityChanged    bool
+}
+
+// DiffOptions of DiffIPLocations.
+type DiffOptions struct {
+	// MinDistanceKm - locations moved by this distance or less are not relocated, any move is if zero.
+	MinDistanceKm float64
+}
+
+// DiffSummary - statistics of the difference between the old and the new sets of locations.
+type DiffSummary struct {
+	Old            int // Distinct IP addresses in the old set.
+	New            int // Distinct IP addresses in the new set.
+	Added          int
+	Removed        int
+	Changed        int // Relocated, or with country or city changed.
+	Unchanged      int
+	Relocated      int
+	CountryChanged int
+	CityChanged    int
+	MaxDistanceKm  float64 // Of the relocated ones.
+	MeanDistanceKm float64 // Of the relocated ones.
+}
+
+// DiffIPLocations - compares the old and the new sets of locations IP by IP, and calls fn with the IPs that differ:
+// changed and added in the order of the new set, then removed in the order of the old one.
+// The old set is kept in memory, the new one is streamed. IPs with several locations are compared by the first one.
+// Returns a wrapped error if any problem occurs.
+func DiffIPLocations(
+	ctx context.Context,
+	oldBatches, newBatches IPLocationBatches,
+	opts DiffOptions,
+	fn func(IPLocationDiff) error,
+) (DiffSummary, error) {
+	oldLocations := map[netip.Addr]*diffedLocation{}
+	var oldOrder []*diffedLocation
+	err := oldBatches(ctx, func(locations []IPLocation) error {
+		for i := range locations {
+			key := locations[i].IP
+			if _, ok := oldLocations[key]; ok {
+				continue
+			}
+			loc := &diffedLocation{location: locations[i]}
+			oldLocations[key] = loc
+			oldOrder = append(oldOrder, loc)
+		}
+		return nil
+	})
+	if err != nil {
+		return DiffSummary{}, fmt.Errorf("failed to read old ip locations: %w", err)
+	}
+
+	summary := DiffSummary{Old: len(oldOrder)}
+	var totalDistance float64
+	seen := map[netip.Addr]struct{}{} // Of the new set.
+	err = newBatches(ctx, func(locations []IPLocation) error {
+		for i := range locations {
+			key := locations[i].IP
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			summary.New++
+			newLoc := locations[i]
+			oldLoc, ok := oldLocations[key]
+			if !ok {
+				summary.Added++
+				if err := fn(IPLocationDiff{Kind: DiffAdded, New: &newLoc}); err != nil {
+					return err
+				}
+				continue
+			}
+			oldLoc.matched = true
+			diff := compareIPLocations(oldLoc.location, newLoc, opts)
+			if !diff.Relocated && !diff.CountryChanged && !diff.CityChanged {
+				summary.Unchanged++
+				continue
+			}
+			summary.Changed++
+			if diff.Relocated {
+				summary.Relocated++
+				summary.MaxDistanceKm = max(summary.MaxDistanceKm, diff.DistanceKm)
+				totalDistance += diff.DistanceKm
+			}
+			if diff.CountryChanged {
+				summary.CountryChanged++
+			}
+			if diff.CityChanged {
+				summary.CityChanged++
+			}
+			if err := fn(diff); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("failed to diff new ip locations: %w", err)
+	}
+	if summary.Relocated > 0 {
+		summary.MeanDistanceKm = totalDistance / float64(summary.Relocated)
+	}
+
+	for _, loc := range oldOrder {
+		if loc.matched {
+			continue
+		}
+		summary.Removed++
+		if err = fn(IPLocationDiff{Kind: DiffRemoved, Old: &loc.location}); err != nil {
+			return summary, fmt.Errorf("failed to diff removed ip locations: %w", err)
+		}
+	}
+	return summary, nil
+}
+
+type diffedLocation struct {
+	location IPLocation
+	matched  bool // The IP is found in the new set.
+}
+
+func compareIPLocations(before, after IPLocation, opts DiffOptions) IPLocationDiff {
+	distance := before.Coordinate.DistanceKm(after.Coordinate)
+	return IPLocationDiff{
+		Kind:           DiffChanged,
+		Old:            &before,
+		New:            &after,
+		DistanceKm:     distance,
+		Relocated:      distance > opts.MinDistanceKm,
+		CountryChanged: before.CountryCode != after.CountryCode,
+		CityChanged:    before.City != after.City,
+	}
+}
@@ -0,0 +1,105 @@
+package geolocation_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func diffLocation(ip byte, countryCode, city string, lat, lon float64) geolocation.IPLocation {
+	return geolocation.IPLocation{IP: netip.AddrFrom4([4]byte{10, 0, 0, ip}), CountryCode: countryCode, City: city,
+		Coordinate: geolocation.Coordinate{Lat: lat, Lon: lon}}
+}
+
+func TestDiffIPLocations(t *testing.T) {
+	t.Parallel()
+	london, paris := diffLocation(1, "UK", "London", 51.5, -0.1), diffLocation(2, "FR", "Paris", 48.9, 2.4)
+	movedParis, removed := diffLocation(2, "FR", "Paris", 48.85, 2.35), diffLocation(3, "US", "Boston", 42.4, -71.1)
+	renamed, added := diffLocation(4, "DE", "Berlin", 52.5, 13.4), diffLocation(5, "NZ", "Auckland", -36.8, 174.7)
+	oldLister := &geolocationtest.ListerFake{Batches: [][]geolocation.IPLocation{
+		{london, paris}, {removed, diffLocation(4, "DE", "Munich", 52.5, 13.4)},
+	}}
+	newLister := &geolocationtest.ListerFake{Batches: [][]geolocation.IPLocation{
+		{added, london, movedParis}, {renamed, diffLocation(1, "UK", "Leeds", 53.8, -1.5)},
+	}}
+
+	var diffs []geolocation.IPLocationDiff
+	summary, err := geolocation.DiffIPLocations(context.Background(),
+		geolocation.DatasetBatches(oldLister, 1), geolocation.DatasetBatches(newLister, 2),
+		geolocation.DiffOptions{MinDistanceKm: 1}, func(diff geolocation.IPLocationDiff) error {
+			diffs = append(diffs, diff)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.ExportFilter{DatasetVersion: 1}, oldLister.Filter)
+	assert.Equal(t, geolocation.ExportFilter{DatasetVersion: 2}, newLister.Filter)
+
+	require.Len(t, diffs, 4)
+	assert.Equal(t, geolocation.IPLocationDiff{Kind: geolocation.DiffAdded, New: &added}, diffs[0])
+	assert.Equal(t, geolocation.DiffChanged, diffs[1].Kind)
+	assert.Equal(t, paris, *diffs[1].Old)
+	assert.Equal(t, movedParis, *diffs[1].New)
+	assert.InDelta(t, 6.7, diffs[1].DistanceKm, 0.1)
+	assert.True(t, diffs[1].Relocated)
+	assert.False(t, diffs[1].CountryChanged)
+	assert.False(t, diffs[1].CityChanged)
+	assert.Equal(t, geolocation.DiffChanged, diffs[2].Kind)
+	assert.Equal(t, renamed, *diffs[2].New)
+	assert.Zero(t, diffs[2].DistanceKm)
+	assert.False(t, diffs[2].Relocated)
+	assert.True(t, diffs[2].CityChanged)
+	assert.Equal(t, geolocation.IPLocationDiff{Kind: geolocation.DiffRemoved, Old: &removed}, diffs[3])
+
+	assert.Equal(t, 4, summary.Old)
+	assert.Equal(t, 4, summary.New, "the second location of 10.0.0.1 is skipped")
+	assert.Equal(t, 1, summary.Added)
+	assert.Equal(t, 1, summary.Removed)
+	assert.Equal(t, 2, summary.Changed)
+	assert.Equal(t, 1, summary.Unchanged)
+	assert.Equal(t, 1, summary.Relocated)
+	assert.Equal(t, 1, summary.CityChanged)
+	assert.Zero(t, summary.CountryChanged)
+	assert.InDelta(t, diffs[1].DistanceKm, summary.MaxDistanceKm, 1e-9)
+	assert.InDelta(t, diffs[1].DistanceKm, summary.MeanDistanceKm, 1e-9)
+}
+
+func TestDiffIPLocations_Errors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	notFound := geolocation.DatasetBatches(&geolocationtest.ListerFake{Err: geolocation.ErrDatasetNotFound}, 42)
+	some := geolocation.DatasetBatches(&geolocationtest.ListerFake{Batches: [][]geolocation.IPLocation{locations}}, 0)
+	noop := func(geolocation.IPLocationDiff) error { return nil }
+
+	_, err := geolocation.DiffIPLocations(ctx, notFound, some, geolocation.DiffOptions{}, noop)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+	_, err = geolocation.DiffIPLocations(ctx, some, notFound, geolocation.DiffOptions{}, noop)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+
+	errBrokenPipe := errors.New("broken pipe")
+	_, err = geolocation.DiffIPLocations(ctx, geolocation.DatasetBatches(&geolocationtest.ListerFake{}, 1), some,
+		geolocation.DiffOptions{}, func(geolocation.IPLocationDiff) error { return errBrokenPipe })
+	require.ErrorIs(t, err, errBrokenPipe)
+}
+
+func TestImporterBatches(t *testing.T) {
+	t.Parallel()
+	importer := &geolocationtest.ImporterFake{
+		Batches:    [][]geolocation.IPLocation{locations[:1]},
+		Statistics: []geolocation.ImportStatistics{{Imported: 1, NonValid: 1}},
+	}
+
+	var batches [][]geolocation.IPLocation
+	err := geolocation.ImporterBatches(importer)(context.Background(), func(batch []geolocation.IPLocation) error {
+		batches = append(batches, batch)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]geolocation.IPLocation{locations[:1]}, batches)
+}
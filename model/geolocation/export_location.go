@@ -0,0 +1,55 @@
+package geolocation
+
+import (
+	"context"
+	"fmt"
+)
+
+// ExportIPLocations - streams IP locations matching the filter from lister to exporter batch by batch,
+// so the whole dataset is never kept in memory. Returns the number of exported locations.
+// Returns ErrDatasetNotFound if the filtered dataset doesn't exist.
+// Returns a wrapped error if any other problem occurs.
+func ExportIPLocations(
+	ctx context.Context,
+	filter ExportFilter,
+	lister IPLocationLister,
+	exporter IPLocationExporter,
+) (int, error) {
+	const batchSize = 8192
+	exported := 0
+	err := lister.ListIPLocations(ctx, filter, batchSize, func(locations []IPLocation) error {
+		if err := exporter.ExportBatch(ctx, locations); err != nil {
+			return fmt.Errorf("failed to export ip locations: %w", err)
+		}
+		exported += len(locations)
+		return nil
+	})
+	if err != nil {
+		return exported, fmt.Errorf("failed to list ip locations: %w", err)
+	}
+	if err = exporter.Flush(); err != nil {
+		return exported, fmt.Errorf("failed to flush exported ip locations: %w", err)
+	}
+	return exported, nil
+}
+
+// ExportFilter - selects IP locations to export. Zero values don't filter.
+type ExportFilter struct {
+	CountryCode    string
+	DatasetVersion int // The active dataset is used if zero.
+}
+
+// IPLocationLister - interface for listing stored IP locations.
+type IPLocationLister interface {
+	// ListIPLocations calls fn with batches of up to batchSize locations matching the filter.
+	// Stops and returns the error returned by fn, if any.
+	// Returns ErrDatasetNotFound if the filtered dataset doesn't exist.
+	ListIPLocations(ctx context.Context, filter ExportFilter, batchSize int, fn func([]IPLocation) error) error
+}
+
+// IPLocationExporter - interface for exporting IP locations to some destination.
+type IPLocationExporter interface {
+	ExportBatch(ctx context.Context, locations []IPLocation) error
+	// Flush writes any buffered data, called once after all the batches are exported.
+	Flush() error
+}
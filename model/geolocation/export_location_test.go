@@ -0,0 +1,48 @@
+package geolocation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestExportIPLocations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	filter := geolocation.ExportFilter{CountryCode: "UK", DatasetVersion: 2}
+	lister := &geolocationtest.ListerFake{Batches: [][]geolocation.IPLocation{locations[:2], locations[2:]}}
+	exporter := &geolocationtest.ExporterFake{}
+
+	n, err := geolocation.ExportIPLocations(ctx, filter, lister, exporter)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, filter, lister.Filter)
+	require.Equal(t, [][]geolocation.IPLocation{locations[:2], locations[2:]}, exporter.Batches)
+	require.True(t, exporter.Flushed)
+}
+
+func TestExportIPLocations_ExportError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	lister := &geolocationtest.ListerFake{Batches: [][]geolocation.IPLocation{locations[:2], locations[2:]}}
+	exporter := &geolocationtest.ExporterFake{Err: errors.New("broken pipe")}
+
+	n, err := geolocation.ExportIPLocations(ctx, geolocation.ExportFilter{}, lister, exporter)
+	require.ErrorIs(t, err, exporter.Err)
+	require.Equal(t, 0, n)
+	require.False(t, exporter.Flushed)
+}
+
+func TestExportIPLocations_DatasetNotFound(t *testing.T) {
+	t.Parallel()
+	lister := &geolocationtest.ListerFake{Err: geolocation.ErrDatasetNotFound}
+
+	_, err := geolocation.ExportIPLocations(context.Background(), geolocation.ExportFilter{}, lister,
+		&geolocationtest.ExporterFake{})
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+}
@@ -0,0 +1,102 @@
+package geolocationtest
+
+import (
+	"context"
+	"io"
+	"net/netip"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// FetcherFunc - IPLocationFetcher calling the function, to fake lookups or check how they are made.
+type FetcherFunc func(ctx context.Context, ip netip.Addr) ([]geolocation.IPLocation, error)
+
+// FetchLocationsByIP - see geolocation.IPLocationFetcher interface specification.
+func (f FetcherFunc) FetchLocationsByIP(ctx context.Context, ip netip.Addr) ([]geolocation.IPLocation, error) {
+	return f(ctx, ip)
+}
+
+// ImporterFake - IPLocationImporter returning copies of the batches one by one, then io.EOF.
+type ImporterFake struct {
+	Batches    [][]geolocation.IPLocation
+	Statistics []geolocation.ImportStatistics // Of the batches, zero if missing.
+	Err        error                          // Returned instead of io.EOF after the batches, if set.
+	next       int
+}
+
+// ImportNextBatch - see geolocation.IPLocationImporter interface specification. The size is ignored.
+func (f *ImporterFake) ImportNextBatch(
+	ctx context.Context,
+	_ int,
+) ([]geolocation.IPLocation, geolocation.ImportStatistics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, geolocation.ImportStatistics{}, err //nolint:wrapcheck
+	}
+	if f.next >= len(f.Batches) {
+		if f.Err != nil {
+			return nil, geolocation.ImportStatistics{}, f.Err
+		}
+		return nil, geolocation.ImportStatistics{}, io.EOF
+	}
+	// Callers own the batch and may modify it in place, keep the fixtures intact.
+	batch, stats := append([]geolocation.IPLocation{}, f.Batches[f.next]...), geolocation.ImportStatistics{}
+	if f.next < len(f.Statistics) {
+		stats = f.Statistics[f.next]
+	}
+	f.next++
+	return batch, stats, nil
+}
+
+// ListerFake - IPLocationLister passing the batches to the callback, recording the filter.
+type ListerFake struct {
+	Batches [][]geolocation.IPLocation
+	Err     error                    // Returned instead of listing the batches, if set.
+	Filter  geolocation.ExportFilter // Of the last call.
+}
+
+// ListIPLocations - see geolocation.IPLocationLister interface specification. The batch size is ignored.
+func (f *ListerFake) ListIPLocations(
+	ctx context.Context,
+	filter geolocation.ExportFilter,
+	_ int,
+	fn func([]geolocation.IPLocation) error,
+) error {
+	f.Filter = filter
+	if err := ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+	if f.Err != nil {
+		return f.Err
+	}
+	for _, batch := range f.Batches {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExporterFake - IPLocationExporter recording copies of the exported batches.
+type ExporterFake struct {
+	Batches [][]geolocation.IPLocation
+	Flushed bool
+	Err     error // Returned by ExportBatch instead of recording the batch, if set.
+}
+
+// ExportBatch - see geolocation.IPLocationExporter interface specification.
+func (f *ExporterFake) ExportBatch(ctx context.Context, locations []geolocation.IPLocation) error {
+	if err := ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Batches = append(f.Batches, append([]geolocation.IPLocation{}, locations...))
+	return nil
+}
+
+// Flush - see geolocation.IPLocationExporter interface specification.
+func (f *ExporterFake) Flush() error {
+	f.Flushed = true
+	return nil
+}
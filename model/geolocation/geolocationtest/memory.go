@@ -0,0 +1,258 @@
+package geolocationtest
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Faults - errors returned by MemoryStorage operations instead of performing them, none if nil.
+type Faults struct {
+	Create   error // CreateDataset.
+	Store    error // StoreIPLocations.
+	Activate error // ActivateDataset.
+	Fetch    error // FetchLocationsByIP.
+	Profile  error // ProfileDataset.
+}
+
+// MemoryStorage - in-memory implementation of storage interfaces, to replace stored data with a fake in tests.
+// Safe for concurrent use.
+type MemoryStorage struct {
+	mu       sync.Mutex
+	datasets []geolocation.Dataset // Version is the index plus one, zero if removed.
+	records  [][]geolocation.IPLocation
+	active   int // Version of the active dataset, zero if none.
+	faults   Faults
+}
+
+// NewMemoryStorage - creates empty storage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+// NewMemoryStorageWith - creates storage with the active dataset of the locations, version 1.
+func NewMemoryStorageWith(locations []geolocation.IPLocation) *MemoryStorage {
+	s := NewMemoryStorage()
+	ctx := context.Background()
+	dataset, _ := s.CreateDataset(ctx)
+	_ = s.StoreIPLocations(ctx, dataset.Version, locations)
+	_ = s.ActivateDataset(ctx, dataset.Version)
+	return s
+}
+
+// SetFaults - makes the following operations fail with the faults.
+func (s *MemoryStorage) SetFaults(faults Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = faults
+}
+
+// CreateDataset - see geolocation.IPLocationStorer interface specification.
+func (s *MemoryStorage) CreateDataset(ctx context.Context) (geolocation.Dataset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := firstError(ctx.Err(), s.faults.Create); err != nil {
+		return geolocation.Dataset{}, err
+	}
+	dataset := geolocation.Dataset{Version: len(s.datasets) + 1, CreatedAt: time.Now().UTC()}
+	s.datasets = append(s.datasets, dataset)
+	s.records = append(s.records, nil)
+	return dataset, nil
+}
+
+// StoreIPLocations - see geolocation.IPLocationStorer interface specification.
+func (s *MemoryStorage) StoreIPLocations(
+	ctx context.Context,
+	datasetVersion int,
+	locations []geolocation.IPLocation,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := firstError(ctx.Err(), s.faults.Store); err != nil {
+		return err
+	}
+	if !s.exists(datasetVersion) {
+		return geolocation.ErrDatasetNotFound
+	}
+	for _, loc := range locations {
+		loc.IP = geolocation.NormalizeAddr(loc.IP)
+		loc.DatasetVersion = datasetVersion
+		s.records[datasetVersion-1] = append(s.records[datasetVersion-1], loc)
+	}
+	return nil
+}
+
+// ActivateDataset - see geolocation.IPLocationStorer interface specification.
+func (s *MemoryStorage) ActivateDataset(ctx context.Context, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := firstError(ctx.Err(), s.faults.Activate); err != nil {
+		return err
+	}
+	if !s.exists(version) {
+		return geolocation.ErrDatasetNotFound
+	}
+	if s.active != 0 {
+		s.datasets[s.active-1].Active = false
+	}
+	dataset := &s.datasets[version-1]
+	dataset.Active, dataset.ActivatedAt, dataset.Records = true, time.Now().UTC(), len(s.records[version-1])
+	s.active = version
+	return nil
+}
+
+// FetchLocationsByIP - see geolocation.IPLocationFetcher interface specification.
+func (s *MemoryStorage) FetchLocationsByIP(ctx context.Context, ip netip.Addr) ([]geolocation.IPLocation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := firstError(ctx.Err(), s.faults.Fetch); err != nil {
+		return nil, err
+	}
+	locations := []geolocation.IPLocation{}
+	if s.active == 0 {
+		return locations, nil
+	}
+	ip = geolocation.NormalizeAddr(ip)
+	for _, loc := range s.records[s.active-1] {
+		if loc.IP == ip {
+			locations = append(locations, loc)
+		}
+	}
+	return locations, nil
+}
+
+// FetchDataset - see geolocation.DatasetFetcher interface specification.
+func (s *MemoryStorage) FetchDataset(ctx context.Context, version int) (geolocation.Dataset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return geolocation.Dataset{}, err //nolint:wrapcheck
+	}
+	version, err := s.resolve(version)
+	if err != nil {
+		return geolocation.Dataset{}, err
+	}
+	return s.datasets[version-1], nil
+}
+
+// ListDatasets - see geolocation.DatasetManager interface specification.
+func (s *MemoryStorage) ListDatasets(ctx context.Context) ([]geolocation.Dataset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	var datasets []geolocation.Dataset
+	for _, d := range s.datasets {
+		if d.Version != 0 {
+			datasets = append(datasets, d)
+		}
+	}
+	return datasets, nil
+}
+
+// RemoveDataset - see geolocation.DatasetRemover interface specification. Reclaimed bytes are the size of the
+// removed records, not counting their strings.
+func (s *MemoryStorage) RemoveDataset(ctx context.Context, version int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+	if !s.exists(version) {
+		return 0, geolocation.ErrDatasetNotFound
+	}
+	if version == s.active {
+		return 0, geolocation.ErrDatasetActive
+	}
+	reclaimed := int64(len(s.records[version-1])) * int64(unsafe.Sizeof(geolocation.IPLocation{}))
+	s.datasets[version-1], s.records[version-1] = geolocation.Dataset{}, nil
+	return reclaimed, nil
+}
+
+// ListIPLocations - see geolocation.IPLocationLister interface specification.
+// The locations are copied before fn is called, so fn may use the storage.
+func (s *MemoryStorage) ListIPLocations(
+	ctx context.Context,
+	filter geolocation.ExportFilter,
+	batchSize int,
+	fn func([]geolocation.IPLocation) error,
+) error {
+	s.mu.Lock()
+	version, err := s.resolve(filter.DatasetVersion)
+	var locations []geolocation.IPLocation
+	if err == nil {
+		for _, loc := range s.records[version-1] {
+			if filter.CountryCode == "" || strings.EqualFold(loc.CountryCode, filter.CountryCode) {
+				locations = append(locations, loc)
+			}
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for len(locations) > 0 {
+		if err = ctx.Err(); err != nil {
+			return err //nolint:wrapcheck
+		}
+		n := min(batchSize, len(locations))
+		if err = fn(locations[:n:n]); err != nil {
+			return err
+		}
+		locations = locations[n:]
+	}
+	return nil
+}
+
+// ProfileDataset - see geolocation.DatasetProfiler interface specification.
+func (s *MemoryStorage) ProfileDataset(ctx context.Context, version int) (geolocation.DatasetProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := firstError(ctx.Err(), s.faults.Profile); err != nil {
+		return geolocation.DatasetProfile{}, err
+	}
+	version, err := s.resolve(version)
+	if err != nil {
+		return geolocation.DatasetProfile{}, err
+	}
+	profile := geolocation.DatasetProfile{Countries: map[string]int{}}
+	ips := map[netip.Addr]int{}
+	for _, loc := range s.records[version-1] {
+		profile.Countries[loc.CountryCode]++
+		if ips[loc.IP]++; ips[loc.IP] == 2 {
+			profile.AmbiguousIPs++
+		}
+	}
+	profile.IPs = len(ips)
+	return profile, nil
+}
+
+func (s *MemoryStorage) exists(version int) bool {
+	return version > 0 && version <= len(s.datasets) && s.datasets[version-1].Version != 0
+}
+
+// resolve - zero version means the active dataset.
+func (s *MemoryStorage) resolve(version int) (int, error) {
+	if version == 0 {
+		version = s.active
+	}
+	if !s.exists(version) {
+		return 0, geolocation.ErrDatasetNotFound
+	}
+	return version, nil
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package geolocationtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestMemoryStorage_Conformance(t *testing.T) {
+	t.Parallel()
+	geolocationtest.TestStorage(t, func(*testing.T) geolocationtest.Storage {
+		return geolocationtest.NewMemoryStorage()
+	})
+}
+
+func TestMemoryStorage_Faults(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := geolocationtest.NewMemoryStorageWith(geolocationtest.Locations)
+	errNoDB := errors.New("no db")
+	s.SetFaults(geolocationtest.Faults{Fetch: errNoDB, Store: errNoDB})
+
+	_, err := s.FetchLocationsByIP(ctx, geolocationtest.Locations[0].IP)
+	require.ErrorIs(t, err, errNoDB)
+	require.ErrorIs(t, s.StoreIPLocations(ctx, 1, geolocationtest.Locations), errNoDB)
+
+	s.SetFaults(geolocationtest.Faults{})
+	locs, err := s.FetchLocationsByIP(ctx, geolocationtest.Locations[0].IP)
+	require.NoError(t, err)
+	assert.Equal(t, geolocationtest.WithVersion(geolocationtest.Locations[:1], 1), locs)
+}
+
+func TestMemoryStorage_ListIPLocations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := geolocationtest.NewMemoryStorageWith(geolocationtest.Locations)
+	locs := geolocationtest.WithVersion(geolocationtest.Locations, 1)
+
+	var batches [][]geolocation.IPLocation
+	err := s.ListIPLocations(ctx, geolocation.ExportFilter{CountryCode: "uk"}, 1, func(b []geolocation.IPLocation) error {
+		batches = append(batches, b)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]geolocation.IPLocation{locs[:1], locs[2:]}, batches)
+
+	err = s.ListIPLocations(ctx, geolocation.ExportFilter{DatasetVersion: 2}, 1, nil)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+}
+
+func TestMemoryStorage_ProfileDataset(t *testing.T) {
+	t.Parallel()
+	ambiguous := geolocationtest.Locations[1]
+	ambiguous.IP = geolocationtest.Locations[0].IP
+	s := geolocationtest.NewMemoryStorageWith(append([]geolocation.IPLocation{ambiguous}, geolocationtest.Locations...))
+
+	profile, err := s.ProfileDataset(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.DatasetProfile{IPs: 3, AmbiguousIPs: 1, Countries: map[string]int{"UK": 2, "US": 2}},
+		profile)
+}
+
+func TestImporterFake(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	importer := &geolocationtest.ImporterFake{
+		Batches:    [][]geolocation.IPLocation{geolocationtest.Locations},
+		Statistics: []geolocation.ImportStatistics{{Imported: 3}},
+	}
+	s := geolocationtest.NewMemoryStorage()
+
+	stats, err := geolocation.ImportIPLocations(ctx, importer, s, geolocation.ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Imported)
+	locs, err := s.FetchLocationsByIP(ctx, geolocationtest.Locations[1].IP)
+	require.NoError(t, err)
+	require.Len(t, locs, 1)
+	assert.NotEmpty(t, locs[0].Provenance.ImportRunID)
+	locs[0].Provenance = geolocation.Provenance{}
+	assert.Equal(t, geolocationtest.WithVersion(geolocationtest.Locations[1:2], stats.DatasetVersion), locs)
+}
@@ -0,0 +1,228 @@
+// Package geolocationtest provides a conformance test suite for implementations of geolocation storage interfaces.
+package geolocationtest
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// Storage - a backend storing IP locations and fetching them from the active dataset.
+type Storage interface {
+	geolocation.IPLocationStorer
+	geolocation.IPLocationFetcher
+}
+
+// TestStorage - runs the conformance suite against storages created by newStorage, a new empty one per subtest.
+func TestStorage(t *testing.T, newStorage func(t *testing.T) Storage) {
+	t.Run("RoundTrip", func(t *testing.T) { testRoundTrip(t, newStorage(t)) })
+	t.Run("SeveralLocations", func(t *testing.T) { testSeveralLocations(t, newStorage(t)) })
+	t.Run("IPv6", func(t *testing.T) { testIPv6(t, newStorage(t)) })
+	t.Run("Provenance", func(t *testing.T) { testProvenance(t, newStorage(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newStorage(t)) })
+	t.Run("NotActivated", func(t *testing.T) { testNotActivated(t, newStorage(t)) })
+	t.Run("SwitchActive", func(t *testing.T) { testSwitchActive(t, newStorage(t)) })
+	t.Run("ActivateNotFound", func(t *testing.T) { testActivateNotFound(t, newStorage(t)) })
+	t.Run("ContextCanceled", func(t *testing.T) { testContextCanceled(t, newStorage(t)) })
+	t.Run("LargeBatch", func(t *testing.T) { testLargeBatch(t, newStorage(t)) })
+	t.Run("RemoveDataset", func(t *testing.T) { testRemoveDataset(t, newStorage(t)) })
+}
+
+// Locations - fixture of IP locations of different countries and cities.
+var Locations = []geolocation.IPLocation{
+	{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK", CountryName: "United Kingdom", City: "London",
+		Coordinate: geolocation.Coordinate{Lat: 51.5072, Lon: -0.1276}, MysteryValue: 42},
+	{IP: netip.AddrFrom4([4]byte{9, 9, 9, 9}), CountryCode: "US", CountryName: "United States", City: "New York",
+		Coordinate: geolocation.Coordinate{Lat: 40.7128, Lon: -74.006}, MysteryValue: 7},
+	{IP: netip.AddrFrom4([4]byte{8, 8, 4, 4}), CountryCode: "UK", CountryName: "United Kingdom", City: "Leeds",
+		Coordinate: geolocation.Coordinate{Lat: 53.8008, Lon: -1.5491}},
+}
+
+// WithVersion - copies of the locations as they are fetched from the dataset.
+func WithVersion(locations []geolocation.IPLocation, version int) []geolocation.IPLocation {
+	res := make([]geolocation.IPLocation, len(locations))
+	for i := range locations {
+		res[i] = locations[i]
+		res[i].DatasetVersion = version
+	}
+	return res
+}
+
+// StoreDataset - creates a dataset, stores the locations to it and activates it if asked to. Returns the version.
+func StoreDataset(
+	ctx context.Context,
+	t *testing.T,
+	storer geolocation.IPLocationStorer,
+	locations []geolocation.IPLocation,
+	activate bool,
+) int {
+	t.Helper()
+	dataset, err := storer.CreateDataset(ctx)
+	require.NoError(t, err)
+	require.NoError(t, storer.StoreIPLocations(ctx, dataset.Version, locations))
+	if activate {
+		require.NoError(t, storer.ActivateDataset(ctx, dataset.Version))
+	}
+	return dataset.Version
+}
+
+func testRoundTrip(t *testing.T, s Storage) {
+	ctx := context.Background()
+	version := StoreDataset(ctx, t, s, Locations, true)
+	for _, loc := range WithVersion(Locations, version) {
+		locs, err := s.FetchLocationsByIP(ctx, loc.IP)
+		require.NoError(t, err)
+		assert.Equal(t, []geolocation.IPLocation{loc}, locs)
+	}
+}
+
+func testSeveralLocations(t *testing.T, s Storage) {
+	ctx := context.Background()
+	leeds := Locations[2]
+	leeds.IP = Locations[0].IP
+	stored := []geolocation.IPLocation{Locations[0], Locations[1], leeds}
+	stored = WithVersion(stored, StoreDataset(ctx, t, s, stored, true))
+
+	locs, err := s.FetchLocationsByIP(ctx, Locations[0].IP)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []geolocation.IPLocation{stored[0], stored[2]}, locs)
+}
+
+func testIPv6(t *testing.T, s Storage) {
+	ctx := context.Background()
+	v6 := Locations[1]
+	v6.IP = netip.MustParseAddr("2001:db8::808:808") // Ends with the bytes of 8.8.8.8.
+	stored := WithVersion([]geolocation.IPLocation{Locations[0], v6}, StoreDataset(ctx, t, s,
+		[]geolocation.IPLocation{Locations[0], v6}, true))
+
+	locs, err := s.FetchLocationsByIP(ctx, v6.IP)
+	require.NoError(t, err)
+	assert.Equal(t, stored[1:], locs)
+	locs, err = s.FetchLocationsByIP(ctx, netip.MustParseAddr("::ffff:8.8.8.8")) // IPv4-mapped.
+	require.NoError(t, err)
+	assert.Equal(t, stored[:1], locs)
+}
+
+func testProvenance(t *testing.T, s Storage) {
+	ctx := context.Background()
+	stored := []geolocation.IPLocation{Locations[0], Locations[1]}
+	stored[0].Provenance = geolocation.Provenance{SourceID: "sha256:9f86d0", SourceName: "dump.csv",
+		ImportRunID: "run-1", Line: 2, ObservedAt: time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC)}
+	stored = WithVersion(stored, StoreDataset(ctx, t, s, stored, true))
+
+	for _, loc := range stored { // Locations without provenance are stored as well.
+		locs, err := s.FetchLocationsByIP(ctx, loc.IP)
+		require.NoError(t, err)
+		assert.Equal(t, []geolocation.IPLocation{loc}, locs)
+	}
+}
+
+func testNotFound(t *testing.T, s Storage) {
+	ctx := context.Background()
+	StoreDataset(ctx, t, s, Locations, true)
+	locs, err := s.FetchLocationsByIP(ctx, netip.AddrFrom4([4]byte{1, 1, 1, 1}))
+	require.NoError(t, err)
+	assert.NotNil(t, locs)
+	assert.Empty(t, locs)
+}
+
+func testNotActivated(t *testing.T, s Storage) {
+	ctx := context.Background()
+	locs, err := s.FetchLocationsByIP(ctx, Locations[0].IP)
+	require.NoError(t, err)
+	assert.Empty(t, locs)
+
+	StoreDataset(ctx, t, s, Locations, false)
+	locs, err = s.FetchLocationsByIP(ctx, Locations[0].IP)
+	require.NoError(t, err)
+	assert.Empty(t, locs)
+}
+
+func testSwitchActive(t *testing.T, s Storage) {
+	ctx := context.Background()
+	first := StoreDataset(ctx, t, s, Locations[:1], true)
+	moved := Locations[1]
+	moved.IP = Locations[0].IP
+	second := StoreDataset(ctx, t, s, []geolocation.IPLocation{moved}, true)
+
+	locs, err := s.FetchLocationsByIP(ctx, moved.IP)
+	require.NoError(t, err)
+	assert.Equal(t, WithVersion([]geolocation.IPLocation{moved}, second), locs)
+
+	require.NoError(t, s.ActivateDataset(ctx, first))
+	locs, err = s.FetchLocationsByIP(ctx, moved.IP)
+	require.NoError(t, err)
+	assert.Equal(t, WithVersion(Locations[:1], first), locs)
+}
+
+func testActivateNotFound(t *testing.T, s Storage) {
+	require.ErrorIs(t, s.ActivateDataset(context.Background(), 42), geolocation.ErrDatasetNotFound)
+}
+
+func testContextCanceled(t *testing.T, s Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	version := StoreDataset(ctx, t, s, Locations, true)
+	cancel()
+
+	_, err := s.FetchLocationsByIP(ctx, Locations[0].IP)
+	require.Error(t, err)
+	require.Error(t, s.StoreIPLocations(ctx, version, Locations))
+}
+
+// largeBatchSize - number of locations stored at once by the LargeBatch test.
+const largeBatchSize = 20000
+
+func testLargeBatch(t *testing.T, s Storage) {
+	ctx := context.Background()
+	locations := make([]geolocation.IPLocation, largeBatchSize)
+	for i := range locations {
+		locations[i] = Locations[i%len(Locations)]
+		locations[i].IP = netip.AddrFrom4([4]byte{10, byte(i >> 16), byte(i >> 8), byte(i)})
+	}
+	locations = WithVersion(locations, StoreDataset(ctx, t, s, locations, true))
+
+	for i := 0; i < len(locations); i += 997 {
+		locs, err := s.FetchLocationsByIP(ctx, locations[i].IP)
+		require.NoError(t, err)
+		assert.Equal(t, locations[i:i+1], locs)
+	}
+	locs, err := s.FetchLocationsByIP(ctx, locations[len(locations)-1].IP)
+	require.NoError(t, err)
+	assert.Equal(t, locations[len(locations)-1:], locs)
+}
+
+// testRemoveDataset - skipped unless the storage is a geolocation.DatasetRemover.
+func testRemoveDataset(t *testing.T, s Storage) {
+	remover, ok := s.(geolocation.DatasetRemover)
+	if !ok {
+		t.Skip("storage doesn't remove datasets")
+	}
+	ctx := context.Background()
+	old := StoreDataset(ctx, t, s, Locations, true)
+	active := StoreDataset(ctx, t, s, Locations[:1], true)
+
+	_, err := remover.RemoveDataset(ctx, active)
+	require.ErrorIs(t, err, geolocation.ErrDatasetActive)
+	reclaimed, err := remover.RemoveDataset(ctx, old)
+	require.NoError(t, err)
+	assert.Positive(t, reclaimed)
+	_, err = remover.RemoveDataset(ctx, old)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+
+	_, err = remover.FetchDataset(ctx, old)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+	require.ErrorIs(t, s.ActivateDataset(ctx, old), geolocation.ErrDatasetNotFound)
+	datasets, err := remover.ListDatasets(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 1)
+	assert.Equal(t, active, datasets[0].Version)
+	locs, err := s.FetchLocationsByIP(ctx, Locations[0].IP)
+	require.NoError(t, err)
+	assert.Equal(t, WithVersion(Locations[:1], active), locs)
+}
@@ -0,0 +1,62 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrImportJobNotFound = errors.New("import job not found")
+	ErrImportJobFinished = errors.New("import job is finished")
+)
+
+// ImportJobState - state of an import job, jobs are run in the background by the server.
+type ImportJobState string
+
+const (
+	ImportJobPending   ImportJobState = "pending"
+	ImportJobRunning   ImportJobState = "running"
+	ImportJobSucceeded ImportJobState = "succeeded"
+	ImportJobFailed    ImportJobState = "failed"
+	ImportJobCanceled  ImportJobState = "canceled"
+)
+
+// Finished - the job won't change anymore.
+func (s ImportJobState) Finished() bool {
+	return s == ImportJobSucceeded || s == ImportJobFailed || s == ImportJobCanceled
+}
+
+// ImportJob - import of IP locations from the source, kept in the storage to survive restarts of the server.
+type ImportJob struct {
+	ID     int
+	Source string // Path or URL of the data.
+	Format string
+	State  ImportJobState
+	// Statistics is the progress while the job is running. DatasetVersion is set once the dataset is created.
+	Statistics      ImportStatistics
+	Error           string // Why the job failed.
+	CancelRequested bool
+	CreatedAt       time.Time
+	StartedAt       time.Time // Zero if the job has not been started.
+	FinishedAt      time.Time // Zero if the job is not finished.
+}
+
+// ImportJobStorer - interface for keeping import jobs.
+type ImportJobStorer interface {
+	// CreateImportJob stores a pending job, and returns it with ID and creation time set.
+	CreateImportJob(ctx context.Context, job ImportJob) (ImportJob, error)
+	// FetchImportJob returns ErrImportJobNotFound if there is no such job.
+	FetchImportJob(ctx context.Context, id int) (ImportJob, error)
+	// ListImportJobs returns up to limit most recent jobs, the newest first.
+	ListImportJobs(ctx context.Context, limit int) ([]ImportJob, error)
+	// ClaimImportJob marks the oldest pending job as running, or a running one not updated for staleAfter,
+	// as its runner has gone. Returns ErrImportJobNotFound if there is nothing to run.
+	ClaimImportJob(ctx context.Context, staleAfter time.Duration) (ImportJob, error)
+	// UpdateImportJob saves state, statistics and error of the job,
+	// and returns it with the cancellation request of the storage.
+	UpdateImportJob(ctx context.Context, job ImportJob) (ImportJob, error)
+	// CancelImportJob cancels a pending job at once, and requests cancellation of a running one.
+	// Returns ErrImportJobNotFound or ErrImportJobFinished if the job can't be canceled.
+	CancelImportJob(ctx context.Context, id int) (ImportJob, error)
+}
@@ -3,43 +3,227 @@ package geolocation
 import (
 	"context"
 	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ImportIPLocations - imports IP locations with providing statistics.
-// Returns a wrapped error if any problem occurs.
+// ImportOptions - optional parameters of ImportIPLocations, the zero value is valid.
+type ImportOptions struct {
+	// Gates are checked before activation, the dataset is not activated if any of them fails.
+	Gates QualityGates
+	// Profiler profiles the imported and the active datasets, required by gates on the stored data.
+	Profiler DatasetProfiler
+	// Remover removes the dataset of a failed import with the locations stored so far, best effort.
+	// The dataset is left behind if nil, as well as the dataset failed by gates.
+	Remover DatasetRemover
+	// Progress is called with the cumulative progress once the dataset is created, after every stored batch,
+	// and once the dataset is activated.
+	Progress func(ImportProgress)
+	// Sizer estimates how much of the source is processed to calculate ETA, no ETA if nil.
+	Sizer ImportSizer
+	// Source is the provenance of every imported location, lines and observation times are taken from importers.
+	// ImportRunID is generated if empty, ObservedAt is the start of the import if zero.
+	Source Provenance
+}
+
+// NewImportRunID - random ID of an import run.
+func NewImportRunID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b) // Never fails on supported platforms.
+	return hex.EncodeToString(b)
+}
+
+// ImportSizer - interface for estimating the progress of reading the source, usually implemented by importers.
+type ImportSizer interface {
+	// Processed returns the fraction of the source read so far, from 0 to 1.
+	Processed() float64
+}
+
+// ImportProgress - cumulative progress of an import.
+type ImportProgress struct {
+	// Statistics of the stored batches, TimeSpent is the time elapsed. The statistics are a copy.
+	Statistics    ImportStatistics
+	RowsPerSecond float64       // Records of all kinds read per second.
+	Processed     float64       // Fraction of the source read, zero if unknown.
+	ETA           time.Duration // Estimated time remaining, zero if unknown.
+	Done          bool          // The dataset is activated, this is the last call.
+}
+
+func (o ImportOptions) report(stats ImportStatistics, elapsed time.Duration, done bool) {
+	if o.Progress == nil {
+		return
+	}
+	p := ImportProgress{Done: done}
+	p.Statistics.Add(stats) // Deep copy.
+	p.Statistics.DatasetVersion, p.Statistics.TimeSpent = stats.DatasetVersion, elapsed
+	p.Statistics.QualityGates = append([]QualityGateResult(nil), stats.QualityGates...)
+	if elapsed > 0 {
+		p.RowsPerSecond = float64(stats.Total()) / elapsed.Seconds()
+	}
+	switch {
+	case done:
+		p.Processed = 1
+	case o.Sizer != nil:
+		p.Processed = min(max(o.Sizer.Processed(), 0), 1)
+		if p.Processed > 0 {
+			p.ETA = time.Duration(float64(elapsed) * (1 - p.Processed) / p.Processed)
+		}
+	}
+	o.Progress(p)
+}
+
+// ImportIPLocations - imports IP locations to a new dataset with providing statistics.
+// The dataset is activated once all the locations are stored, so the previous one is used until then.
+// Returns a wrapped error if any problem occurs, ErrQualityGateFailed is returned with the statistics.
 func ImportIPLocations(
 	ctx context.Context,
 	importer IPLocationImporter,
 	storer IPLocationStorer,
+	opts ImportOptions,
+) (ImportStatistics, error) {
+	ctx, span := otel.Tracer(TracerName).Start(ctx, "geolocation.ImportIPLocations")
+	defer span.End()
+
+	stats, err := importIPLocations(ctx, importer, storer, opts)
+	if err != nil {
+		RecordSpanError(span, err)
+		return stats, err
+	}
+	span.SetAttributes(DatasetVersionKey.Int(stats.DatasetVersion), ResultCountKey.Int(stats.Imported))
+	return stats, nil
+}
+
+func importIPLocations(
+	ctx context.Context,
+	importer IPLocationImporter,
+	storer IPLocationStorer,
+	opts ImportOptions,
 ) (ImportStatistics, error) {
-	const batchSize = 65536
-	totalStats := ImportStatistics{}
-	depup := make(ipLocationsDeduplicator)
 	start := time.Now()
+	source := opts.Source
+	if source.ImportRunID == "" {
+		source.ImportRunID = NewImportRunID()
+	}
+	if source.ObservedAt.IsZero() {
+		source.ObservedAt = start.UTC()
+	}
+	dataset, err := storer.CreateDataset(ctx)
+	if err != nil {
+		return ImportStatistics{}, fmt.Errorf("failed to create dataset: %w", err)
+	}
+	opts.report(ImportStatistics{DatasetVersion: dataset.Version}, time.Since(start), false)
+
+	misplaced := &misplacedCounter{gates: opts.Gates}
+	totalStats, err := importBatches(ctx, importer, storer, dataset.Version, source, misplaced,
+		func(stats ImportStatistics) {
+			opts.report(stats, time.Since(start), false)
+		})
+	if err != nil {
+		opts.removeFailed(ctx, dataset.Version)
+		return ImportStatistics{}, err
+	}
+	totalStats.QualityGates, err = checkQualityGates(ctx, opts.Gates, opts.Profiler, totalStats, misplaced)
+	if errors.Is(err, ErrQualityGateFailed) {
+		totalStats.TimeSpent = time.Since(start)
+		return totalStats, fmt.Errorf("dataset %d is not activated: %w", dataset.Version, err)
+	}
+	if err != nil {
+		opts.removeFailed(ctx, dataset.Version)
+		return ImportStatistics{}, err
+	}
+
+	if err = storer.ActivateDataset(ctx, dataset.Version); err != nil {
+		opts.removeFailed(ctx, dataset.Version)
+		return ImportStatistics{}, fmt.Errorf("failed to activate dataset %d: %w", dataset.Version, err)
+	}
+	totalStats.TimeSpent = time.Since(start)
+	opts.report(totalStats, totalStats.TimeSpent, true)
+	return totalStats, nil
+}
+
+// removeFailed removes the dataset of the failed import, even if ctx is canceled, as that is a usual cause of the
+// failure. The error is recorded to the span, the retention policy collects the dataset if it is left behind.
+func (o ImportOptions) removeFailed(ctx context.Context, version int) {
+	if o.Remover == nil {
+		return
+	}
+	const removeTimeout = time.Minute
+	removeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), removeTimeout)
+	defer cancel()
+	if _, err := o.Remover.RemoveDataset(removeCtx, version); err != nil {
+		trace.SpanFromContext(ctx).RecordError(fmt.Errorf("failed to remove dataset %d: %w", version, err))
+	}
+}
+
+// importBatches imports all the batches, and calls progress with the cumulative statistics after every one.
+func importBatches(
+	ctx context.Context,
+	importer IPLocationImporter,
+	storer IPLocationStorer,
+	datasetVersion int,
+	source Provenance,
+	misplaced *misplacedCounter,
+	progress func(ImportStatistics),
+) (ImportStatistics, error) {
+	totalStats := ImportStatistics{DatasetVersion: datasetVersion}
+	depup := make(ipLocationsDeduplicator)
 
 	for {
-		ipLocations, stats, err := importer.ImportNextBatch(ctx, batchSize)
+		stats, err := importBatch(ctx, importer, storer, datasetVersion, source, depup, misplaced)
+		if errors.Is(err, io.EOF) {
+			return totalStats, nil
+		}
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				totalStats.TimeSpent = time.Since(start)
-				return totalStats, nil
-			}
-			return ImportStatistics{}, fmt.Errorf("failed to import ip locations: %w", err)
+			return ImportStatistics{}, err
 		}
+		totalStats.Add(stats)
+		progress(totalStats)
+	}
+}
+
+// importBatch imports and stores the next batch in its own span, returns io.EOF when no more data is available.
+func importBatch(
+	ctx context.Context,
+	importer IPLocationImporter,
+	storer IPLocationStorer,
+	datasetVersion int,
+	source Provenance,
+	depup ipLocationsDeduplicator,
+	misplaced *misplacedCounter,
+) (ImportStatistics, error) {
+	const batchSize = 65536
+	ctx, span := otel.Tracer(TracerName).Start(ctx, "geolocation.ImportBatch",
+		trace.WithAttributes(BatchSizeKey.Int(batchSize), DatasetVersionKey.Int(datasetVersion)))
+	defer span.End()
 
-		var dups int
-		ipLocations, dups = depup.deduplicate(ipLocations)
-		stats.ApplyDuplicates(dups)
+	ipLocations, stats, err := importer.ImportNextBatch(ctx, batchSize)
+	if errors.Is(err, io.EOF) {
+		return ImportStatistics{}, err //nolint:wrapcheck // Sentinel.
+	}
+	if err != nil {
+		RecordSpanError(span, err)
+		return ImportStatistics{}, fmt.Errorf("failed to import ip locations: %w", err)
+	}
 
-		if err = storer.StoreIPLocations(ctx, ipLocations); err != nil {
-			return ImportStatistics{}, fmt.Errorf("failed to store ip locations: %w", err)
-		}
-		totalStats.Add(stats)
+	var dups int
+	ipLocations, dups = depup.deduplicate(ipLocations)
+	addProvenance(ipLocations, source)
+	stats.ApplyDuplicates(dups)
+	misplaced.count(ipLocations)
+	span.SetAttributes(ResultCountKey.Int(len(ipLocations)))
+
+	if err = storer.StoreIPLocations(ctx, datasetVersion, ipLocations); err != nil {
+		RecordSpanError(span, err)
+		return ImportStatistics{}, fmt.Errorf("failed to store ip locations: %w", err)
 	}
+	return stats, nil
 }
 
 // IPLocationImporter - interface for importing IP locations from some source.
@@ -50,21 +234,44 @@ type IPLocationImporter interface {
 
 // IPLocationStorer - interface for storing IP locations.
 type IPLocationStorer interface {
-	StoreIPLocations(ctx context.Context, locations []IPLocation) error
+	// CreateDataset creates a new inactive dataset to store IP locations to.
+	CreateDataset(ctx context.Context) (Dataset, error)
+	StoreIPLocations(ctx context.Context, datasetVersion int, locations []IPLocation) error
+	// ActivateDataset makes the dataset active, deactivating the previously active one.
+	// Returns ErrDatasetNotFound if there is no such dataset.
+	ActivateDataset(ctx context.Context, datasetVersion int) error
 }
 
 // ImportStatistics - provides statistics about import process.
 type ImportStatistics struct {
-	Imported   int
-	NonValid   int
-	Duplicated int
-	TimeSpent  time.Duration
+	Imported        int
+	NonValid        int
+	NonValidReasons map[NonValidReason]int // Breakdown of NonValid, if known.
+	Duplicated      int
+	TimeSpent       time.Duration
+	DatasetVersion  int
+	QualityGates    []QualityGateResult // Results of the gates checked before activation.
 }
 
 func (s *ImportStatistics) Add(other ImportStatistics) {
 	s.Imported += other.Imported
 	s.NonValid += other.NonValid
 	s.Duplicated += other.Duplicated
+	for reason, n := range other.NonValidReasons {
+		if s.NonValidReasons == nil {
+			s.NonValidReasons = make(map[NonValidReason]int)
+		}
+		s.NonValidReasons[reason] += n
+	}
+}
+
+// AddNonValid counts a non-valid record, rejected because of the error.
+func (s *ImportStatistics) AddNonValid(err error) {
+	if s.NonValidReasons == nil {
+		s.NonValidReasons = make(map[NonValidReason]int)
+	}
+	s.NonValid++
+	s.NonValidReasons[NonValidReasonOf(err)]++
 }
 
 func (s *ImportStatistics) ApplyDuplicates(dups int) {
@@ -76,6 +283,47 @@ func (s *ImportStatistics) Total() int {
 	return s.Imported + s.NonValid + s.Duplicated
 }
 
+// NonValidRatio - share of non-valid records among all the records, zero if there are no records.
+func (s *ImportStatistics) NonValidRatio() float64 {
+	if s.Total() == 0 {
+		return 0
+	}
+	return float64(s.NonValid) / float64(s.Total())
+}
+
+// NonValidReason - reason of rejecting a non-valid record.
+type NonValidReason string
+
+const (
+	NonValidMalformed    NonValidReason = "malformed" // The record can't be parsed at all, e.g. wrong number of fields.
+	NonValidIP           NonValidReason = "invalid_ip"
+	NonValidCountryCode  NonValidReason = "invalid_country_code"
+	NonValidCountryName  NonValidReason = "invalid_country_name"
+	NonValidCity         NonValidReason = "invalid_city"
+	NonValidCoordinate   NonValidReason = "invalid_coordinate"
+	NonValidMysteryValue NonValidReason = "invalid_mystery_value"
+)
+
+// NonValidReasonOf returns the reason of rejecting a record by its validation error.
+func NonValidReasonOf(err error) NonValidReason {
+	for _, r := range []struct {
+		err    error
+		reason NonValidReason
+	}{
+		{ErrInvalidIP, NonValidIP},
+		{ErrInvalidCountryCode, NonValidCountryCode},
+		{ErrInvalidCountryName, NonValidCountryName},
+		{ErrInvalidCity, NonValidCity},
+		{ErrInvalidCoordinate, NonValidCoordinate},
+		{ErrInvalidMysteryValue, NonValidMysteryValue},
+	} {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+	return NonValidMalformed
+}
+
 type ipLocationsDeduplicator map[[md5.Size]byte]bool
 
 func (d *ipLocationsDeduplicator) deduplicate(locations []IPLocation) (result []IPLocation, duplicated int) {
@@ -90,3 +338,14 @@ func (d *ipLocationsDeduplicator) deduplicate(locations []IPLocation) (result []
 	}
 	return locations, duplicated
 }
+
+// addProvenance sets the provenance of the source, keeping lines and observation times given by the importer.
+func addProvenance(locations []IPLocation, source Provenance) {
+	for i := range locations {
+		p := &locations[i].Provenance
+		p.SourceID, p.SourceName, p.ImportRunID = source.SourceID, source.SourceName, source.ImportRunID
+		if p.ObservedAt.IsZero() {
+			p.ObservedAt = source.ObservedAt
+		}
+	}
+}
@@ -2,15 +2,17 @@ package geolocation_test
 
 import (
 	"context"
+	"errors"
 	"io"
-	"net"
+	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
 )
 
 func TestImportStatistics_Add(t *testing.T) {
@@ -60,52 +62,151 @@ func TestImportStatistics_Total(t *testing.T) {
 
 func TestImportIPLocations(t *testing.T) {
 	t.Parallel()
-	importer, storer := new(importerMock), new(storerMock)
-	importer.On("ImportNextBatch", mock.Anything, mock.AnythingOfType("int")).Return(
-		locations, geolocation.ImportStatistics{Imported: 3, TimeSpent: 0}, nil).Once()
-	importer.On("ImportNextBatch", mock.Anything, mock.AnythingOfType("int")).Return(
-		[]geolocation.IPLocation{}, geolocation.ImportStatistics{}, io.EOF).Once()
-
-	dedupLocs := []geolocation.IPLocation{locations[0], locations[2]}
-	storer.On("StoreIPLocations", mock.Anything, dedupLocs).Return(nil).Once()
+	importer := &geolocationtest.ImporterFake{
+		Batches:    [][]geolocation.IPLocation{locations},
+		Statistics: []geolocation.ImportStatistics{{Imported: 3, TimeSpent: 0}},
+	}
+	storer := geolocationtest.NewMemoryStorage()
+	source := geolocation.Provenance{SourceID: "sha256:9f86d0", SourceName: "dump.csv", ImportRunID: "run-1",
+		ObservedAt: time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC)}
 
-	stats, err := geolocation.ImportIPLocations(context.Background(), importer, storer)
+	stats, err := geolocation.ImportIPLocations(context.Background(), importer, storer,
+		geolocation.ImportOptions{Source: source})
 	require.NoError(t, err)
 	assert.Equal(t, 2, stats.Imported)
 	assert.Equal(t, 1, stats.Duplicated)
 	assert.Equal(t, 0, stats.NonValid)
+	assert.Equal(t, 1, stats.DatasetVersion)
+
+	dedupLocs := geolocationtest.WithVersion([]geolocation.IPLocation{locations[0], locations[2]}, 1)
+	for i := range dedupLocs {
+		dedupLocs[i].Provenance = source
+	}
+	assert.Equal(t, dedupLocs, storedLocations(t, storer))
+}
+
+func TestImportIPLocations_Provenance(t *testing.T) {
+	t.Parallel()
+	observed := time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC)
+	locs := []geolocation.IPLocation{locations[0], locations[2]}
+	locs[0].Provenance = geolocation.Provenance{Line: 2}
+	locs[1].Provenance = geolocation.Provenance{Line: 4, ObservedAt: observed}
+	importer := &geolocationtest.ImporterFake{Batches: [][]geolocation.IPLocation{locs}}
+	storer := geolocationtest.NewMemoryStorage()
+	start := time.Now()
+
+	_, err := geolocation.ImportIPLocations(context.Background(), importer, storer,
+		geolocation.ImportOptions{Source: geolocation.Provenance{SourceName: "dump.csv"}})
+	require.NoError(t, err)
+	stored := storedLocations(t, storer)
+	require.Len(t, stored, 2)
+	assert.Equal(t, "dump.csv", stored[0].Provenance.SourceName)
+	assert.Equal(t, 2, stored[0].Provenance.Line)
+	assert.NotEmpty(t, stored[0].Provenance.ImportRunID)
+	assert.WithinRange(t, stored[0].Provenance.ObservedAt, start, time.Now()) // The import time by default.
+	assert.Equal(t, stored[0].Provenance.ImportRunID, stored[1].Provenance.ImportRunID)
+	assert.Equal(t, 4, stored[1].Provenance.Line)
+	assert.Equal(t, observed, stored[1].Provenance.ObservedAt)
+}
 
-	importer.AssertExpectations(t)
-	storer.AssertExpectations(t)
+// storedLocations returns locations of the active dataset.
+func storedLocations(t *testing.T, lister geolocation.IPLocationLister) []geolocation.IPLocation {
+	t.Helper()
+	var stored []geolocation.IPLocation
+	require.NoError(t, lister.ListIPLocations(context.Background(), geolocation.ExportFilter{}, 10,
+		func(locs []geolocation.IPLocation) error {
+			stored = append(stored, locs...)
+			return nil
+		}))
+	return stored
 }
 
-// TODO: Add more cases for ImportIPLocations.
+func TestImportIPLocations_StoreError(t *testing.T) {
+	t.Parallel()
+	importer := &geolocationtest.ImporterFake{Batches: [][]geolocation.IPLocation{locations[:1]}}
+	storer := geolocationtest.NewMemoryStorage()
+	storer.SetFaults(geolocationtest.Faults{Store: errors.New("disk is full")})
+
+	_, err := geolocation.ImportIPLocations(context.Background(), importer, storer, geolocation.ImportOptions{})
+	require.Error(t, err)
+
+	dataset, err := storer.FetchDataset(context.Background(), 1)
+	require.NoError(t, err)
+	assert.False(t, dataset.Active, "the dataset must not be activated")
+}
+
+func TestImportIPLocations_RemoveFailed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	importer := &geolocationtest.ImporterFake{Batches: [][]geolocation.IPLocation{locations[:1]},
+		Err: errors.New("connection reset")}
+	storer := geolocationtest.NewMemoryStorage()
+
+	_, err := geolocation.ImportIPLocations(ctx, importer, storer, geolocation.ImportOptions{Remover: storer})
+	require.ErrorIs(t, err, importer.Err)
+	_, err = storer.FetchDataset(ctx, 1)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound, "the dataset must be removed")
 
-type importerMock struct {
-	mock.Mock
+	storer.SetFaults(geolocationtest.Faults{Activate: errors.New("connection lost")})
+	importer = &geolocationtest.ImporterFake{Batches: [][]geolocation.IPLocation{locations[:1]}}
+	_, err = geolocation.ImportIPLocations(ctx, importer, storer, geolocation.ImportOptions{Remover: storer})
+	require.Error(t, err)
+	_, err = storer.FetchDataset(ctx, 2)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound, "the dataset must be removed")
 }
 
-func (i *importerMock) ImportNextBatch(
-	ctx context.Context,
-	size int,
-) ([]geolocation.IPLocation, geolocation.ImportStatistics, error) {
-	args := i.Called(ctx, size)
-	//nolint:wrapcheck
-	return args.Get(0).([]geolocation.IPLocation), args.Get(1).(geolocation.ImportStatistics), args.Error(2)
+func TestImportIPLocations_Progress(t *testing.T) {
+	t.Parallel()
+	importer := &geolocationtest.ImporterFake{
+		Batches: [][]geolocation.IPLocation{locations[:1], locations[2:]},
+		Statistics: []geolocation.ImportStatistics{
+			{Imported: 1, NonValid: 1, NonValidReasons: map[geolocation.NonValidReason]int{geolocation.NonValidIP: 1}},
+			{Imported: 1},
+		},
+	}
+	storer := geolocationtest.NewMemoryStorage()
+	sizer := &sizerStub{}
+
+	var reports []geolocation.ImportProgress
+	_, err := geolocation.ImportIPLocations(context.Background(), importer, storer, geolocation.ImportOptions{
+		Progress: func(p geolocation.ImportProgress) {
+			reports = append(reports, p)
+			sizer.processed += 0.25
+		},
+		Sizer: sizer,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, reports, 4)
+	assert.Equal(t, geolocation.ImportStatistics{DatasetVersion: 1, TimeSpent: reports[0].Statistics.TimeSpent},
+		reports[0].Statistics)
+	first, second, last := reports[1], reports[2], reports[3]
+	assert.Equal(t, 1, first.Statistics.Imported)
+	assert.Equal(t, 2, first.Statistics.Total())
+	assert.Equal(t, 1, first.Statistics.DatasetVersion)
+	assert.InDelta(t, 0.25, first.Processed, 1e-9)
+	assert.InDelta(t, 3*first.Statistics.TimeSpent, first.ETA, float64(time.Microsecond))
+	assert.False(t, first.Done)
+	assert.Equal(t, 2, second.Statistics.Imported)
+	assert.InDelta(t, 0.5, second.Processed, 1e-9)
+	assert.Equal(t, map[geolocation.NonValidReason]int{geolocation.NonValidIP: 1}, first.Statistics.NonValidReasons)
+	assert.True(t, last.Done)
+	assert.Equal(t, 1.0, last.Processed)
+	assert.Zero(t, last.ETA)
+	assert.Equal(t, 2, last.Statistics.Imported)
 }
 
-type storerMock struct {
-	mock.Mock
+type sizerStub struct {
+	processed float64
 }
 
-func (s *storerMock) StoreIPLocations(ctx context.Context, locations []geolocation.IPLocation) error {
-	args := s.Called(ctx, locations)
-	return args.Error(0) //nolint:wrapcheck
+func (s *sizerStub) Processed() float64 {
+	return s.processed
 }
 
 var locations = []geolocation.IPLocation{
 	{
-		IP:          net.IPv4(1, 2, 3, 4),
+		IP:          netip.AddrFrom4([4]byte{1, 2, 3, 4}),
 		CountryCode: "UK",
 		CountryName: "United Kingdom",
 		City:        "London",
@@ -116,7 +217,7 @@ var locations = []geolocation.IPLocation{
 		MysteryValue: 42,
 	},
 	{
-		IP:          net.IPv4(1, 2, 3, 4),
+		IP:          netip.AddrFrom4([4]byte{1, 2, 3, 4}),
 		CountryCode: "UK",
 		CountryName: "United Kingdom",
 		City:        "London",
@@ -127,7 +228,7 @@ var locations = []geolocation.IPLocation{
 		MysteryValue: 42,
 	},
 	{
-		IP:          net.IPv4(1, 2, 3, 6),
+		IP:          netip.AddrFrom4([4]byte{1, 2, 3, 6}),
 		CountryCode: "NZ",
 		CountryName: "New Zealand",
 		City:        "Auckland",
@@ -138,3 +239,42 @@ var locations = []geolocation.IPLocation{
 		MysteryValue: 42,
 	},
 }
+
+func TestImportStatistics_AddNonValid(t *testing.T) {
+	t.Parallel()
+	s1 := geolocation.ImportStatistics{}
+	s1.AddNonValid(errors.New("wrong number of fields"))
+	s2 := geolocation.ImportStatistics{}
+	_, err := geolocation.NewIPLocationFromStrings("8.8.8.X", "UK", "United Kingdom", "London", "1", "2", "42")
+	s2.AddNonValid(err)
+	s2.AddNonValid(err)
+	s1.Add(s2)
+	require.Equal(t, geolocation.ImportStatistics{
+		NonValid: 3,
+		NonValidReasons: map[geolocation.NonValidReason]int{
+			geolocation.NonValidMalformed: 1,
+			geolocation.NonValidIP:        2,
+		},
+	}, s1)
+}
+
+func TestNonValidReasonOf(t *testing.T) {
+	t.Parallel()
+	newLocation := func(ip, code, name, city, lat, lon, mystery string) error {
+		_, err := geolocation.NewIPLocationFromStrings(ip, code, name, city, lat, lon, mystery)
+		return err
+	}
+	assert.Equal(t, geolocation.NonValidIP, geolocation.NonValidReasonOf(
+		newLocation("", "UK", "United Kingdom", "London", "1", "2", "42")))
+	assert.Equal(t, geolocation.NonValidCountryCode, geolocation.NonValidReasonOf(
+		newLocation("8.8.8.8", "UKR", "United Kingdom", "London", "1", "2", "42")))
+	assert.Equal(t, geolocation.NonValidCountryName, geolocation.NonValidReasonOf(
+		newLocation("8.8.8.8", "UK", "UK", "London", "1", "2", "42")))
+	assert.Equal(t, geolocation.NonValidCity, geolocation.NonValidReasonOf(
+		newLocation("8.8.8.8", "UK", "United Kingdom", "", "1", "2", "42")))
+	assert.Equal(t, geolocation.NonValidCoordinate, geolocation.NonValidReasonOf(
+		newLocation("8.8.8.8", "UK", "United Kingdom", "London", "91", "2", "42")))
+	assert.Equal(t, geolocation.NonValidMysteryValue, geolocation.NonValidReasonOf(
+		newLocation("8.8.8.8", "UK", "United Kingdom", "London", "1", "2", "-42")))
+	assert.Equal(t, geolocation.NonValidMalformed, geolocation.NonValidReasonOf(io.ErrUnexpectedEOF))
+}
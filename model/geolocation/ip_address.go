@@ -0,0 +1,56 @@
+package geolocation
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+)
+
+// ParseAddr - parses IPv4 or IPv6 address and normalizes it with NormalizeAddr.
+func ParseAddr(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%w: failed to parse: %s", ErrInvalidIP, s)
+	}
+	return NormalizeAddr(addr), nil
+}
+
+// NormalizeAddr - the form IP addresses are imported, stored and looked up in: IPv4-mapped IPv6 addresses
+// (::ffff:1.2.3.4) are unmapped to IPv4, IPv6 zones are stripped.
+func NormalizeAddr(addr netip.Addr) netip.Addr {
+	return addr.Unmap().WithZone("")
+}
+
+var (
+	prefix6to4   = netip.MustParsePrefix("2002::/16")
+	prefixTeredo = netip.MustParsePrefix("2001::/32")
+)
+
+// EmbeddedIPv4 - IPv4 address embedded into 6to4 (2002:AABB:CCDD::/48 for A.B.C.D) or Teredo (client address
+// in the last 32 bits, inverted) IPv6 address, and whether there is one. IPv4 addresses are returned as is.
+func EmbeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	addr = NormalizeAddr(addr)
+	if addr.Is4() {
+		return addr, true
+	}
+	b := addr.As16()
+	switch {
+	case prefix6to4.Contains(addr):
+		return netip.AddrFrom4([4]byte{b[2], b[3], b[4], b[5]}), true
+	case prefixTeredo.Contains(addr):
+		return netip.AddrFrom4([4]byte{^b[12], ^b[13], ^b[14], ^b[15]}), true
+	}
+	return addr, false
+}
+
+// EmbeddedIPv4Fetcher - IPLocationFetcher looking 6to4 and Teredo addresses up by their embedded IPv4 addresses.
+// IPv4-mapped addresses are looked up as IPv4 anyway.
+type EmbeddedIPv4Fetcher struct {
+	IPLocationFetcher
+}
+
+// FetchLocationsByIP - see IPLocationFetcher interface specification.
+func (f EmbeddedIPv4Fetcher) FetchLocationsByIP(ctx context.Context, ip netip.Addr) ([]IPLocation, error) {
+	ip, _ = EmbeddedIPv4(ip)
+	return f.IPLocationFetcher.FetchLocationsByIP(ctx, ip) //nolint:wrapcheck
+}
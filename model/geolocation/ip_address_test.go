@@ -0,0 +1,77 @@
+package geolocation_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestParseAddr(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		s    string
+		want netip.Addr
+	}{
+		{s: "8.8.8.8", want: netip.AddrFrom4([4]byte{8, 8, 8, 8})},
+		{s: "::ffff:8.8.8.8", want: netip.AddrFrom4([4]byte{8, 8, 8, 8})},
+		{s: "::FFFF:808:808", want: netip.AddrFrom4([4]byte{8, 8, 8, 8})},
+		{s: "2001:DB8::1", want: netip.MustParseAddr("2001:db8::1")},
+		{s: "fe80::1%eth0", want: netip.MustParseAddr("fe80::1")},
+	}
+	for _, tt := range tests {
+		got, err := geolocation.ParseAddr(tt.s)
+		require.NoError(t, err, tt.s)
+		assert.Equal(t, tt.want, got, tt.s)
+	}
+
+	for _, s := range []string{"", "8.8.8", "8.8.8.256", "example.com", "1.2.3.4/32"} {
+		_, err := geolocation.ParseAddr(s)
+		require.ErrorIs(t, err, geolocation.ErrInvalidIP, s)
+	}
+}
+
+func TestEmbeddedIPv4(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		addr   string
+		want   string
+		wantOK bool
+	}{
+		{addr: "192.0.2.1", want: "192.0.2.1", wantOK: true},
+		{addr: "::ffff:192.0.2.1", want: "192.0.2.1", wantOK: true},
+		{addr: "2002:c000:201::1", want: "192.0.2.1", wantOK: true},
+		{addr: "2001:0:4136:e378:8000:63bf:3fff:fdd2", want: "192.0.2.45", wantOK: true},
+		{addr: "2001:db8::1", want: "2001:db8::1"},
+		{addr: "fe80::1%eth0", want: "fe80::1"},
+	}
+	for _, tt := range tests {
+		got, ok := geolocation.EmbeddedIPv4(netip.MustParseAddr(tt.addr))
+		assert.Equal(t, tt.wantOK, ok, tt.addr)
+		assert.Equal(t, netip.MustParseAddr(tt.want), got, tt.addr)
+	}
+}
+
+func TestEmbeddedIPv4Fetcher(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var fetched []netip.Addr
+	fetcher := geolocationtest.FetcherFunc(func(_ context.Context, ip netip.Addr) ([]geolocation.IPLocation, error) {
+		fetched = append(fetched, ip)
+		return []geolocation.IPLocation{}, nil
+	})
+
+	embedded := geolocation.EmbeddedIPv4Fetcher{IPLocationFetcher: fetcher}
+	for _, addr := range []string{"2002:c000:201::1", "192.0.2.1", "2001:db8::1"} {
+		_, err := embedded.FetchLocationsByIP(ctx, netip.MustParseAddr(addr))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []netip.Addr{
+		netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1"),
+	}, fetched)
+}
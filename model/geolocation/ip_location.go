@@ -4,20 +4,45 @@ import (
 	"bytes"
 	"crypto/md5"
 	"encoding/gob"
+	"errors"
 	"fmt"
-	"net"
+	"net/netip"
 	"regexp"
 	"strconv"
+	"time"
+)
+
+// Validation errors, wrapped by NewIPLocationFromStrings. See NonValidReasonOf.
+var (
+	ErrInvalidIP           = errors.New("invalid ip address")
+	ErrInvalidCountryCode  = errors.New("invalid country code")
+	ErrInvalidCountryName  = errors.New("invalid country name")
+	ErrInvalidCity         = errors.New("invalid city")
+	ErrInvalidCoordinate   = errors.New("invalid coordinate")
+	ErrInvalidMysteryValue = errors.New("invalid mystery value")
 )
 
 // IPLocation - IP address that was observed in some location.Can be obtained from CSV or other sources.
 type IPLocation struct {
-	IP          net.IP
+	IP          netip.Addr // Normalized, see NormalizeAddr.
 	CountryCode string
 	CountryName string
 	City        string
 	Coordinate
 	MysteryValue uint64
+	// DatasetVersion - version of the dataset the location is fetched from, zero if it is not stored yet.
+	DatasetVersion int
+	// Provenance - set by the import, not a part of the record compared for duplicates.
+	Provenance Provenance
+}
+
+// Provenance - where an IP location comes from. Fields are empty for locations imported before it was recorded.
+type Provenance struct {
+	SourceID    string    // Identifies the content of the source, e.g. its SHA-256.
+	SourceName  string    // Human-readable name of the source, e.g. its path or URL.
+	ImportRunID string    // Identifies the import which stored the location.
+	Line        int       // Line of the record in the source (row for Parquet) starting from 1, zero if unknown.
+	ObservedAt  time.Time // When the location was observed, the time of the import unless the source tells.
 }
 
 // NewIPLocationFromStrings - creates IPLocation from strings representation. Useful for CSVs, logs, etc.
@@ -30,30 +55,30 @@ func NewIPLocationFromStrings(
 	longitude,
 	mystery string,
 ) (IPLocation, error) {
-	ipAddr := net.ParseIP(ip)
-	if ipAddr == nil {
-		return IPLocation{}, fmt.Errorf("failed to parse ip address: %s", ip)
+	ipAddr, err := ParseAddr(ip)
+	if err != nil {
+		return IPLocation{}, err
 	}
 
 	// TODO: Is better to validate names through countries/cities catalog with normalizing.
 	if !validCountryCode(countryCode) {
-		return IPLocation{}, fmt.Errorf("country code must be 2 characters: %s", countryCode)
+		return IPLocation{}, fmt.Errorf("%w: must be 2 characters: %s", ErrInvalidCountryCode, countryCode)
 	}
 	if len(countryName) < 4 {
-		return IPLocation{}, fmt.Errorf("country name must be at least 4 characters: %s", countryName)
+		return IPLocation{}, fmt.Errorf("%w: must be at least 4 characters: %s", ErrInvalidCountryName, countryName)
 	}
 	if city == "" {
-		return IPLocation{}, fmt.Errorf("city name is empty")
+		return IPLocation{}, fmt.Errorf("%w: city name is empty", ErrInvalidCity)
 	}
 
 	coord, err := NewCoordinateFromStrings(latitude, longitude)
 	if err != nil {
-		return IPLocation{}, fmt.Errorf("failed to parse coordinate: %w", err)
+		return IPLocation{}, fmt.Errorf("%w: %v", ErrInvalidCoordinate, err) //nolint:errorlint
 	}
 
 	mysteryValue, err := strconv.ParseUint(mystery, 10, 64)
 	if err != nil {
-		return IPLocation{}, fmt.Errorf("failed to parse mystery value: %w", err)
+		return IPLocation{}, fmt.Errorf("%w: %v", ErrInvalidMysteryValue, err) //nolint:errorlint
 	}
 
 	return IPLocation{
@@ -66,9 +91,13 @@ func NewIPLocationFromStrings(
 	}, nil
 }
 
+// MD5 - fingerprint of the record, equal locations have equal fingerprints. Provenance is not fingerprinted,
+// so the same record on different lines of the source is a duplicate.
 func (l *IPLocation) MD5() [md5.Size]byte {
 	var b bytes.Buffer
-	_ = gob.NewEncoder(&b).Encode(*l)
+	record := *l
+	record.Provenance = Provenance{}
+	_ = gob.NewEncoder(&b).Encode(record)
 	return md5.Sum(b.Bytes())
 }
 
@@ -1,7 +1,7 @@
 package geolocation_test
 
 import (
-	"net"
+	"net/netip"
 	"reflect"
 	"testing"
 
@@ -39,7 +39,31 @@ func TestNewIPLocationFromStrings(t *testing.T) {
 				mystery:     "42",
 			},
 			want: geolocation.IPLocation{
-				IP:          net.IPv4(8, 8, 8, 8),
+				IP:          netip.AddrFrom4([4]byte{8, 8, 8, 8}),
+				CountryCode: "UK",
+				CountryName: "United Kingdom",
+				City:        "London",
+				Coordinate: geolocation.Coordinate{
+					Lat: 1.23,
+					Lon: -0.42,
+				},
+				MysteryValue: 42,
+			},
+			wantErr: false,
+		},
+		{
+			name: "IPv4-mapped address is unmapped",
+			args: args{
+				ip:          "::ffff:8.8.8.8",
+				countryCode: "UK",
+				countryName: "United Kingdom",
+				city:        "London",
+				latitude:    "1.23",
+				longitude:   "-0.42",
+				mystery:     "42",
+			},
+			want: geolocation.IPLocation{
+				IP:          netip.AddrFrom4([4]byte{8, 8, 8, 8}),
 				CountryCode: "UK",
 				CountryName: "United Kingdom",
 				City:        "London",
@@ -171,7 +195,7 @@ func TestNewIPLocationFromStrings(t *testing.T) {
 func TestIPLocation_MD5(t *testing.T) {
 	t.Parallel()
 	loc := geolocation.IPLocation{
-		IP:          net.IPv4(8, 8, 8, 8),
+		IP:          netip.AddrFrom4([4]byte{8, 8, 8, 8}),
 		CountryCode: "UK",
 		CountryName: "United Kingdom",
 		City:        "London",
@@ -182,7 +206,7 @@ func TestIPLocation_MD5(t *testing.T) {
 		MysteryValue: 2342,
 	}
 	notEqualLoc := geolocation.IPLocation{
-		IP:          net.IPv4(8, 8, 8, 8),
+		IP:          netip.AddrFrom4([4]byte{8, 8, 8, 8}),
 		CountryCode: "UK",
 		CountryName: "United Kingdom",
 		City:        "London",
@@ -193,7 +217,7 @@ func TestIPLocation_MD5(t *testing.T) {
 		MysteryValue: 2341, // <-- DIFFERENT
 	}
 	equalLoc := geolocation.IPLocation{
-		IP:          net.IPv4(8, 8, 8, 8),
+		IP:          netip.AddrFrom4([4]byte{8, 8, 8, 8}),
 		CountryCode: "UK",
 		CountryName: "United Kingdom",
 		City:        "London",
@@ -202,6 +226,7 @@ func TestIPLocation_MD5(t *testing.T) {
 			Lon: -0.42,
 		},
 		MysteryValue: 2342,
+		Provenance:   geolocation.Provenance{SourceName: "other.csv", Line: 42},
 	}
 	assert.Equal(t, loc.MD5(), equalLoc.MD5())
 	assert.NotEqual(t, loc.MD5(), notEqualLoc.MD5())
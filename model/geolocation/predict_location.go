@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
+	"net/netip"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var ErrIPLocationNotFound = errors.New("ip location not found")
@@ -14,11 +17,17 @@ var ErrIPLocationAmbiguous = errors.New("ip location is ambiguous")
 // Returns ErrIPLocationNotFound if IP location is not found.
 // Returns ErrIPLocationAmbiguous if IP more than one location known for the IP.
 // Returns a wrapped error if any other error occurs.
-func PredictIPLocation(ctx context.Context, ip net.IP, fetcher IPLocationFetcher) (IPLocation, error) {
+func PredictIPLocation(ctx context.Context, ip netip.Addr, fetcher IPLocationFetcher) (IPLocation, error) {
+	ctx, span := otel.Tracer(TracerName).Start(ctx, "geolocation.PredictIPLocation",
+		trace.WithAttributes(IPFamilyAttribute(ip)))
+	defer span.End()
+
 	locations, err := fetcher.FetchLocationsByIP(ctx, ip)
 	if err != nil {
+		RecordSpanError(span, err)
 		return IPLocation{}, fmt.Errorf("failed to fetch ip locations: %w", err)
 	}
+	span.SetAttributes(ResultCountKey.Int(len(locations)))
 	if len(locations) == 0 {
 		return IPLocation{}, ErrIPLocationNotFound
 	}
@@ -32,5 +41,5 @@ func PredictIPLocation(ctx context.Context, ip net.IP, fetcher IPLocationFetcher
 type IPLocationFetcher interface {
 	// FetchLocationsByIP returns all possible locations for given IP address.
 	// If no locations are found, returns empty slice.
-	FetchLocationsByIP(ctx context.Context, ip net.IP) ([]IPLocation, error)
+	FetchLocationsByIP(ctx context.Context, ip netip.Addr) ([]IPLocation, error)
 }
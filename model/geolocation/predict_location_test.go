@@ -2,60 +2,44 @@ package geolocation_test
 
 import (
 	"context"
-	"net"
+	"net/netip"
 	"testing"
 
-	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
 )
 
 func TestPredictIPLocation(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
-	fetcher := new(fetcherMock)
-	ip := net.IPv4(1, 2, 3, 6)
-	fetcher.On("FetchLocationsByIP", ctx, ip).Return(locations[2:], nil).Once()
+	fetcher := geolocationtest.NewMemoryStorageWith(locations)
+	ip := netip.AddrFrom4([4]byte{1, 2, 3, 6})
 
 	loc, err := geolocation.PredictIPLocation(ctx, ip, fetcher)
 	require.NoError(t, err)
-	require.Equal(t, locations[2], loc)
-
-	fetcher.AssertExpectations(t)
+	require.Equal(t, geolocationtest.WithVersion(locations[2:], 1)[0], loc)
 }
 
 func TestPredictIPLocation_Multiple(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
-	fetcher := new(fetcherMock)
-	ip := net.IPv4(1, 2, 3, 6)
-	fetcher.On("FetchLocationsByIP", ctx, ip).Return(locations, nil).Once()
+	ip := netip.AddrFrom4([4]byte{1, 2, 3, 4})
+	auckland := locations[2]
+	auckland.IP = ip
+	fetcher := geolocationtest.NewMemoryStorageWith([]geolocation.IPLocation{locations[0], auckland})
 
 	_, err := geolocation.PredictIPLocation(ctx, ip, fetcher)
 	require.EqualError(t, geolocation.ErrIPLocationAmbiguous, err.Error())
-
-	fetcher.AssertExpectations(t)
 }
 
 func TestPredictIPLocation_NotFound(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
-	fetcher := new(fetcherMock)
-	ip := net.IPv4(1, 2, 3, 6)
-	fetcher.On("FetchLocationsByIP", ctx, ip).Return([]geolocation.IPLocation{}, nil).Once()
+	fetcher := geolocationtest.NewMemoryStorageWith(locations)
+	ip := netip.AddrFrom4([4]byte{1, 2, 3, 5})
 
 	_, err := geolocation.PredictIPLocation(ctx, ip, fetcher)
 	require.EqualError(t, geolocation.ErrIPLocationNotFound, err.Error())
-
-	fetcher.AssertExpectations(t)
-}
-
-type fetcherMock struct {
-	mock.Mock
-}
-
-func (f *fetcherMock) FetchLocationsByIP(ctx context.Context, ip net.IP) ([]geolocation.IPLocation, error) {
-	args := f.Called(ctx, ip)
-	return args.Get(0).([]geolocation.IPLocation), args.Error(1) //nolint:wrapcheck
 }
@@ -0,0 +1,208 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrQualityGateFailed - the imported dataset failed quality gates, so it is not activated.
+var ErrQualityGateFailed = errors.New("quality gate failed")
+
+// Names of quality gates, see QualityGates.
+const (
+	GateMinRecords        = "min_records"
+	GateMaxNonValidRatio  = "max_non_valid_ratio"
+	GateMaxAmbiguousRatio = "max_ambiguous_ratio"
+	GateMaxCountryDrift   = "max_country_drift"
+	GateMaxMisplacedRatio = "max_misplaced_ratio"
+)
+
+// QualityGates - checks of an imported dataset run before its activation, zero thresholds disable the checks.
+type QualityGates struct {
+	MinRecords        int     // Min number of imported records.
+	MaxNonValidRatio  float64 // Max share of non-valid records among all the source records.
+	MaxAmbiguousRatio float64 // Max share of IPs with several locations among the imported IPs.
+	// MaxCountryDrift - max total variation distance (from 0 to 1) between shares of records by country
+	// of the imported and the active datasets. Passed if there is no active dataset.
+	MaxCountryDrift float64
+	// MaxMisplacedRatio - max share of locations farther than MaxCentroidDistanceKm from the centroid of their
+	// country among the checked ones. Locations of countries missing in CountryCentroids are not checked.
+	MaxMisplacedRatio     float64
+	MaxCentroidDistanceKm float64
+	CountryCentroids      map[string]Coordinate // By country code.
+}
+
+// needProfile - the gates use DatasetProfiler.
+func (g QualityGates) needProfile() bool {
+	return g.MaxAmbiguousRatio > 0 || g.MaxCountryDrift > 0
+}
+
+// QualityGateResult - outcome of a quality gate.
+type QualityGateResult struct {
+	Gate      string
+	Value     float64
+	Threshold float64
+	Passed    bool
+}
+
+func (r QualityGateResult) String() string {
+	op := "<="
+	if r.Gate == GateMinRecords {
+		op = ">="
+	}
+	if !r.Passed {
+		op = "not " + op
+	}
+	return fmt.Sprintf("%s: %g %s %g", r.Gate, r.Value, op, r.Threshold)
+}
+
+// DatasetProfiler - interface for profiling stored datasets for quality gates.
+type DatasetProfiler interface {
+	// ProfileDataset returns the profile of the dataset, the active one if the version is zero.
+	// Returns ErrDatasetNotFound if there is no such dataset.
+	ProfileDataset(ctx context.Context, version int) (DatasetProfile, error)
+}
+
+// DatasetProfile - distribution of records of a dataset.
+type DatasetProfile struct {
+	IPs          int            // Distinct IP addresses.
+	AmbiguousIPs int            // IP addresses with several locations.
+	Countries    map[string]int // Number of records by country code.
+}
+
+// misplacedCounter counts locations far from centroids of their countries while they are imported.
+type misplacedCounter struct {
+	gates     QualityGates
+	checked   int
+	misplaced int
+}
+
+func (c *misplacedCounter) count(locations []IPLocation) {
+	if c.gates.MaxMisplacedRatio <= 0 {
+		return
+	}
+	for i := range locations {
+		centroid, ok := c.gates.CountryCentroids[locations[i].CountryCode]
+		if !ok {
+			continue
+		}
+		c.checked++
+		if centroid.DistanceKm(locations[i].Coordinate) > c.gates.MaxCentroidDistanceKm {
+			c.misplaced++
+		}
+	}
+}
+
+// checkQualityGates checks the imported dataset, returns ErrQualityGateFailed listing the failed gates.
+func checkQualityGates(
+	ctx context.Context,
+	gates QualityGates,
+	profiler DatasetProfiler,
+	stats ImportStatistics,
+	misplaced *misplacedCounter,
+) ([]QualityGateResult, error) {
+	var results []QualityGateResult
+	if gates.MinRecords > 0 {
+		results = append(results, QualityGateResult{Gate: GateMinRecords, Value: float64(stats.Imported),
+			Threshold: float64(gates.MinRecords), Passed: stats.Imported >= gates.MinRecords})
+	}
+	if gates.MaxNonValidRatio > 0 {
+		results = append(results, maxGate(GateMaxNonValidRatio, stats.NonValidRatio(), gates.MaxNonValidRatio))
+	}
+	if gates.MaxMisplacedRatio > 0 {
+		results = append(results,
+			maxGate(GateMaxMisplacedRatio, ratio(misplaced.misplaced, misplaced.checked), gates.MaxMisplacedRatio))
+	}
+	if gates.needProfile() {
+		profiled, err := checkProfileGates(ctx, gates, profiler, stats.DatasetVersion)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, profiled...)
+	}
+
+	var failed []string
+	for _, r := range results {
+		if !r.Passed {
+			failed = append(failed, r.String())
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%w: %s", ErrQualityGateFailed, strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+func checkProfileGates(
+	ctx context.Context,
+	gates QualityGates,
+	profiler DatasetProfiler,
+	version int,
+) ([]QualityGateResult, error) {
+	if profiler == nil {
+		return nil, errors.New("dataset profiler is required by quality gates")
+	}
+	imported, err := profiler.ProfileDataset(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to profile dataset %d: %w", version, err)
+	}
+	var results []QualityGateResult
+	if gates.MaxAmbiguousRatio > 0 {
+		results = append(results,
+			maxGate(GateMaxAmbiguousRatio, ratio(imported.AmbiguousIPs, imported.IPs), gates.MaxAmbiguousRatio))
+	}
+	if gates.MaxCountryDrift > 0 {
+		active, err := profiler.ProfileDataset(ctx, 0)
+		switch {
+		case errors.Is(err, ErrDatasetNotFound): // The first import, nothing to compare with.
+			results = append(results, maxGate(GateMaxCountryDrift, 0, gates.MaxCountryDrift))
+		case err != nil:
+			return nil, fmt.Errorf("failed to profile active dataset: %w", err)
+		default:
+			drift := countryDrift(imported.Countries, active.Countries)
+			results = append(results, maxGate(GateMaxCountryDrift, drift, gates.MaxCountryDrift))
+		}
+	}
+	return results, nil
+}
+
+func maxGate(gate string, value, threshold float64) QualityGateResult {
+	return QualityGateResult{Gate: gate, Value: value, Threshold: threshold, Passed: value <= threshold}
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+// countryDrift - total variation distance between shares of records by country, from 0 (same) to 1 (disjoint).
+func countryDrift(a, b map[string]int) float64 {
+	var totalA, totalB int
+	for _, n := range a {
+		totalA += n
+	}
+	for _, n := range b {
+		totalB += n
+	}
+	if totalA == 0 && totalB == 0 {
+		return 0
+	}
+	if totalA == 0 || totalB == 0 {
+		return 1
+	}
+	var distance float64
+	for country, n := range a {
+		distance += math.Abs(ratio(n, totalA) - ratio(b[country], totalB))
+	}
+	for country, n := range b {
+		if _, ok := a[country]; !ok {
+			distance += ratio(n, totalB)
+		}
+	}
+	return distance / 2
+}
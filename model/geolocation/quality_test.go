@@ -0,0 +1,120 @@
+package geolocation_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+// importTwoLocations sets up an import of London and Auckland with one non-valid record to dataset 1.
+func importTwoLocations() (*geolocationtest.ImporterFake, *geolocationtest.MemoryStorage) {
+	importer := &geolocationtest.ImporterFake{
+		Batches:    [][]geolocation.IPLocation{{locations[0], locations[2]}},
+		Statistics: []geolocation.ImportStatistics{{Imported: 2, NonValid: 1}},
+	}
+	return importer, geolocationtest.NewMemoryStorage()
+}
+
+// assertActive checks whether the dataset of the storage is activated.
+func assertActive(t *testing.T, storage *geolocationtest.MemoryStorage, version int, active bool) {
+	t.Helper()
+	dataset, err := storage.FetchDataset(context.Background(), version)
+	require.NoError(t, err)
+	assert.Equal(t, active, dataset.Active)
+}
+
+var centroids = map[string]geolocation.Coordinate{
+	"UK": {Lat: 54, Lon: -2.5},     // About 300 km from London.
+	"NZ": {Lat: -41.3, Lon: 174.8}, // About 500 km from Auckland.
+	"US": {Lat: 39.8, Lon: -98.6},  // Not imported.
+}
+
+func TestImportIPLocations_QualityGatesFailed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	leeds, wellington := locations[0], locations[2] // Share IPs with London and Auckland.
+	leeds.City, leeds.Coordinate = "Leeds", geolocation.Coordinate{Lat: 53.8, Lon: -1.55}
+	wellington.City, wellington.Coordinate = "Wellington", centroids["NZ"]
+	importer := &geolocationtest.ImporterFake{
+		Batches:    [][]geolocation.IPLocation{{locations[0], leeds, locations[2], wellington}},
+		Statistics: []geolocation.ImportStatistics{{Imported: 4, NonValid: 1}},
+	}
+	storage := geolocationtest.NewMemoryStorage()
+	active := []geolocation.IPLocation{locations[0], locations[0], locations[0], locations[0]}
+	for i := range active {
+		active[i].IP = netip.AddrFrom4([4]byte{10, 0, 0, byte(i)})
+	}
+	active[3].CountryCode = "US"
+	geolocationtest.StoreDataset(ctx, t, storage, active, true)
+
+	stats, err := geolocation.ImportIPLocations(ctx, importer, storage, geolocation.ImportOptions{
+		Gates: geolocation.QualityGates{
+			MinRecords:            5,
+			MaxNonValidRatio:      0.5,
+			MaxAmbiguousRatio:     0.1,
+			MaxCountryDrift:       0.4,
+			MaxMisplacedRatio:     0.2,
+			MaxCentroidDistanceKm: 400,
+			CountryCentroids:      centroids,
+		},
+		Profiler: storage,
+	})
+	require.ErrorIs(t, err, geolocation.ErrQualityGateFailed)
+	assert.Equal(t, "dataset 2 is not activated: quality gate failed: min_records: 4 not >= 5, "+
+		"max_misplaced_ratio: 0.25 not <= 0.2, max_ambiguous_ratio: 1 not <= 0.1, max_country_drift: 0.5 not <= 0.4",
+		err.Error())
+	assert.Equal(t, 4, stats.Imported)
+	assert.Equal(t, []geolocation.QualityGateResult{
+		{Gate: geolocation.GateMinRecords, Value: 4, Threshold: 5},
+		{Gate: geolocation.GateMaxNonValidRatio, Value: 0.2, Threshold: 0.5, Passed: true},
+		{Gate: geolocation.GateMaxMisplacedRatio, Value: 0.25, Threshold: 0.2},
+		{Gate: geolocation.GateMaxAmbiguousRatio, Value: 1, Threshold: 0.1},
+		{Gate: geolocation.GateMaxCountryDrift, Value: 0.5, Threshold: 0.4},
+	}, stats.QualityGates)
+
+	assertActive(t, storage, 2, false)
+	assertActive(t, storage, 1, true)
+}
+
+func TestImportIPLocations_QualityGatesPassed(t *testing.T) {
+	t.Parallel()
+	importer, storer := importTwoLocations()
+
+	stats, err := geolocation.ImportIPLocations(context.Background(), importer, storer, geolocation.ImportOptions{
+		Gates: geolocation.QualityGates{
+			MaxCountryDrift:       0.1,
+			MaxMisplacedRatio:     0.1,
+			MaxCentroidDistanceKm: 600,
+			CountryCentroids:      centroids,
+		},
+		Profiler: storer,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []geolocation.QualityGateResult{
+		{Gate: geolocation.GateMaxMisplacedRatio, Value: 0, Threshold: 0.1, Passed: true},
+		{Gate: geolocation.GateMaxCountryDrift, Value: 0, Threshold: 0.1, Passed: true}, // The first dataset.
+	}, stats.QualityGates)
+
+	assertActive(t, storer, 1, true)
+}
+
+func TestImportIPLocations_QualityGatesProfileError(t *testing.T) {
+	t.Parallel()
+	importer, storer := importTwoLocations()
+	storer.SetFaults(geolocationtest.Faults{Profile: errors.New("no db")})
+
+	_, err := geolocation.ImportIPLocations(context.Background(), importer, storer, geolocation.ImportOptions{
+		Gates:    geolocation.QualityGates{MaxAmbiguousRatio: 0.1},
+		Profiler: storer,
+	})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, geolocation.ErrQualityGateFailed)
+	assertActive(t, storer, 1, false)
+}
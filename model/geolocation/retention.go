@@ -0,0 +1,112 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrDatasetActive - the active dataset can't be removed.
+var ErrDatasetActive = errors.New("dataset is active")
+
+// DatasetRemover - interface for removing datasets with their IP locations.
+type DatasetRemover interface {
+	DatasetManager
+	// RemoveDataset removes the inactive dataset with its IP locations, and returns the number of bytes reclaimed.
+	// Returns ErrDatasetNotFound if there is no such dataset, ErrDatasetActive if it is active.
+	RemoveDataset(ctx context.Context, version int) (int64, error)
+}
+
+// RetentionPolicy - which datasets are kept, a dataset is kept if any of the rules keeps it. The active dataset
+// is always kept, and so are newer ones (being imported or rolled back from) unless they have never been activated
+// for FailedImportGrace or KeepNewerThan, whichever is longer: those are left behind by failed imports.
+// The policy is disabled if it has no rules.
+type RetentionPolicy struct {
+	KeepLast      int           // Number of the last activated versions kept, no rule if 0.
+	KeepNewerThan time.Duration // Datasets created within the duration are kept, no rule if 0.
+}
+
+// FailedImportGrace - a dataset newer than the active one, which has never been activated, is considered to be
+// left behind by a failed import after the grace period. Imports must not take longer.
+const FailedImportGrace = 24 * time.Hour
+
+// Enabled - the policy has rules.
+func (p RetentionPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepNewerThan > 0
+}
+
+func (p RetentionPolicy) String() string {
+	return fmt.Sprintf("keep last %d, keep newer than %s", p.KeepLast, p.KeepNewerThan)
+}
+
+// Expired - datasets not kept by the policy at the moment, ordered by version.
+// Nothing is expired if the policy is disabled or there is no active dataset.
+func (p RetentionPolicy) Expired(datasets []Dataset, now time.Time) []Dataset {
+	if !p.Enabled() {
+		return nil
+	}
+	datasets = slices.Clone(datasets)
+	slices.SortFunc(datasets, func(a, b Dataset) int { return a.Version - b.Version })
+	active := slices.IndexFunc(datasets, func(d Dataset) bool { return d.Active })
+	if active < 0 {
+		return nil
+	}
+	lastActivated := make(map[int]bool, p.KeepLast) // Versions kept by KeepLast, failed imports are not counted.
+	for i := len(datasets) - 1; i >= 0 && len(lastActivated) < p.KeepLast; i-- {
+		if datasets[i].Active || !datasets[i].ActivatedAt.IsZero() {
+			lastActivated[datasets[i].Version] = true
+		}
+	}
+	var expired []Dataset
+	for _, d := range datasets[:active] {
+		if lastActivated[d.Version] {
+			continue
+		}
+		if p.KeepNewerThan > 0 && now.Sub(d.CreatedAt) < p.KeepNewerThan {
+			continue
+		}
+		expired = append(expired, d)
+	}
+	grace := max(p.KeepNewerThan, FailedImportGrace)
+	for _, d := range datasets[active+1:] {
+		if d.ActivatedAt.IsZero() && now.Sub(d.CreatedAt) >= grace {
+			expired = append(expired, d)
+		}
+	}
+	return expired
+}
+
+// GCReport - outcome of a garbage collection.
+type GCReport struct {
+	Removed        []Dataset
+	ReclaimedBytes int64
+}
+
+// CollectGarbage - removes datasets expired by the policy. Datasets activated or removed meanwhile are skipped.
+// Datasets removed before an error are reported along with it.
+func CollectGarbage(
+	ctx context.Context,
+	policy RetentionPolicy,
+	remover DatasetRemover,
+	now time.Time,
+) (GCReport, error) {
+	report := GCReport{}
+	datasets, err := remover.ListDatasets(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to list datasets: %w", err)
+	}
+	for _, d := range policy.Expired(datasets, now) {
+		reclaimed, err := remover.RemoveDataset(ctx, d.Version)
+		if errors.Is(err, ErrDatasetActive) || errors.Is(err, ErrDatasetNotFound) {
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to remove dataset %d: %w", d.Version, err)
+		}
+		report.Removed = append(report.Removed, d)
+		report.ReclaimedBytes += reclaimed
+	}
+	return report, nil
+}
@@ -0,0 +1,113 @@
+package geolocation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestRetentionPolicy_Expired(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 7, 17, 8, 27, 44, 0, time.UTC)
+	datasets := []geolocation.Dataset{
+		{Version: 5, CreatedAt: now.Add(-time.Hour)}, // Newer than the active one.
+		{Version: 1, CreatedAt: now.Add(-72 * time.Hour), ActivatedAt: now.Add(-72 * time.Hour)},
+		{Version: 2, CreatedAt: now.Add(-48 * time.Hour), ActivatedAt: now.Add(-48 * time.Hour)},
+		{Version: 3, CreatedAt: now.Add(-24 * time.Hour), ActivatedAt: now.Add(-24 * time.Hour)},
+		{Version: 4, CreatedAt: now.Add(-2 * time.Hour), ActivatedAt: now.Add(-2 * time.Hour), Active: true},
+		{Version: 6, CreatedAt: now.Add(-30 * time.Hour)},                                       // A failed import.
+		{Version: 7, CreatedAt: now.Add(-72 * time.Hour), ActivatedAt: now.Add(-3 * time.Hour)}, // Rolled back from.
+	}
+	versions := func(datasets []geolocation.Dataset) []int {
+		var res []int
+		for _, d := range datasets {
+			res = append(res, d.Version)
+		}
+		return res
+	}
+	tests := []struct {
+		name     string
+		policy   geolocation.RetentionPolicy
+		expected []int
+	}{
+		{"Disabled", geolocation.RetentionPolicy{}, nil},
+		{"KeepLast", geolocation.RetentionPolicy{KeepLast: 3}, []int{1, 2, 6}},
+		{"KeepLastOne", geolocation.RetentionPolicy{KeepLast: 1}, []int{1, 2, 3, 6}},
+		{"KeepNewerThan", geolocation.RetentionPolicy{KeepNewerThan: 36 * time.Hour}, []int{1, 2}},
+		{"AnyRuleKeeps", geolocation.RetentionPolicy{KeepLast: 4, KeepNewerThan: 60 * time.Hour}, []int{1}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, versions(tt.policy.Expired(datasets, now)), tt.name)
+	}
+
+	datasets[4].Active = false
+	assert.Empty(t, geolocation.RetentionPolicy{KeepLast: 1}.Expired(datasets, now), "NoActive")
+}
+
+func TestCollectGarbage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := geolocationtest.NewMemoryStorage()
+	for i := 0; i < 3; i++ {
+		geolocationtest.StoreDataset(ctx, t, s, geolocationtest.Locations, true)
+	}
+	failed := geolocationtest.StoreDataset(ctx, t, s, geolocationtest.Locations[:1], false)
+
+	// The failed import is not one of the last 2 versions.
+	report, err := geolocation.CollectGarbage(ctx, geolocation.RetentionPolicy{KeepLast: 2}, s, time.Now())
+	require.NoError(t, err)
+	require.Len(t, report.Removed, 1)
+	assert.Equal(t, 1, report.Removed[0].Version)
+	assert.Positive(t, report.ReclaimedBytes)
+
+	datasets, err := s.ListDatasets(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 3)
+	assert.Equal(t, 2, datasets[0].Version)
+	assert.True(t, datasets[1].Active)
+	assert.Equal(t, failed, datasets[2].Version)
+}
+
+func TestCollectGarbage_RemovedMeanwhile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := geolocationtest.NewMemoryStorage()
+	for i := 0; i < 3; i++ {
+		geolocationtest.StoreDataset(ctx, t, s, geolocationtest.Locations, true)
+	}
+	remover := &removerStub{MemoryStorage: s, errs: map[int]error{1: geolocation.ErrDatasetNotFound}}
+
+	report, err := geolocation.CollectGarbage(ctx, geolocation.RetentionPolicy{KeepLast: 1}, remover, time.Now())
+	require.NoError(t, err)
+	require.Len(t, report.Removed, 1)
+	assert.Equal(t, 2, report.Removed[0].Version)
+
+	remover.errs[3] = errors.New("connection lost")
+	require.NoError(t, s.ActivateDataset(ctx, 1))
+	geolocationtest.StoreDataset(ctx, t, s, geolocationtest.Locations, true)
+	delete(remover.errs, 1)
+	report, err = geolocation.CollectGarbage(ctx, geolocation.RetentionPolicy{KeepLast: 1}, remover, time.Now())
+	require.ErrorContains(t, err, "connection lost")
+	require.Len(t, report.Removed, 1)
+	assert.Equal(t, 1, report.Removed[0].Version)
+}
+
+// removerStub fails removal of datasets with errs, others are removed from the storage.
+type removerStub struct {
+	*geolocationtest.MemoryStorage
+	errs map[int]error
+}
+
+func (r *removerStub) RemoveDataset(ctx context.Context, version int) (int64, error) {
+	if err := r.errs[version]; err != nil {
+		return 0, err
+	}
+	return r.MemoryStorage.RemoveDataset(ctx, version) //nolint:wrapcheck
+}
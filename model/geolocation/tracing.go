@@ -0,0 +1,34 @@
+package geolocation
+
+import (
+	"net/netip"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of OpenTelemetry tracer used by the model and its implementations.
+const TracerName = "github.com/dronnix/search-accomodation/model/geolocation"
+
+// Span attributes, shared by the model and its implementations.
+const (
+	ResultCountKey    = attribute.Key("geolocation.result_count")
+	BatchSizeKey      = attribute.Key("geolocation.batch_size")
+	DatasetVersionKey = attribute.Key("geolocation.dataset_version")
+)
+
+// IPFamilyAttribute returns network.type span attribute (ipv4 or ipv6) of the IP address.
+func IPFamilyAttribute(ip netip.Addr) attribute.KeyValue {
+	if ip.Is4() {
+		return semconv.NetworkTypeIpv4
+	}
+	return semconv.NetworkTypeIpv6
+}
+
+// RecordSpanError marks the span as failed with the error.
+func RecordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,79 @@
+package geolocation_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dronnix/search-accomodation/internal/tracing/tracingtest"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestIPFamilyAttribute(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, semconv.NetworkTypeIpv4, geolocation.IPFamilyAttribute(netip.MustParseAddr("1.2.3.4")))
+	assert.Equal(t, semconv.NetworkTypeIpv6, geolocation.IPFamilyAttribute(netip.MustParseAddr("2001:db8::1")))
+}
+
+func TestPredictIPLocation_Span(t *testing.T) { // Not parallel - uses global tracer provider.
+	exporter := tracingtest.Setup(t)
+	ip := netip.MustParseAddr("2001:db8::1")
+	var fetchCtx context.Context
+	fetcher := geolocationtest.FetcherFunc(func(ctx context.Context, _ netip.Addr) ([]geolocation.IPLocation, error) {
+		fetchCtx = ctx
+		return locations, nil
+	})
+
+	_, err := geolocation.PredictIPLocation(context.Background(), ip, fetcher)
+	require.ErrorIs(t, err, geolocation.ErrIPLocationAmbiguous)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "geolocation.PredictIPLocation", spans[0].Name)
+	assert.Contains(t, spans[0].Attributes, semconv.NetworkTypeIpv6)
+	assert.Contains(t, spans[0].Attributes, geolocation.ResultCountKey.Int(len(locations)))
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, spans[0].SpanContext.SpanID(), trace.SpanContextFromContext(fetchCtx).SpanID())
+}
+
+func TestImportIPLocations_Spans(t *testing.T) { // Not parallel - uses global tracer provider.
+	exporter := tracingtest.Setup(t)
+	importer := &geolocationtest.ImporterFake{
+		Batches:    [][]geolocation.IPLocation{locations},
+		Statistics: []geolocation.ImportStatistics{{Imported: 3}},
+	}
+	storer := geolocationtest.NewMemoryStorage()
+	storer.SetFaults(geolocationtest.Faults{Store: errors.New("disk is full")})
+
+	_, err := geolocation.ImportIPLocations(context.Background(), importer, storer, geolocation.ImportOptions{})
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	batch, root := spans[0], spans[1]
+	assert.Equal(t, "geolocation.ImportBatch", batch.Name)
+	assert.Equal(t, root.SpanContext.SpanID(), batch.Parent.SpanID())
+	assert.Contains(t, batch.Attributes, geolocation.ResultCountKey.Int(2))
+	assert.Contains(t, batch.Attributes, geolocation.DatasetVersionKey.Int(1))
+	assert.Contains(t, attributeKeys(batch.Attributes), geolocation.BatchSizeKey)
+	assert.Equal(t, codes.Error, batch.Status.Code)
+	assert.Equal(t, "geolocation.ImportIPLocations", root.Name)
+	assert.Equal(t, codes.Error, root.Status.Code)
+}
+
+func attributeKeys(attrs []attribute.KeyValue) []attribute.Key {
+	keys := make([]attribute.Key, 0, len(attrs))
+	for _, a := range attrs {
+		keys = append(keys, a.Key)
+	}
+	return keys
+}
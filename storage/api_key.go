@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+// APIKeyStorage is implementation of apikey.Fetcher/apikey.Manager on top of PostgreSQL.
+// The schema is migrated together with IPLocationStorage.
+type APIKeyStorage struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+}
+
+var _ apikey.Fetcher = (*APIKeyStorage)(nil)
+var _ apikey.Manager = (*APIKeyStorage)(nil)
+
+func NewAPIKeyStorage(pool *pgxpool.Pool, logger *slog.Logger) *APIKeyStorage {
+	return &APIKeyStorage{pool: pool, logger: logger}
+}
+
+const apiKeyColumns = "id, name, hash, admin, rate_per_second, burst, daily_quota, created_at, revoked_at"
+
+// FetchAPIKey - see apikey.Fetcher interface specification.
+func (s *APIKeyStorage) FetchAPIKey(ctx context.Context, hash string) (apikey.Key, error) {
+	row := s.pool.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM geolocation.api_key WHERE hash = $1;", hash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apikey.Key{}, apikey.ErrKeyNotFound
+	}
+	if err != nil {
+		return apikey.Key{}, fmt.Errorf("unable to fetch api key: %w", err)
+	}
+	return key, nil
+}
+
+// CreateAPIKey - see apikey.Manager interface specification.
+func (s *APIKeyStorage) CreateAPIKey(ctx context.Context, key apikey.Key) (apikey.Key, error) {
+	err := s.pool.QueryRow(ctx, "INSERT INTO geolocation.api_key "+
+		"(name, hash, admin, rate_per_second, burst, daily_quota) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at;",
+		key.Name, key.Hash, key.Admin, key.RatePerSecond, key.Burst, key.DailyQuota).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return apikey.Key{}, fmt.Errorf("unable to create api key: %w", err)
+	}
+	s.logger.InfoContext(ctx, "api key created", "api_key_id", key.ID, "name", key.Name, "admin", key.Admin)
+	return key, nil
+}
+
+// RevokeAPIKey - see apikey.Manager interface specification. Revoking a revoked key keeps the original time.
+func (s *APIKeyStorage) RevokeAPIKey(ctx context.Context, id int) error {
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE geolocation.api_key SET revoked_at = coalesce(revoked_at, now()) WHERE id = $1;", id)
+	if err != nil {
+		return fmt.Errorf("unable to revoke api key %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("unable to revoke api key %d: %w", id, apikey.ErrKeyNotFound)
+	}
+	s.logger.InfoContext(ctx, "api key revoked", "api_key_id", id)
+	return nil
+}
+
+// ListAPIKeys - see apikey.Manager interface specification.
+func (s *APIKeyStorage) ListAPIKeys(ctx context.Context) ([]apikey.Key, error) {
+	rows, err := s.pool.Query(ctx, "SELECT "+apiKeyColumns+" FROM geolocation.api_key ORDER BY id;")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list api keys: %w", err)
+	}
+	defer rows.Close()
+	var keys []apikey.Key
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func scanAPIKey(row pgx.Row) (apikey.Key, error) {
+	key := apikey.Key{}
+	var revokedAt *time.Time
+	err := row.Scan(&key.ID, &key.Name, &key.Hash, &key.Admin, &key.RatePerSecond, &key.Burst, &key.DailyQuota,
+		&key.CreatedAt, &revokedAt)
+	if err != nil {
+		return apikey.Key{}, err //nolint:wrapcheck
+	}
+	if revokedAt != nil {
+		key.RevokedAt = *revokedAt
+	}
+	return key, nil
+}
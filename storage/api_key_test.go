@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/apikey"
+)
+
+func setUpAPIKeyDB(t *testing.T) (context.Context, *APIKeyStorage, func()) {
+	ctx, ipLocStorage, teardown := setUpDB(t)
+	require.NoError(t, ipLocStorage.MigrateUp(ctx, migrations))
+	return ctx, NewAPIKeyStorage(ipLocStorage.pool, logging.Discard()), teardown
+}
+
+func TestAPIKeyStorage_CreateAPIKey_FetchAPIKey(t *testing.T) {
+	ctx, storage, teardown := setUpAPIKeyDB(t)
+	defer teardown()
+
+	_, err := storage.FetchAPIKey(ctx, apikey.Hash("unknown"))
+	require.ErrorIs(t, err, apikey.ErrKeyNotFound)
+
+	limits := apikey.Limits{RatePerSecond: 2.5, Burst: 5, DailyQuota: 1000}
+	created, err := storage.CreateAPIKey(ctx, apikey.Key{
+		Name: "partner", Hash: apikey.Hash("secret"), Admin: true, Limits: limits,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	key, err := storage.FetchAPIKey(ctx, apikey.Hash("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+	assert.Equal(t, "partner", key.Name)
+	assert.Equal(t, limits, key.Limits)
+	assert.True(t, key.Admin)
+	assert.False(t, key.Revoked())
+}
+
+func TestAPIKeyStorage_RevokeAPIKey(t *testing.T) {
+	ctx, storage, teardown := setUpAPIKeyDB(t)
+	defer teardown()
+
+	created, err := storage.CreateAPIKey(ctx, apikey.Key{Name: "partner", Hash: apikey.Hash("secret")})
+	require.NoError(t, err)
+	require.NoError(t, storage.RevokeAPIKey(ctx, created.ID))
+	require.ErrorIs(t, storage.RevokeAPIKey(ctx, created.ID+1), apikey.ErrKeyNotFound)
+
+	key, err := storage.FetchAPIKey(ctx, apikey.Hash("secret"))
+	require.NoError(t, err)
+	assert.True(t, key.Revoked())
+
+	require.NoError(t, storage.RevokeAPIKey(ctx, created.ID))
+	again, err := storage.FetchAPIKey(ctx, apikey.Hash("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, key.RevokedAt, again.RevokedAt)
+}
+
+func TestAPIKeyStorage_ListAPIKeys(t *testing.T) {
+	ctx, storage, teardown := setUpAPIKeyDB(t)
+	defer teardown()
+
+	keys, err := storage.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	first, err := storage.CreateAPIKey(ctx, apikey.Key{Name: "first", Hash: apikey.Hash("1")})
+	require.NoError(t, err)
+	second, err := storage.CreateAPIKey(ctx, apikey.Key{Name: "second", Hash: apikey.Hash("2")})
+	require.NoError(t, err)
+	require.NoError(t, storage.RevokeAPIKey(ctx, first.ID))
+
+	keys, err = storage.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, first.ID, keys[0].ID)
+	assert.True(t, keys[0].Revoked())
+	assert.Equal(t, second.ID, keys[1].ID)
+	assert.False(t, keys[1].Revoked())
+}
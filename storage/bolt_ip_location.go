@@ -0,0 +1,475 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// BoltIPLocationStorage - IP location storage embedded into a single bbolt file, for small deployments and tests.
+// The file is locked by the process that opened it, so the importer and the server can't use it at the same time.
+type BoltIPLocationStorage struct {
+	db     *bolt.DB
+	logger *slog.Logger
+}
+
+var _ geolocation.DatasetRemover = (*BoltIPLocationStorage)(nil)
+
+var (
+	boltDatasetsBucket = []byte("datasets") // Version -> boltDataset.
+	boltMetaBucket     = []byte("meta")
+	boltActiveKey      = []byte("active") // Version of the active dataset, absent if there is none.
+	// Locations of each dataset are stored in a nested bucket keyed by the version, with nested buckets of records
+	// keyed by sequence number in the order they are stored, and of the index of records by IP address.
+	boltLocationsBucket = []byte("locations")
+	boltRecordsBucket   = []byte("records")
+	boltIPsBucket       = []byte("ips") // IP length, IP, sequence number -> nothing.
+)
+
+// NewBoltIPLocationStorage opens or creates the bbolt file.
+func NewBoltIPLocationStorage(path string, logger *slog.Logger) (*BoltIPLocationStorage, error) {
+	const openTimeout = time.Second // Fail instead of waiting for another process to close the file.
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open bolt file %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltDatasetsBucket, boltMetaBucket, boltLocationsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err //nolint:wrapcheck
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("unable to create buckets: %w", err)
+	}
+	return &BoltIPLocationStorage{db: db, logger: logger}, nil
+}
+
+// Close closes the file.
+func (s *BoltIPLocationStorage) Close() error {
+	return s.db.Close() //nolint:wrapcheck
+}
+
+// Ping checks the file is open.
+func (s *BoltIPLocationStorage) Ping(context.Context) error {
+	return s.db.View(func(*bolt.Tx) error { return nil }) //nolint:wrapcheck
+}
+
+type boltDataset struct {
+	CreatedAt   time.Time `json:"created_at"`
+	ActivatedAt time.Time `json:"activated_at"`
+	Records     int       `json:"records"`
+}
+
+type boltIPLocation struct {
+	IP           netip.Addr `json:"ip"`
+	CountryCode  string     `json:"country_code"`
+	CountryName  string     `json:"country_name"`
+	City         string     `json:"city"`
+	Lat          float64    `json:"lat"`
+	Lon          float64    `json:"lon"`
+	MysteryValue uint64     `json:"mystery_value"`
+
+	SourceID    string     `json:"source_id,omitempty"`
+	SourceName  string     `json:"source_name,omitempty"`
+	ImportRunID string     `json:"import_run_id,omitempty"`
+	SourceLine  int        `json:"source_line,omitempty"`
+	ObservedAt  *time.Time `json:"observed_at,omitempty"`
+}
+
+// CreateDataset - see geolocation.IPLocationStorer interface specification.
+func (s *BoltIPLocationStorage) CreateDataset(ctx context.Context) (geolocation.Dataset, error) {
+	dataset := geolocation.Dataset{CreatedAt: time.Now().UTC()}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		datasets := tx.Bucket(boltDatasetsBucket)
+		seq, err := datasets.NextSequence()
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		dataset.Version = int(seq)
+		key := boltKey(seq)
+		if err = putJSON(datasets, key, boltDataset{CreatedAt: dataset.CreatedAt}); err != nil {
+			return err
+		}
+		locations, err := tx.Bucket(boltLocationsBucket).CreateBucket(key)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		if _, err = locations.CreateBucket(boltRecordsBucket); err != nil {
+			return err //nolint:wrapcheck
+		}
+		_, err = locations.CreateBucket(boltIPsBucket)
+		return err //nolint:wrapcheck
+	})
+	if err != nil {
+		return geolocation.Dataset{}, fmt.Errorf("unable to create dataset: %w", err)
+	}
+	s.logger.InfoContext(ctx, "dataset created", "dataset_version", dataset.Version)
+	return dataset, nil
+}
+
+// StoreIPLocations - see geolocation.IPLocationStorer interface specification. The batch is stored atomically.
+func (s *BoltIPLocationStorage) StoreIPLocations(
+	ctx context.Context,
+	datasetVersion int,
+	locations []geolocation.IPLocation,
+) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		dataset := tx.Bucket(boltLocationsBucket).Bucket(boltKey(uint64(datasetVersion)))
+		if dataset == nil {
+			return geolocation.ErrDatasetNotFound
+		}
+		records, ips := dataset.Bucket(boltRecordsBucket), dataset.Bucket(boltIPsBucket)
+		for i := range locations {
+			if err := ctx.Err(); err != nil {
+				return err //nolint:wrapcheck
+			}
+			seq, err := records.NextSequence()
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			loc := &locations[i]
+			key := boltKey(seq)
+			stored := boltIPLocation{
+				IP:           geolocation.NormalizeAddr(loc.IP),
+				CountryCode:  loc.CountryCode,
+				CountryName:  loc.CountryName,
+				City:         loc.City,
+				Lat:          loc.Lat,
+				Lon:          loc.Lon,
+				MysteryValue: loc.MysteryValue,
+				SourceID:     loc.Provenance.SourceID,
+				SourceName:   loc.Provenance.SourceName,
+				ImportRunID:  loc.Provenance.ImportRunID,
+				SourceLine:   loc.Provenance.Line,
+			}
+			if !loc.Provenance.ObservedAt.IsZero() {
+				stored.ObservedAt = &loc.Provenance.ObservedAt
+			}
+			if err = putJSON(records, key, stored); err != nil {
+				return err
+			}
+			if err = ips.Put(append(boltIPPrefix(loc.IP), key...), nil); err != nil {
+				return err //nolint:wrapcheck
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to store ip locations: %w", err)
+	}
+	return nil
+}
+
+// ActivateDataset - see geolocation.IPLocationStorer interface specification.
+func (s *BoltIPLocationStorage) ActivateDataset(ctx context.Context, version int) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		key := boltKey(uint64(version))
+		datasets := tx.Bucket(boltDatasetsBucket)
+		dataset, err := getBoltDataset(datasets, key)
+		if err != nil {
+			return err
+		}
+		dataset.ActivatedAt = time.Now().UTC()
+		dataset.Records = tx.Bucket(boltLocationsBucket).Bucket(key).Bucket(boltRecordsBucket).Stats().KeyN
+		if err = putJSON(datasets, key, dataset); err != nil {
+			return err
+		}
+		return tx.Bucket(boltMetaBucket).Put(boltActiveKey, key) //nolint:wrapcheck
+	})
+	if err != nil {
+		return fmt.Errorf("unable to activate dataset %d: %w", version, err)
+	}
+	s.logger.InfoContext(ctx, "dataset activated", "dataset_version", version)
+	return nil
+}
+
+// FetchDataset - see geolocation.DatasetFetcher interface specification.
+func (s *BoltIPLocationStorage) FetchDataset(_ context.Context, version int) (geolocation.Dataset, error) {
+	var dataset geolocation.Dataset
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key, err := resolveBoltDataset(tx, version)
+		if err != nil {
+			return err
+		}
+		dataset, err = readBoltDataset(tx, key)
+		return err
+	})
+	if errors.Is(err, geolocation.ErrDatasetNotFound) {
+		return geolocation.Dataset{}, geolocation.ErrDatasetNotFound
+	}
+	if err != nil {
+		return geolocation.Dataset{}, fmt.Errorf("unable to fetch dataset: %w", err)
+	}
+	return dataset, nil
+}
+
+// ListDatasets - see geolocation.DatasetManager interface specification.
+func (s *BoltIPLocationStorage) ListDatasets(context.Context) ([]geolocation.Dataset, error) {
+	var datasets []geolocation.Dataset
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltDatasetsBucket).ForEach(func(k, _ []byte) error {
+			dataset, err := readBoltDataset(tx, k)
+			datasets = append(datasets, dataset)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list datasets: %w", err)
+	}
+	return datasets, nil
+}
+
+// RemoveDataset - see geolocation.DatasetRemover interface specification. Reclaimed pages are reused by the
+// following imports, the file doesn't shrink.
+func (s *BoltIPLocationStorage) RemoveDataset(ctx context.Context, version int) (int64, error) {
+	var reclaimed int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		key := boltKey(uint64(version))
+		datasets := tx.Bucket(boltDatasetsBucket)
+		if datasets.Get(key) == nil {
+			return geolocation.ErrDatasetNotFound
+		}
+		if bytes.Equal(tx.Bucket(boltMetaBucket).Get(boltActiveKey), key) {
+			return geolocation.ErrDatasetActive
+		}
+		locations := tx.Bucket(boltLocationsBucket)
+		stats := locations.Bucket(key).Stats()
+		reclaimed = int64(stats.BranchAlloc + stats.LeafAlloc + stats.InlineBucketInuse)
+		if err := locations.DeleteBucket(key); err != nil {
+			return err //nolint:wrapcheck
+		}
+		return datasets.Delete(key) //nolint:wrapcheck
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to remove dataset %d: %w", version, err)
+	}
+	s.logger.InfoContext(ctx, "dataset removed", "dataset_version", version, "reclaimed_bytes", reclaimed)
+	return reclaimed, nil
+}
+
+// FetchLocationsByIP - see geolocation.IPLocationFetcher interface specification.
+func (s *BoltIPLocationStorage) FetchLocationsByIP(
+	ctx context.Context,
+	ip netip.Addr,
+) ([]geolocation.IPLocation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to fetch locations by ip: %w", err)
+	}
+	locations := []geolocation.IPLocation{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(boltMetaBucket).Get(boltActiveKey)
+		if key == nil {
+			return nil
+		}
+		dataset := tx.Bucket(boltLocationsBucket).Bucket(key)
+		records, version := dataset.Bucket(boltRecordsBucket), int(binary.BigEndian.Uint64(key))
+		prefix := boltIPPrefix(ip)
+		c := dataset.Bucket(boltIPsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err //nolint:wrapcheck
+			}
+			loc, err := readBoltIPLocation(records, k[len(prefix):], version)
+			if err != nil {
+				return err
+			}
+			locations = append(locations, loc)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch locations by ip: %w", err)
+	}
+	return locations, nil
+}
+
+// ListIPLocations - see geolocation.IPLocationLister interface specification.
+// Locations are listed in the order they are stored, within a single read transaction.
+func (s *BoltIPLocationStorage) ListIPLocations(
+	ctx context.Context,
+	filter geolocation.ExportFilter,
+	batchSize int,
+	fn func([]geolocation.IPLocation) error,
+) error {
+	return s.db.View(func(tx *bolt.Tx) error { //nolint:wrapcheck
+		key, err := resolveBoltDataset(tx, filter.DatasetVersion)
+		if err != nil {
+			return err
+		}
+		version := int(binary.BigEndian.Uint64(key))
+		batch := make([]geolocation.IPLocation, 0, batchSize)
+		err = tx.Bucket(boltLocationsBucket).Bucket(key).Bucket(boltRecordsBucket).ForEach(func(_, v []byte) error {
+			loc, err := decodeBoltIPLocation(v, version)
+			if err != nil {
+				return err
+			}
+			if filter.CountryCode != "" && !strings.EqualFold(loc.CountryCode, filter.CountryCode) {
+				return nil
+			}
+			if batch = append(batch, loc); len(batch) < batchSize {
+				return nil
+			}
+			if err = fn(batch); err != nil {
+				return err
+			}
+			batch = make([]geolocation.IPLocation, 0, batchSize)
+			return ctx.Err() //nolint:wrapcheck
+		})
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+		return fn(batch)
+	})
+}
+
+// ProfileDataset - see geolocation.DatasetProfiler interface specification.
+func (s *BoltIPLocationStorage) ProfileDataset(_ context.Context, version int) (geolocation.DatasetProfile, error) {
+	profile := geolocation.DatasetProfile{Countries: map[string]int{}}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key, err := resolveBoltDataset(tx, version)
+		if err != nil {
+			return err
+		}
+		dataset := tx.Bucket(boltLocationsBucket).Bucket(key)
+		err = dataset.Bucket(boltRecordsBucket).ForEach(func(_, v []byte) error {
+			loc, err := decodeBoltIPLocation(v, 0)
+			if err != nil {
+				return err
+			}
+			profile.Countries[loc.CountryCode]++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// The index is ordered by IP, so locations of an IP are adjacent.
+		var last []byte
+		locations := 0
+		return dataset.Bucket(boltIPsBucket).ForEach(func(k, _ []byte) error {
+			prefix := k[:len(k)-8]
+			if !bytes.Equal(prefix, last) {
+				profile.IPs++
+				last, locations = prefix, 0
+			}
+			if locations++; locations == 2 {
+				profile.AmbiguousIPs++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return geolocation.DatasetProfile{}, fmt.Errorf("unable to profile dataset: %w", err)
+	}
+	return profile, nil
+}
+
+// resolveBoltDataset returns the key of the dataset, zero version means the active dataset.
+func resolveBoltDataset(tx *bolt.Tx, version int) ([]byte, error) {
+	if version == 0 {
+		key := tx.Bucket(boltMetaBucket).Get(boltActiveKey)
+		if key == nil {
+			return nil, geolocation.ErrDatasetNotFound
+		}
+		return key, nil
+	}
+	key := boltKey(uint64(version))
+	if tx.Bucket(boltDatasetsBucket).Get(key) == nil {
+		return nil, geolocation.ErrDatasetNotFound
+	}
+	return key, nil
+}
+
+func readBoltDataset(tx *bolt.Tx, key []byte) (geolocation.Dataset, error) {
+	stored, err := getBoltDataset(tx.Bucket(boltDatasetsBucket), key)
+	if err != nil {
+		return geolocation.Dataset{}, err
+	}
+	active := tx.Bucket(boltMetaBucket).Get(boltActiveKey)
+	return geolocation.Dataset{
+		Version:     int(binary.BigEndian.Uint64(key)),
+		CreatedAt:   stored.CreatedAt,
+		ActivatedAt: stored.ActivatedAt,
+		Active:      bytes.Equal(active, key),
+		Records:     stored.Records,
+	}, nil
+}
+
+func readBoltIPLocation(records *bolt.Bucket, key []byte, version int) (geolocation.IPLocation, error) {
+	v := records.Get(key)
+	if v == nil {
+		return geolocation.IPLocation{}, fmt.Errorf("ip location %x is indexed, but not stored", key)
+	}
+	return decodeBoltIPLocation(v, version)
+}
+
+func decodeBoltIPLocation(v []byte, version int) (geolocation.IPLocation, error) {
+	var stored boltIPLocation
+	if err := json.Unmarshal(v, &stored); err != nil {
+		return geolocation.IPLocation{}, fmt.Errorf("unable to decode ip location: %w", err)
+	}
+	location := geolocation.IPLocation{
+		IP:             stored.IP,
+		CountryCode:    stored.CountryCode,
+		CountryName:    stored.CountryName,
+		City:           stored.City,
+		Coordinate:     geolocation.Coordinate{Lat: stored.Lat, Lon: stored.Lon},
+		MysteryValue:   stored.MysteryValue,
+		DatasetVersion: version,
+		Provenance: geolocation.Provenance{
+			SourceID:    stored.SourceID,
+			SourceName:  stored.SourceName,
+			ImportRunID: stored.ImportRunID,
+			Line:        stored.SourceLine,
+		},
+	}
+	if stored.ObservedAt != nil {
+		location.Provenance.ObservedAt = *stored.ObservedAt
+	}
+	return location, nil
+}
+
+// boltKey - big-endian keys are ordered the same way as the numbers.
+func boltKey(n uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, n)
+}
+
+// boltIPPrefix - the length goes first, so an IPv4 address is not a prefix of IPv6 ones.
+func boltIPPrefix(ip netip.Addr) []byte {
+	addr := geolocation.NormalizeAddr(ip).AsSlice()
+	return append([]byte{byte(len(addr))}, addr...)
+}
+
+func putJSON(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to encode %x: %w", key, err)
+	}
+	return b.Put(key, data) //nolint:wrapcheck
+}
+
+func getBoltDataset(datasets *bolt.Bucket, key []byte) (boltDataset, error) {
+	var dataset boltDataset
+	data := datasets.Get(key)
+	if data == nil {
+		return dataset, geolocation.ErrDatasetNotFound
+	}
+	if err := json.Unmarshal(data, &dataset); err != nil {
+		return dataset, fmt.Errorf("unable to decode dataset %x: %w", key, err)
+	}
+	return dataset, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func setupBoltIPLocationStorage(t *testing.T) *BoltIPLocationStorage {
+	s, err := NewBoltIPLocationStorage(filepath.Join(t.TempDir(), "iplocation.db"), logging.Discard())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+	return s
+}
+
+func TestBoltIPLocationStorage_Conformance(t *testing.T) {
+	geolocationtest.TestStorage(t, func(t *testing.T) geolocationtest.Storage {
+		return setupBoltIPLocationStorage(t)
+	})
+}
+
+func TestBoltIPLocationStorage_Datasets(t *testing.T) {
+	ctx := context.Background()
+	s := setupBoltIPLocationStorage(t)
+	_, err := s.FetchDataset(ctx, 0)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+
+	active := geolocationtest.StoreDataset(ctx, t, s, geolocationtest.Locations, true)
+	inactive := geolocationtest.StoreDataset(ctx, t, s, geolocationtest.Locations[:1], false)
+
+	dataset, err := s.FetchDataset(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, active, dataset.Version)
+	assert.True(t, dataset.Active)
+	assert.Equal(t, len(geolocationtest.Locations), dataset.Records)
+	assert.False(t, dataset.ActivatedAt.IsZero())
+
+	datasets, err := s.ListDatasets(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 2)
+	assert.Equal(t, dataset, datasets[0])
+	assert.Equal(t, inactive, datasets[1].Version)
+	assert.False(t, datasets[1].Active)
+	assert.True(t, datasets[1].ActivatedAt.IsZero())
+
+	_, err = s.FetchDataset(ctx, 42)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+}
+
+func TestBoltIPLocationStorage_ListIPLocations(t *testing.T) {
+	ctx := context.Background()
+	s := setupBoltIPLocationStorage(t)
+	locs := geolocationtest.Locations
+	first := geolocationtest.StoreDataset(ctx, t, s, locs[:1], true)
+	locs = geolocationtest.WithVersion(locs, geolocationtest.StoreDataset(ctx, t, s, locs, true))
+
+	list := func(filter geolocation.ExportFilter) ([][]geolocation.IPLocation, error) {
+		var batches [][]geolocation.IPLocation
+		err := s.ListIPLocations(ctx, filter, 2, func(locs []geolocation.IPLocation) error {
+			batches = append(batches, locs)
+			return nil
+		})
+		return batches, err
+	}
+
+	batches, err := list(geolocation.ExportFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, [][]geolocation.IPLocation{locs[:2], locs[2:]}, batches)
+
+	batches, err = list(geolocation.ExportFilter{CountryCode: "uk"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]geolocation.IPLocation{{locs[0], locs[2]}}, batches)
+
+	batches, err = list(geolocation.ExportFilter{DatasetVersion: first})
+	require.NoError(t, err)
+	assert.Equal(t, [][]geolocation.IPLocation{geolocationtest.WithVersion(locs[:1], first)}, batches)
+
+	_, err = list(geolocation.ExportFilter{DatasetVersion: 42})
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+}
+
+func TestBoltIPLocationStorage_ProfileDataset(t *testing.T) {
+	ctx := context.Background()
+	s := setupBoltIPLocationStorage(t)
+	locs := append([]geolocation.IPLocation{}, geolocationtest.Locations...)
+	ambiguous := locs[1]
+	ambiguous.IP = locs[0].IP
+	version := geolocationtest.StoreDataset(ctx, t, s, append(locs, ambiguous), false)
+
+	profile, err := s.ProfileDataset(ctx, version)
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.DatasetProfile{IPs: 3, AmbiguousIPs: 1, Countries: map[string]int{"UK": 2, "US": 2}},
+		profile)
+
+	_, err = s.ProfileDataset(ctx, 0)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+}
+
+func TestBoltIPLocationStorage_Locked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "iplocation.db")
+	s, err := NewBoltIPLocationStorage(path, logging.Discard())
+	require.NoError(t, err)
+	defer s.Close()
+	_, err = NewBoltIPLocationStorage(path, logging.Discard())
+	require.Error(t, err)
+}
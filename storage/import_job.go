@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// ImportJobStorage is implementation of geolocation.ImportJobStorer on top of PostgreSQL.
+// The schema is migrated together with IPLocationStorage.
+type ImportJobStorage struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+}
+
+var _ geolocation.ImportJobStorer = (*ImportJobStorage)(nil)
+
+func NewImportJobStorage(pool *pgxpool.Pool, logger *slog.Logger) *ImportJobStorage {
+	return &ImportJobStorage{pool: pool, logger: logger}
+}
+
+const importJobColumns = "id, source, format, state, coalesce(dataset_id, 0), imported, non_valid, " +
+	"non_valid_reasons, duplicated, quality_gates, error, cancel_requested, created_at, started_at, finished_at"
+
+// CreateImportJob - see geolocation.ImportJobStorer interface specification.
+func (s *ImportJobStorage) CreateImportJob(
+	ctx context.Context,
+	job geolocation.ImportJob,
+) (geolocation.ImportJob, error) {
+	row := s.pool.QueryRow(ctx, "INSERT INTO geolocation.import_job (source, format) VALUES ($1, $2) "+
+		"RETURNING "+importJobColumns+";", job.Source, job.Format)
+	job, err := scanImportJob(row)
+	if err != nil {
+		return geolocation.ImportJob{}, fmt.Errorf("unable to create import job: %w", err)
+	}
+	s.logger.InfoContext(ctx, "import job created", "import_job_id", job.ID, "source", job.Source)
+	return job, nil
+}
+
+// FetchImportJob - see geolocation.ImportJobStorer interface specification.
+func (s *ImportJobStorage) FetchImportJob(ctx context.Context, id int) (geolocation.ImportJob, error) {
+	row := s.pool.QueryRow(ctx, "SELECT "+importJobColumns+" FROM geolocation.import_job WHERE id = $1;", id)
+	job, err := scanImportJob(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return geolocation.ImportJob{}, geolocation.ErrImportJobNotFound
+	}
+	if err != nil {
+		return geolocation.ImportJob{}, fmt.Errorf("unable to fetch import job: %w", err)
+	}
+	return job, nil
+}
+
+// ListImportJobs - see geolocation.ImportJobStorer interface specification.
+func (s *ImportJobStorage) ListImportJobs(ctx context.Context, limit int) ([]geolocation.ImportJob, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+importJobColumns+" FROM geolocation.import_job ORDER BY id DESC LIMIT $1;", limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list import jobs: %w", err)
+	}
+	defer rows.Close()
+	var jobs []geolocation.ImportJob
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan import job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read import jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimImportJob - see geolocation.ImportJobStorer interface specification.
+// Jobs are locked while claimed, so every job is claimed by a single server.
+func (s *ImportJobStorage) ClaimImportJob(
+	ctx context.Context,
+	staleAfter time.Duration,
+) (geolocation.ImportJob, error) {
+	row := s.pool.QueryRow(ctx, "UPDATE geolocation.import_job SET state = 'running', started_at = now(), "+
+		"updated_at = now(), dataset_id = NULL, imported = 0, non_valid = 0, non_valid_reasons = '{}', duplicated = 0, "+
+		"quality_gates = '[]' "+
+		"WHERE id = (SELECT id FROM geolocation.import_job "+
+		"WHERE state = 'pending' OR (state = 'running' AND updated_at < now() - make_interval(secs => $1)) "+
+		"ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING "+importJobColumns+";", staleAfter.Seconds())
+	job, err := scanImportJob(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return geolocation.ImportJob{}, geolocation.ErrImportJobNotFound
+	}
+	if err != nil {
+		return geolocation.ImportJob{}, fmt.Errorf("unable to claim import job: %w", err)
+	}
+	return job, nil
+}
+
+// UpdateImportJob - see geolocation.ImportJobStorer interface specification.
+// Finished jobs get the finish time, jobs put back to pending lose the start time.
+func (s *ImportJobStorage) UpdateImportJob(
+	ctx context.Context,
+	job geolocation.ImportJob,
+) (geolocation.ImportJob, error) {
+	reasons, err := json.Marshal(job.Statistics.NonValidReasons)
+	if err != nil {
+		return geolocation.ImportJob{}, fmt.Errorf("unable to marshal non-valid reasons: %w", err)
+	}
+	if job.Statistics.NonValidReasons == nil {
+		reasons = []byte("{}")
+	}
+	gates, err := marshalQualityGates(job.Statistics.QualityGates)
+	if err != nil {
+		return geolocation.ImportJob{}, err
+	}
+	stats := job.Statistics
+	row := s.pool.QueryRow(ctx, "UPDATE geolocation.import_job SET state = $2, dataset_id = nullif($3::int, 0), "+
+		"imported = $4, non_valid = $5, non_valid_reasons = $6, duplicated = $7, quality_gates = $8, error = $9, "+
+		"updated_at = now(), "+
+		"finished_at = CASE WHEN $2 IN ('succeeded', 'failed', 'canceled') THEN now() END, "+
+		"started_at = CASE WHEN $2 = 'pending' THEN NULL ELSE started_at END "+
+		"WHERE id = $1 RETURNING "+importJobColumns+";",
+		job.ID, string(job.State), stats.DatasetVersion, stats.Imported, stats.NonValid, string(reasons),
+		stats.Duplicated, string(gates), job.Error)
+	job, err = scanImportJob(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return geolocation.ImportJob{}, geolocation.ErrImportJobNotFound
+	}
+	if err != nil {
+		return geolocation.ImportJob{}, fmt.Errorf("unable to update import job: %w", err)
+	}
+	return job, nil
+}
+
+// CancelImportJob - see geolocation.ImportJobStorer interface specification.
+func (s *ImportJobStorage) CancelImportJob(ctx context.Context, id int) (geolocation.ImportJob, error) {
+	row := s.pool.QueryRow(ctx, "UPDATE geolocation.import_job SET cancel_requested = true, "+
+		"state = CASE WHEN state = 'pending' THEN 'canceled' ELSE state END, "+
+		"finished_at = CASE WHEN state = 'pending' THEN now() ELSE finished_at END "+
+		"WHERE id = $1 AND state IN ('pending', 'running') RETURNING "+importJobColumns+";", id)
+	job, err := scanImportJob(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err = s.FetchImportJob(ctx, id); err != nil {
+			return geolocation.ImportJob{}, err
+		}
+		return geolocation.ImportJob{}, geolocation.ErrImportJobFinished
+	}
+	if err != nil {
+		return geolocation.ImportJob{}, fmt.Errorf("unable to cancel import job: %w", err)
+	}
+	s.logger.InfoContext(ctx, "import job cancellation requested", "import_job_id", id)
+	return job, nil
+}
+
+func scanImportJob(row pgx.Row) (geolocation.ImportJob, error) {
+	job := geolocation.ImportJob{}
+	var state string
+	var reasons, gates []byte
+	var startedAt, finishedAt *time.Time
+	err := row.Scan(&job.ID, &job.Source, &job.Format, &state, &job.Statistics.DatasetVersion,
+		&job.Statistics.Imported, &job.Statistics.NonValid, &reasons, &job.Statistics.Duplicated, &gates,
+		&job.Error,
+		&job.CancelRequested, &job.CreatedAt, &startedAt, &finishedAt)
+	if err != nil {
+		return geolocation.ImportJob{}, err //nolint:wrapcheck
+	}
+	job.State = geolocation.ImportJobState(state)
+	if err = json.Unmarshal(reasons, &job.Statistics.NonValidReasons); err != nil {
+		return geolocation.ImportJob{}, fmt.Errorf("unable to unmarshal non-valid reasons: %w", err)
+	}
+	if len(job.Statistics.NonValidReasons) == 0 {
+		job.Statistics.NonValidReasons = nil
+	}
+	if job.Statistics.QualityGates, err = unmarshalQualityGates(gates); err != nil {
+		return geolocation.ImportJob{}, err
+	}
+	if startedAt != nil {
+		job.StartedAt = *startedAt
+	}
+	if finishedAt != nil {
+		job.FinishedAt = *finishedAt
+	}
+	return job, nil
+}
+
+// qualityGate - JSON representation of geolocation.QualityGateResult in the storage.
+type qualityGate struct {
+	Gate      string  `json:"gate"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Passed    bool    `json:"passed"`
+}
+
+func marshalQualityGates(results []geolocation.QualityGateResult) ([]byte, error) {
+	gates := make([]qualityGate, 0, len(results))
+	for _, r := range results {
+		gates = append(gates, qualityGate(r))
+	}
+	data, err := json.Marshal(gates)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal quality gates: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalQualityGates(data []byte) ([]geolocation.QualityGateResult, error) {
+	var gates []qualityGate
+	if err := json.Unmarshal(data, &gates); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal quality gates: %w", err)
+	}
+	var results []geolocation.QualityGateResult
+	for _, g := range gates {
+		results = append(results, geolocation.QualityGateResult(g))
+	}
+	return results, nil
+}
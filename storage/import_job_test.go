@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func setUpImportJobDB(t *testing.T) (context.Context, *ImportJobStorage, *IPLocationStorage, func()) {
+	ctx, ipLocStorage, teardown := setUpDB(t)
+	require.NoError(t, ipLocStorage.MigrateUp(ctx, migrations))
+	return ctx, NewImportJobStorage(ipLocStorage.pool, logging.Discard()), ipLocStorage, teardown
+}
+
+func TestImportJobStorage_CreateImportJob_FetchImportJob(t *testing.T) {
+	ctx, storage, _, teardown := setUpImportJobDB(t)
+	defer teardown()
+
+	_, err := storage.FetchImportJob(ctx, 42)
+	require.ErrorIs(t, err, geolocation.ErrImportJobNotFound)
+
+	created, err := storage.CreateImportJob(ctx, geolocation.ImportJob{Source: "uploads/data.csv", Format: "csv"})
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, geolocation.ImportJobPending, created.State)
+
+	job, err := storage.FetchImportJob(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created, job)
+	assert.True(t, job.StartedAt.IsZero())
+}
+
+func TestImportJobStorage_ClaimImportJob(t *testing.T) {
+	ctx, storage, _, teardown := setUpImportJobDB(t)
+	defer teardown()
+
+	_, err := storage.ClaimImportJob(ctx, time.Minute)
+	require.ErrorIs(t, err, geolocation.ErrImportJobNotFound)
+
+	first, err := storage.CreateImportJob(ctx, geolocation.ImportJob{Source: "1.csv", Format: "csv"})
+	require.NoError(t, err)
+	second, err := storage.CreateImportJob(ctx, geolocation.ImportJob{Source: "2.csv", Format: "csv"})
+	require.NoError(t, err)
+
+	job, err := storage.ClaimImportJob(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, job.ID)
+	assert.Equal(t, geolocation.ImportJobRunning, job.State)
+	assert.False(t, job.StartedAt.IsZero())
+
+	job, err = storage.ClaimImportJob(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, job.ID)
+	_, err = storage.ClaimImportJob(ctx, time.Minute)
+	require.ErrorIs(t, err, geolocation.ErrImportJobNotFound)
+
+	// Runner of the first job has gone.
+	_, err = storage.pool.Exec(ctx,
+		"UPDATE geolocation.import_job SET updated_at = now() - interval '2 minutes' WHERE id = $1;", first.ID)
+	require.NoError(t, err)
+	job, err = storage.ClaimImportJob(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, job.ID)
+}
+
+func TestImportJobStorage_UpdateImportJob(t *testing.T) {
+	ctx, storage, ipLocStorage, teardown := setUpImportJobDB(t)
+	defer teardown()
+	dataset, err := ipLocStorage.CreateDataset(ctx)
+	require.NoError(t, err)
+	_, err = storage.CreateImportJob(ctx, geolocation.ImportJob{Source: "1.csv", Format: "csv"})
+	require.NoError(t, err)
+	job, err := storage.ClaimImportJob(ctx, time.Minute)
+	require.NoError(t, err)
+
+	job.Statistics = geolocation.ImportStatistics{
+		Imported: 10, NonValid: 2, Duplicated: 1, DatasetVersion: dataset.Version,
+		NonValidReasons: map[geolocation.NonValidReason]int{geolocation.NonValidIP: 2},
+		QualityGates: []geolocation.QualityGateResult{
+			{Gate: geolocation.GateMinRecords, Value: 10, Threshold: 100},
+		},
+	}
+	updated, err := storage.UpdateImportJob(ctx, job)
+	require.NoError(t, err)
+	assert.Equal(t, job.Statistics, updated.Statistics)
+	assert.True(t, updated.FinishedAt.IsZero())
+
+	job.State, job.Error = geolocation.ImportJobFailed, "disk is full"
+	updated, err = storage.UpdateImportJob(ctx, job)
+	require.NoError(t, err)
+	assert.Equal(t, "disk is full", updated.Error)
+	assert.False(t, updated.FinishedAt.IsZero())
+
+	_, err = storage.UpdateImportJob(ctx, geolocation.ImportJob{ID: 42, State: geolocation.ImportJobFailed})
+	require.ErrorIs(t, err, geolocation.ErrImportJobNotFound)
+}
+
+func TestImportJobStorage_CancelImportJob(t *testing.T) {
+	ctx, storage, _, teardown := setUpImportJobDB(t)
+	defer teardown()
+	running, err := storage.CreateImportJob(ctx, geolocation.ImportJob{Source: "1.csv", Format: "csv"})
+	require.NoError(t, err)
+	_, err = storage.ClaimImportJob(ctx, time.Minute)
+	require.NoError(t, err)
+	pending, err := storage.CreateImportJob(ctx, geolocation.ImportJob{Source: "2.csv", Format: "csv"})
+	require.NoError(t, err)
+
+	job, err := storage.CancelImportJob(ctx, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.ImportJobCanceled, job.State)
+	_, err = storage.CancelImportJob(ctx, pending.ID)
+	require.ErrorIs(t, err, geolocation.ErrImportJobFinished)
+
+	job, err = storage.CancelImportJob(ctx, running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.ImportJobRunning, job.State, "running jobs are canceled by the runner")
+	assert.True(t, job.CancelRequested)
+
+	_, err = storage.CancelImportJob(ctx, 42)
+	require.ErrorIs(t, err, geolocation.ErrImportJobNotFound)
+}
+
+func TestImportJobStorage_ListImportJobs(t *testing.T) {
+	ctx, storage, _, teardown := setUpImportJobDB(t)
+	defer teardown()
+	for _, source := range []string{"1.csv", "2.csv", "3.csv"} {
+		_, err := storage.CreateImportJob(ctx, geolocation.ImportJob{Source: source, Format: "csv"})
+		require.NoError(t, err)
+	}
+
+	jobs, err := storage.ListImportJobs(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "3.csv", jobs[0].Source)
+	assert.Equal(t, "2.csv", jobs[1].Source)
+}
@@ -2,8 +2,13 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"net"
+	"net/netip"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -11,44 +16,234 @@ import (
 	"github.com/dronnix/search-accomodation/model/geolocation"
 )
 
-// IPLocationStorage is implementation of IPLocationFetcher/IPLocationStorer on top of PostgreSQL.
+// IPLocationStorage is implementation of IPLocationFetcher/IPLocationStorer/IPLocationLister/DatasetManager
+// and DatasetProfiler on top of PostgreSQL.
 type IPLocationStorage struct {
-	pool *pgxpool.Pool
+	pool         *pgxpool.Pool
+	logger       *slog.Logger
+	normalized   bool          // IP locations are stored to the normalized schema.
+	partitioned  bool          // IP locations are stored to partitions of their datasets.
+	replicas     *Replicas     // Locations and datasets are read from, if any.
+	queryTimeout time.Duration // Of lookups and dataset queries, no timeout if zero.
 }
 
 var _ geolocation.IPLocationFetcher = (*IPLocationStorage)(nil)
 var _ geolocation.IPLocationStorer = (*IPLocationStorage)(nil)
+var _ geolocation.IPLocationLister = (*IPLocationStorage)(nil)
+var _ geolocation.DatasetFetcher = (*IPLocationStorage)(nil)
+var _ geolocation.DatasetManager = (*IPLocationStorage)(nil)
+var _ geolocation.DatasetProfiler = (*IPLocationStorage)(nil)
 
-func NewIPLocationStorage(pool *pgxpool.Pool) *IPLocationStorage {
-	return &IPLocationStorage{pool: pool}
+func NewIPLocationStorage(pool *pgxpool.Pool, logger *slog.Logger) *IPLocationStorage {
+	return &IPLocationStorage{pool: pool, logger: logger}
+}
+
+// WithNormalizedSchema returns the storage storing IP locations to the normalized schema, where countries, cities
+// and locations are stored once and referenced. IP locations of both schemas are fetched the same way.
+func (s *IPLocationStorage) WithNormalizedSchema() *IPLocationStorage {
+	normalized := *s
+	normalized.normalized = true
+	return &normalized
+}
+
+// WithReplicas returns the storage reading IP locations and datasets from the read replicas. Writes, migrations
+// and profiling of datasets being imported go to the primary.
+func (s *IPLocationStorage) WithReplicas(replicas *Replicas) *IPLocationStorage {
+	withReplicas := *s
+	withReplicas.replicas = replicas
+	return &withReplicas
+}
+
+// WithQueryTimeout returns the storage limiting lookups and queries of datasets by the timeout. Stores, exports
+// and profiling of datasets take long, they are limited by the statement timeout of the pool only.
+func (s *IPLocationStorage) WithQueryTimeout(timeout time.Duration) *IPLocationStorage {
+	withTimeout := *s
+	withTimeout.queryTimeout = timeout
+	return &withTimeout
+}
+
+// withTimeout limits the context by the query timeout, if any.
+func (s *IPLocationStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// Primary returns the storage reading from the primary, for reads which must see preceding writes.
+func (s *IPLocationStorage) Primary() *IPLocationStorage {
+	return s.WithReplicas(nil)
+}
+
+// reader returns the pool to read IP locations and datasets from.
+func (s *IPLocationStorage) reader() *pgxpool.Pool {
+	if s.replicas == nil {
+		return s.pool
+	}
+	return s.replicas.Pool()
 }
 
 // MigrateUp migrates up database schema.
-func (s *IPLocationStorage) MigrateUp(ctx context.Context, migrationsDir string) error {
-	version, err := Migrate(ctx, s.pool, migrationsDir)
+func (s *IPLocationStorage) MigrateUp(ctx context.Context, migrations fs.FS) error {
+	version, err := Migrate(ctx, s.pool, migrations)
 	if err != nil {
 		return fmt.Errorf("unable to migrate observations to version %d: %w", version, err)
 	}
+	s.logger.InfoContext(ctx, "database schema migrated", "version", version)
 	return nil
 }
 
+// Ping checks the database is reachable.
+func (s *IPLocationStorage) Ping(ctx context.Context) error {
+	if err := s.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("unable to ping database: %w", err)
+	}
+	return nil
+}
+
+// CheckMigrations returns an error if the database schema is not migrated up to the latest version.
+func (s *IPLocationStorage) CheckMigrations(ctx context.Context, migrations fs.FS) error {
+	current, latest, err := MigrationVersions(ctx, s.pool, migrations)
+	if err != nil {
+		return err
+	}
+	if current != latest {
+		return fmt.Errorf("schema version is %d, expected %d", current, latest)
+	}
+	return nil
+}
+
+// CreateDataset - see geolocation.IPLocationStorer interface specification.
+func (s *IPLocationStorage) CreateDataset(ctx context.Context) (geolocation.Dataset, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	dataset := geolocation.Dataset{}
+	err := s.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, "INSERT INTO geolocation.dataset DEFAULT VALUES RETURNING id, created_at;").
+			Scan(&dataset.Version, &dataset.CreatedAt)
+		if err != nil || !s.partitioned {
+			return err //nolint:wrapcheck
+		}
+		return createPartition(ctx, tx, dataset.Version)
+	})
+	if err != nil {
+		return geolocation.Dataset{}, fmt.Errorf("unable to create dataset: %w", err)
+	}
+	s.logger.InfoContext(ctx, "dataset created", "dataset_version", dataset.Version)
+	return dataset, nil
+}
+
+// ActivateDataset - see geolocation.IPLocationStorer interface specification.
+func (s *IPLocationStorage) ActivateDataset(ctx context.Context, version int) error {
+	err := s.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "UPDATE geolocation.dataset SET active = false WHERE active;"); err != nil {
+			return fmt.Errorf("unable to deactivate dataset: %w", err)
+		}
+		tag, err := tx.Exec(ctx, "UPDATE geolocation.dataset SET active = true, activated_at = now(), "+
+			"records = (SELECT count(*) FROM geolocation.ip_location_all WHERE dataset_id = $1) WHERE id = $1;", version)
+		if err != nil {
+			return fmt.Errorf("unable to activate dataset: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return geolocation.ErrDatasetNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to activate dataset %d: %w", version, err)
+	}
+	s.logger.InfoContext(ctx, "dataset activated", "dataset_version", version)
+	return nil
+}
+
+// FetchDataset - see geolocation.DatasetFetcher interface specification.
+func (s *IPLocationStorage) FetchDataset(ctx context.Context, version int) (geolocation.Dataset, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var datasets []datasetRow
+	err := acquireFunc(ctx, s.reader(), func(conn *pgx.Conn) error {
+		rows, err := queryPrepared(ctx, conn, fetchDatasetQuery, version)
+		if err != nil {
+			return err
+		}
+		datasets, err = datasetScanner.ScanAll(rows, 1)
+		return err
+	})
+	if err != nil {
+		return geolocation.Dataset{}, fmt.Errorf("unable to fetch dataset: %w", err)
+	}
+	if len(datasets) == 0 {
+		return geolocation.Dataset{}, geolocation.ErrDatasetNotFound
+	}
+	return datasets[0].dataset(), nil
+}
+
+// ListDatasets - see geolocation.DatasetManager interface specification.
+func (s *IPLocationStorage) ListDatasets(ctx context.Context) ([]geolocation.Dataset, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var rows []datasetRow
+	err := acquireFunc(ctx, s.reader(), func(conn *pgx.Conn) error {
+		res, err := queryPrepared(ctx, conn, listDatasetsQuery)
+		if err != nil {
+			return err
+		}
+		rows, err = datasetScanner.ScanAll(res, 0)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list datasets: %w", err)
+	}
+	var datasets []geolocation.Dataset
+	for i := range rows {
+		datasets = append(datasets, rows[i].dataset())
+	}
+	return datasets, nil
+}
+
 // StoreIPLocations batch using COPY FROM PostgreSQL.
-func (s *IPLocationStorage) StoreIPLocations(ctx context.Context, locations []geolocation.IPLocation) error {
-	table := []string{"geolocation", "ip_location"}
-	columns := []string{"ip_address", "country_code", "country_name", "city", "latitude", "longitude",
-		"mystery_value"}
+func (s *IPLocationStorage) StoreIPLocations(
+	ctx context.Context,
+	datasetVersion int,
+	locations []geolocation.IPLocation,
+) error {
+	ctx, span := startSpan(ctx, "storage.StoreIPLocations",
+		geolocation.BatchSizeKey.Int(len(locations)), geolocation.DatasetVersionKey.Int(datasetVersion))
+	defer span.End()
+	if err := s.storeIPLocations(ctx, datasetVersion, locations); err != nil {
+		geolocation.RecordSpanError(span, err)
+		return err
+	}
+	return nil
+}
+
+func (s *IPLocationStorage) storeIPLocations(
+	ctx context.Context,
+	datasetVersion int,
+	locations []geolocation.IPLocation,
+) error {
+	if s.normalized {
+		return s.storeNormalizedIPLocations(ctx, datasetVersion, locations)
+	}
+	table := pgx.Identifier{"geolocation", "ip_location"}
+	if s.partitioned {
+		table = partitionTable(datasetVersion)
+	}
+	columns := append([]string{"dataset_id", "ip_address", "country_code", "country_name", "city", "latitude",
+		"longitude", "mystery_value"}, provenanceColumns...)
 
 	locs := make([][]interface{}, len(locations))
 	for i := range locations {
-		locs[i] = []interface{}{
-			locations[i].IP,
+		locs[i] = append([]interface{}{
+			datasetVersion,
+			inet(locations[i].IP),
 			locations[i].CountryCode,
 			locations[i].CountryName,
 			locations[i].City,
 			locations[i].Lat,
 			locations[i].Lon,
 			locations[i].MysteryValue,
-		}
+		}, provenanceValues(locations[i].Provenance)...)
 	}
 
 	n, err := s.pool.CopyFrom(ctx, table, columns, pgx.CopyFromRows(locs))
@@ -61,25 +256,171 @@ func (s *IPLocationStorage) StoreIPLocations(ctx context.Context, locations []ge
 	return nil
 }
 
+// provenanceColumns are copied along with IP locations of all layouts.
+var provenanceColumns = []string{"source_id", "source_name", "import_run_id", "source_line", "observed_at"}
+
+// provenanceValues returns values of provenanceColumns, missing ones are stored as NULL.
+func provenanceValues(p geolocation.Provenance) []interface{} {
+	values := []interface{}{nil, nil, nil, nil, nil}
+	for i, v := range []string{p.SourceID, p.SourceName, p.ImportRunID} {
+		if v != "" {
+			values[i] = v
+		}
+	}
+	if p.Line > 0 {
+		values[3] = p.Line
+	}
+	if !p.ObservedAt.IsZero() {
+		values[4] = p.ObservedAt
+	}
+	return values
+}
+
 // FetchLocationsByIP - see geolocation.IPLocationFetcher interface specification.
-func (s *IPLocationStorage) FetchLocationsByIP(ctx context.Context, ip net.IP) ([]geolocation.IPLocation, error) {
-	// TODO: Use prepared statements, builder if needed.
-	rows, err := s.pool.Query(ctx, "SELECT * FROM geolocation.ip_location WHERE ip_address = $1;", ip)
+func (s *IPLocationStorage) FetchLocationsByIP(ctx context.Context, ip netip.Addr) ([]geolocation.IPLocation, error) {
+	ctx, span := startSpan(ctx, "storage.FetchLocationsByIP", geolocation.IPFamilyAttribute(ip))
+	defer span.End()
+	locations, err := s.fetchLocationsByIP(ctx, ip)
+	if err != nil {
+		geolocation.RecordSpanError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(geolocation.ResultCountKey.Int(len(locations)))
+	return locations, nil
+}
+
+func (s *IPLocationStorage) fetchLocationsByIP(ctx context.Context, ip netip.Addr) ([]geolocation.IPLocation, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var locations []geolocation.IPLocation
+	err := acquireFunc(ctx, s.reader(), func(conn *pgx.Conn) error {
+		rows, err := queryPrepared(ctx, conn, fetchLocationsByIPQuery, inet(ip))
+		if err != nil {
+			return err
+		}
+		locations, err = scanIPLocations(rows, 1)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch locations by ip: %w", err)
 	}
-	defer rows.Close()
+	return locations, nil
+}
+
+// ListIPLocations - see geolocation.IPLocationLister interface specification.
+// Uses a server-side cursor, so only one batch is kept in memory.
+func (s *IPLocationStorage) ListIPLocations(
+	ctx context.Context,
+	filter geolocation.ExportFilter,
+	batchSize int,
+	fn func([]geolocation.IPLocation) error,
+) error {
+	return s.reader().BeginTxFunc(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error { //nolint:wrapcheck
+		version, err := resolveDatasetVersion(ctx, tx, filter.DatasetVersion)
+		if err != nil {
+			return err
+		}
+		rows, err := queryPrepared(ctx, tx.Conn(), declareIPLocationCursorQuery, version, filter.CountryCode)
+		if err != nil {
+			return fmt.Errorf("unable to declare cursor: %w", err)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("unable to declare cursor: %w", err)
+		}
+		return fetchCursor(ctx, tx, "ip_location_cursor", batchSize, fn)
+	})
+}
 
-	locations := make([]geolocation.IPLocation, 0, 1)
-	for rows.Next() {
-		loc := geolocation.IPLocation{}
-		// TODO: Use annotated struct.
-		id := 0
-		err = rows.Scan(&id, &loc.IP, &loc.CountryCode, &loc.CountryName, &loc.City, &loc.Lat, &loc.Lon, &loc.MysteryValue)
+// ProfileDataset - see geolocation.DatasetProfiler interface specification.
+func (s *IPLocationStorage) ProfileDataset(ctx context.Context, version int) (geolocation.DatasetProfile, error) {
+	profile := geolocation.DatasetProfile{Countries: map[string]int{}}
+	err := s.pool.BeginTxFunc(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		version, err := resolveDatasetVersion(ctx, tx, version)
 		if err != nil {
+			return err
+		}
+		err = queryRowPrepared(ctx, tx.Conn(), countIPsQuery, version).Scan(&profile.IPs, &profile.AmbiguousIPs)
+		if err != nil {
+			return fmt.Errorf("unable to count ip addresses: %w", err)
+		}
+		rows, err := queryPrepared(ctx, tx.Conn(), countCountriesQuery, version)
+		if err != nil {
+			return fmt.Errorf("unable to count countries: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var country string
+			var records int
+			if err = rows.Scan(&country, &records); err != nil {
+				return fmt.Errorf("unable to scan country: %w", err)
+			}
+			profile.Countries[country] = records
+		}
+		return rows.Err() //nolint:wrapcheck
+	})
+	if err != nil {
+		return geolocation.DatasetProfile{}, err //nolint:wrapcheck
+	}
+	return profile, nil
+}
+
+// fetchCursor fetches IP locations from the cursor batch by batch until it is exhausted.
+func fetchCursor(
+	ctx context.Context,
+	tx pgx.Tx,
+	cursor string,
+	batchSize int,
+	fn func([]geolocation.IPLocation) error,
+) error {
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s;", batchSize, cursor)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("unable to fetch from cursor: %w", err)
+		}
+		locations, err := scanIPLocations(rows, batchSize)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if len(locations) == 0 {
+			return nil
+		}
+		if err = fn(locations); err != nil {
+			return err
+		}
+	}
+}
+
+// resolveDatasetVersion checks that the dataset exists, zero version means the active dataset.
+func resolveDatasetVersion(ctx context.Context, tx pgx.Tx, version int) (int, error) {
+	err := queryRowPrepared(ctx, tx.Conn(), resolveDatasetQuery, version).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, geolocation.ErrDatasetNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to resolve dataset: %w", err)
+	}
+	return version, nil
+}
+
+// inet - IPv4 addresses are stored as IPv4 inet, IPv6 ones as IPv6, so lookups match the imported addresses.
+func inet(addr netip.Addr) net.IP {
+	return geolocation.NormalizeAddr(addr).AsSlice()
+}
+
+// scanIPLocations scans the rows until they are exhausted, and closes them.
+func scanIPLocations(rows pgx.Rows, capacity int) ([]geolocation.IPLocation, error) {
+	scanned, err := ipLocationScanner.ScanAll(rows, capacity)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan ip locations: %w", err)
+	}
+	locations := make([]geolocation.IPLocation, len(scanned))
+	for i := range scanned {
+		if locations[i], err = scanned[i].ipLocation(); err != nil {
 			return nil, fmt.Errorf("unable to scan ip location: %w", err)
 		}
-		locations = append(locations, loc)
 	}
 	return locations, nil
 }
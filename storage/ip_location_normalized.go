@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// storeNormalizedIPLocations resolves countries, cities and locations of the batch in bulk, inserting the missing
+// ones, and copies IP locations referencing them. The batch is stored in a single transaction.
+func (s *IPLocationStorage) storeNormalizedIPLocations(
+	ctx context.Context,
+	datasetVersion int,
+	locations []geolocation.IPLocation,
+) error {
+	return s.pool.BeginFunc(ctx, func(tx pgx.Tx) error { //nolint:wrapcheck
+		locationIDs, err := resolveLocations(ctx, tx, locations)
+		if err != nil {
+			return err
+		}
+		locs := make([][]interface{}, len(locations))
+		for i := range locations {
+			locs[i] = append([]interface{}{datasetVersion, inet(locations[i].IP), locationIDs[i],
+				locations[i].MysteryValue}, provenanceValues(locations[i].Provenance)...)
+		}
+		n, err := tx.CopyFrom(ctx, pgx.Identifier{"geolocation_normalized", "ip_location"},
+			append([]string{"dataset_id", "ip_address", "location_id", "mystery_value"}, provenanceColumns...),
+			pgx.CopyFromRows(locs))
+		if err != nil {
+			return fmt.Errorf("unable to copy observations to db: %w", err)
+		}
+		if n != int64(len(locations)) {
+			return fmt.Errorf("stored unexpected number of observations")
+		}
+		return nil
+	})
+}
+
+type countryKey struct {
+	code, name string
+}
+
+type cityKey struct {
+	countryID int32
+	name      string
+}
+
+type locationKey struct {
+	cityID   int32
+	lat, lon float64
+}
+
+// resolveLocations returns IDs of the locations in the normalized schema, in the order of the IP locations.
+func resolveLocations(ctx context.Context, tx pgx.Tx, locations []geolocation.IPLocation) ([]int32, error) {
+	countries := map[countryKey]int32{}
+	for i := range locations {
+		countries[countryKey{code: locations[i].CountryCode, name: locations[i].CountryName}] = 0
+	}
+	var codes, names []string
+	for k := range countries {
+		codes, names = append(codes, k.code), append(names, k.name)
+	}
+	err := resolveDimension(ctx, tx, "country", "code, name", "$1::text[], $2::text[]", func(rows pgx.Rows) error {
+		var k countryKey
+		var id int32
+		if err := rows.Scan(&id, &k.code, &k.name); err != nil {
+			return err //nolint:wrapcheck
+		}
+		countries[k] = id
+		return nil
+	}, codes, names)
+	if err != nil {
+		return nil, err
+	}
+
+	cities := map[cityKey]int32{}
+	for i := range locations {
+		country := countries[countryKey{code: locations[i].CountryCode, name: locations[i].CountryName}]
+		cities[cityKey{countryID: country, name: locations[i].City}] = 0
+	}
+	countryIDs, cityNames := make([]int32, 0, len(cities)), make([]string, 0, len(cities))
+	for k := range cities {
+		countryIDs, cityNames = append(countryIDs, k.countryID), append(cityNames, k.name)
+	}
+	err = resolveDimension(ctx, tx, "city", "country_id, name", "$1::int[], $2::text[]", func(rows pgx.Rows) error {
+		var k cityKey
+		var id int32
+		if err := rows.Scan(&id, &k.countryID, &k.name); err != nil {
+			return err //nolint:wrapcheck
+		}
+		cities[k] = id
+		return nil
+	}, countryIDs, cityNames)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]locationKey, len(locations))
+	locs := map[locationKey]int32{}
+	for i := range locations {
+		country := countries[countryKey{code: locations[i].CountryCode, name: locations[i].CountryName}]
+		city := cities[cityKey{countryID: country, name: locations[i].City}]
+		keys[i] = locationKey{cityID: city, lat: locations[i].Lat, lon: locations[i].Lon}
+		locs[keys[i]] = 0
+	}
+	cityIDs, lats, lons := make([]int32, 0, len(locs)), make([]float64, 0, len(locs)), make([]float64, 0, len(locs))
+	for k := range locs {
+		cityIDs, lats, lons = append(cityIDs, k.cityID), append(lats, k.lat), append(lons, k.lon)
+	}
+	err = resolveDimension(ctx, tx, "location", "city_id, latitude, longitude", "$1::int[], $2::float[], $3::float[]",
+		func(rows pgx.Rows) error {
+			var k locationKey
+			var id int32
+			if err := rows.Scan(&id, &k.cityID, &k.lat, &k.lon); err != nil {
+				return err //nolint:wrapcheck
+			}
+			locs[k] = id
+			return nil
+		}, cityIDs, lats, lons)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int32, len(locations))
+	for i, k := range keys {
+		ids[i] = locs[k]
+	}
+	return ids, nil
+}
+
+// resolveDimension inserts the missing rows of the table given as column arrays, and scans ids and columns of all
+// of them. Rows inserted by concurrent imports are selected too, as the select sees rows committed before it.
+func resolveDimension(
+	ctx context.Context,
+	tx pgx.Tx,
+	table, columns, arrays string,
+	scan func(pgx.Rows) error,
+	args ...interface{},
+) error {
+	_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO geolocation_normalized.%s (%s) SELECT * FROM unnest(%s) "+
+		"ON CONFLICT DO NOTHING;", table, columns, arrays), args...)
+	if err != nil {
+		return fmt.Errorf("unable to insert %s: %w", table, err)
+	}
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT id, %s FROM geolocation_normalized.%s "+
+		"WHERE (%s) IN (SELECT * FROM unnest(%s));", columns, table, columns, arrays), args...)
+	if err != nil {
+		return fmt.Errorf("unable to select %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err = scan(rows); err != nil {
+			return fmt.Errorf("unable to scan %s: %w", table, err)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("unable to read %s: %w", table, err)
+	}
+	return nil
+}
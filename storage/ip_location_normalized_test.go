@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestIPLocationStorage_WithNormalizedSchema(t *testing.T) {
+	ctx, storage, teardown := setUpDB(t)
+	defer teardown()
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
+	normalized := storage.WithNormalizedSchema()
+
+	london := geolocation.Coordinate{Lat: 51.5072, Lon: -0.1276}
+	locsToSave := []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK", CountryName: "United Kingdom", City: "London",
+			Coordinate: london, MysteryValue: 1},
+		{IP: netip.AddrFrom4([4]byte{8, 8, 4, 4}), CountryCode: "UK", CountryName: "United Kingdom", City: "London",
+			Coordinate: london, MysteryValue: 2},
+		{IP: netip.MustParseAddr("2001:db8::1"), CountryCode: "US", CountryName: "United States", City: "New York"},
+	}
+	version := geolocationtest.StoreDataset(ctx, t, normalized, locsToSave, true)
+	locsToSave = geolocationtest.WithVersion(locsToSave, version)
+
+	locs, err := storage.FetchLocationsByIP(ctx, locsToSave[1].IP)
+	require.NoError(t, err)
+	assert.Equal(t, locsToSave[1:2], locs)
+
+	var batches [][]geolocation.IPLocation
+	err = storage.ListIPLocations(ctx, geolocation.ExportFilter{}, 10, func(locs []geolocation.IPLocation) error {
+		batches = append(batches, locs)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]geolocation.IPLocation{locsToSave}, batches)
+
+	count := func(table string) int {
+		var n int
+		require.NoError(t, storage.pool.QueryRow(ctx, "SELECT count(*) FROM geolocation_normalized."+table).Scan(&n))
+		return n
+	}
+	assert.Equal(t, 2, count("country"))
+	assert.Equal(t, 2, count("city"))
+	assert.Equal(t, 2, count("location"))
+
+	// Dimensions are reused by the next dataset.
+	geolocationtest.StoreDataset(ctx, t, normalized, locsToSave[:1], true)
+	assert.Equal(t, 2, count("location"))
+}
+
+func TestIPLocationStorage_WithNormalizedSchema_CoexistsWithFlat(t *testing.T) {
+	ctx, storage, teardown := setUpDB(t)
+	defer teardown()
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
+
+	flat := []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK", CountryName: "United Kingdom", City: "London"},
+	}
+	normalized := []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "US", CountryName: "United States", City: "New York"},
+	}
+	flatVersion := geolocationtest.StoreDataset(ctx, t, storage, flat, true)
+	normalizedVersion := geolocationtest.StoreDataset(ctx, t, storage.WithNormalizedSchema(), normalized, true)
+
+	locs, err := storage.FetchLocationsByIP(ctx, flat[0].IP)
+	require.NoError(t, err)
+	assert.Equal(t, geolocationtest.WithVersion(normalized, normalizedVersion), locs)
+
+	require.NoError(t, storage.ActivateDataset(ctx, flatVersion))
+	locs, err = storage.FetchLocationsByIP(ctx, flat[0].IP)
+	require.NoError(t, err)
+	assert.Equal(t, geolocationtest.WithVersion(flat, flatVersion), locs)
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// ErrPartitionNotFound is returned if the dataset has no partition, attached or detached.
+var ErrPartitionNotFound = errors.New("partition not found")
+
+const (
+	partitionSchema = "geolocation_partitioned"
+	partitionPrefix = "ip_location_"
+)
+
+// Partition of a dataset in the partitioned layout.
+type Partition struct {
+	DatasetVersion int
+	Table          string // Qualified name.
+	Attached       bool   // Detached partitions are kept, but their locations are not visible.
+	Bytes          int64  // Of the table and its indexes.
+}
+
+// WithPartitionedLayout returns the storage creating a partition of its own for every dataset, IP locations are
+// copied to the partition of their dataset. IP locations of all layouts are fetched the same way.
+func (s *IPLocationStorage) WithPartitionedLayout() *IPLocationStorage {
+	partitioned := *s
+	partitioned.partitioned = true
+	return &partitioned
+}
+
+// partitionTable returns the partition of the dataset.
+func partitionTable(version int) pgx.Identifier {
+	return pgx.Identifier{partitionSchema, partitionPrefix + strconv.Itoa(version)}
+}
+
+// createPartition creates the partition of the dataset. The check constraint repeating the partition bound
+// lets the partition be attached back without scanning it.
+func createPartition(ctx context.Context, tx pgx.Tx, version int) error {
+	table := partitionTable(version).Sanitize()
+	_, err := tx.Exec(ctx, fmt.Sprintf("CREATE TABLE %s PARTITION OF %s.ip_location FOR VALUES IN (%d);",
+		table, partitionSchema, version))
+	if err != nil {
+		return fmt.Errorf("unable to create partition: %w", err)
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT dataset_id_check CHECK (dataset_id = %d);",
+		table, version))
+	if err != nil {
+		return fmt.Errorf("unable to constrain partition: %w", err)
+	}
+	return nil
+}
+
+// ListPartitions returns partitions of the datasets ordered by version, detached ones included.
+func (s *IPLocationStorage) ListPartitions(ctx context.Context) ([]Partition, error) {
+	rows, err := s.pool.Query(ctx, "SELECT c.relname, c.relispartition, pg_total_relation_size(c.oid) "+
+		"FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace "+
+		"WHERE n.nspname = $1 AND c.relkind = 'r' AND c.relname ~ '^ip_location_[0-9]+$';", partitionSchema)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list partitions: %w", err)
+	}
+	defer rows.Close()
+	var partitions []Partition
+	for rows.Next() {
+		var name string
+		p := Partition{}
+		if err = rows.Scan(&name, &p.Attached, &p.Bytes); err != nil {
+			return nil, fmt.Errorf("unable to scan partition: %w", err)
+		}
+		if p.DatasetVersion, err = strconv.Atoi(strings.TrimPrefix(name, partitionPrefix)); err != nil {
+			continue // Not created by the storage.
+		}
+		p.Table = partitionSchema + "." + name
+		partitions = append(partitions, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to list partitions: %w", err)
+	}
+	slices.SortFunc(partitions, func(a, b Partition) int { return a.DatasetVersion - b.DatasetVersion })
+	return partitions, nil
+}
+
+// AttachPartition attaches the detached partition of the dataset back, its locations become visible again.
+func (s *IPLocationStorage) AttachPartition(ctx context.Context, version int) error {
+	err := s.alterPartition(ctx, version, true, func(tx pgx.Tx, attached bool) error {
+		if attached {
+			return nil
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s.ip_location ATTACH PARTITION %s FOR VALUES IN (%d);",
+			partitionSchema, partitionTable(version).Sanitize(), version))
+		return err //nolint:wrapcheck
+	})
+	if err != nil {
+		return fmt.Errorf("unable to attach partition of dataset %d: %w", version, err)
+	}
+	s.logger.InfoContext(ctx, "partition attached", "dataset_version", version)
+	return nil
+}
+
+// DetachPartition detaches the partition of the inactive dataset, keeping the table.
+func (s *IPLocationStorage) DetachPartition(ctx context.Context, version int) error {
+	err := s.alterPartition(ctx, version, false, func(tx pgx.Tx, attached bool) error {
+		if !attached {
+			return nil
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s.ip_location DETACH PARTITION %s;",
+			partitionSchema, partitionTable(version).Sanitize()))
+		return err //nolint:wrapcheck
+	})
+	if err != nil {
+		return fmt.Errorf("unable to detach partition of dataset %d: %w", version, err)
+	}
+	s.logger.InfoContext(ctx, "partition detached", "dataset_version", version)
+	return nil
+}
+
+// DropPartition drops the partition of the inactive dataset, attached or detached, and deletes the dataset.
+func (s *IPLocationStorage) DropPartition(ctx context.Context, version int) error {
+	err := s.alterPartition(ctx, version, false, func(tx pgx.Tx, _ bool) error {
+		if _, err := tx.Exec(ctx, "DROP TABLE "+partitionTable(version).Sanitize()+";"); err != nil {
+			return err //nolint:wrapcheck
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM geolocation.dataset WHERE id = $1;", version)
+		return err //nolint:wrapcheck
+	})
+	if err != nil {
+		return fmt.Errorf("unable to drop partition of dataset %d: %w", version, err)
+	}
+	s.logger.InfoContext(ctx, "partition dropped", "dataset_version", version)
+	return nil
+}
+
+// alterPartition calls alter in a transaction locking the dataset, so that it is not activated meanwhile.
+// The partition of the active dataset is altered only if allowed.
+func (s *IPLocationStorage) alterPartition(
+	ctx context.Context,
+	version int,
+	allowActive bool,
+	alter func(tx pgx.Tx, attached bool) error,
+) error {
+	return s.pool.BeginFunc(ctx, func(tx pgx.Tx) error { //nolint:wrapcheck
+		active, err := lockDataset(ctx, tx, version)
+		if err != nil && !errors.Is(err, geolocation.ErrDatasetNotFound) { // Partitions may outlive their datasets.
+			return err
+		}
+		if active && !allowActive {
+			return geolocation.ErrDatasetActive
+		}
+		var attached bool
+		err = tx.QueryRow(ctx, "SELECT c.relispartition FROM pg_class c "+
+			"JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = $2;",
+			partitionSchema, partitionPrefix+strconv.Itoa(version)).Scan(&attached)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrPartitionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("unable to fetch partition: %w", err)
+		}
+		return alter(tx, attached)
+	})
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestIPLocationStorage_WithPartitionedLayout_Conformance(t *testing.T) {
+	geolocationtest.TestStorage(t, func(t *testing.T) geolocationtest.Storage {
+		ctx, storage, teardown := setUpDB(t)
+		t.Cleanup(teardown)
+		require.NoError(t, storage.MigrateUp(ctx, migrations))
+		return storage.WithPartitionedLayout()
+	})
+}
+
+func TestIPLocationStorage_WithPartitionedLayout(t *testing.T) {
+	ctx, storage, teardown := setUpDB(t)
+	defer teardown()
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
+	partitioned := storage.WithPartitionedLayout()
+
+	flat := []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK", CountryName: "United Kingdom", City: "London"},
+	}
+	locs := []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "US", CountryName: "United States", City: "New York"},
+	}
+	flatVersion := geolocationtest.StoreDataset(ctx, t, storage, flat, false)
+	old := geolocationtest.StoreDataset(ctx, t, partitioned, locs, false)
+	version := geolocationtest.StoreDataset(ctx, t, partitioned, locs, true)
+
+	partitions, err := storage.ListPartitions(ctx)
+	require.NoError(t, err)
+	require.Len(t, partitions, 2)
+	assert.Equal(t, old, partitions[0].DatasetVersion)
+	assert.Equal(t, version, partitions[1].DatasetVersion)
+	assert.Equal(t, "geolocation_partitioned.ip_location_"+strconv.Itoa(version), partitions[1].Table)
+	assert.True(t, partitions[1].Attached)
+	assert.Positive(t, partitions[1].Bytes)
+
+	fetched, err := storage.FetchLocationsByIP(ctx, locs[0].IP)
+	require.NoError(t, err)
+	assert.Equal(t, geolocationtest.WithVersion(locs, version), fetched)
+
+	require.ErrorIs(t, storage.DetachPartition(ctx, version), geolocation.ErrDatasetActive)
+	require.ErrorIs(t, storage.DropPartition(ctx, version), geolocation.ErrDatasetActive)
+	require.ErrorIs(t, storage.DetachPartition(ctx, flatVersion), ErrPartitionNotFound)
+
+	// Locations of the detached partition are not visible, until it is attached back.
+	require.NoError(t, storage.DetachPartition(ctx, old))
+	require.NoError(t, storage.ActivateDataset(ctx, old))
+	fetched, err = storage.FetchLocationsByIP(ctx, locs[0].IP)
+	require.NoError(t, err)
+	assert.Empty(t, fetched)
+	require.NoError(t, storage.AttachPartition(ctx, old))
+	fetched, err = storage.FetchLocationsByIP(ctx, locs[0].IP)
+	require.NoError(t, err)
+	assert.Equal(t, geolocationtest.WithVersion(locs, old), fetched)
+
+	require.NoError(t, storage.DropPartition(ctx, version))
+	_, err = storage.FetchDataset(ctx, version)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+	partitions, err = storage.ListPartitions(ctx)
+	require.NoError(t, err)
+	require.Len(t, partitions, 1)
+	assert.Equal(t, old, partitions[0].DatasetVersion)
+}
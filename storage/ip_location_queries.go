@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+// ipLocationRow is an IP location as selected from geolocation.ip_location_all.
+type ipLocationRow struct {
+	IP           net.IP  `db:"ip_address"`
+	CountryCode  string  `db:"country_code"`
+	CountryName  string  `db:"country_name"`
+	City         string  `db:"city"`
+	Lat          float64 `db:"latitude"`
+	Lon          float64 `db:"longitude"`
+	MysteryValue uint64  `db:"mystery_value"`
+	DatasetID    int     `db:"dataset_id"`
+
+	SourceID    string     `db:"source_id"`
+	SourceName  string     `db:"source_name"`
+	ImportRunID string     `db:"import_run_id"`
+	SourceLine  int        `db:"source_line"`
+	ObservedAt  *time.Time `db:"observed_at"`
+}
+
+func (r *ipLocationRow) ipLocation() (geolocation.IPLocation, error) {
+	addr, ok := netip.AddrFromSlice(r.IP)
+	if !ok {
+		return geolocation.IPLocation{}, fmt.Errorf("invalid ip address: %v", r.IP)
+	}
+	location := geolocation.IPLocation{
+		IP:             geolocation.NormalizeAddr(addr),
+		CountryCode:    r.CountryCode,
+		CountryName:    r.CountryName,
+		City:           r.City,
+		Coordinate:     geolocation.Coordinate{Lat: r.Lat, Lon: r.Lon},
+		MysteryValue:   r.MysteryValue,
+		DatasetVersion: r.DatasetID,
+		Provenance: geolocation.Provenance{
+			SourceID:    r.SourceID,
+			SourceName:  r.SourceName,
+			ImportRunID: r.ImportRunID,
+			Line:        r.SourceLine,
+		},
+	}
+	if r.ObservedAt != nil {
+		location.Provenance.ObservedAt = r.ObservedAt.UTC()
+	}
+	return location, nil
+}
+
+// datasetRow is a dataset as selected from geolocation.dataset.
+type datasetRow struct {
+	ID          int        `db:"id"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ActivatedAt *time.Time `db:"activated_at"`
+	Active      bool       `db:"active"`
+	Records     int        `db:"records"`
+}
+
+func (r *datasetRow) dataset() geolocation.Dataset {
+	dataset := geolocation.Dataset{Version: r.ID, CreatedAt: r.CreatedAt, Active: r.Active, Records: r.Records}
+	if r.ActivatedAt != nil {
+		dataset.ActivatedAt = *r.ActivatedAt
+	}
+	return dataset
+}
+
+var (
+	ipLocationScanner = newStructScanner[ipLocationRow]()
+	datasetScanner    = newStructScanner[datasetRow]()
+)
+
+// preparedQuery is a query of the read path, prepared under its name on the connections.
+type preparedQuery struct {
+	name string
+	sql  string
+}
+
+var (
+	fetchLocationsByIPQuery = preparedQuery{
+		name: "fetch_locations_by_ip",
+		sql: "SELECT " + ipLocationScanner.Columns() + " FROM geolocation.ip_location_all " +
+			"WHERE ip_address = $1 AND dataset_id = (SELECT id FROM geolocation.dataset WHERE active);",
+	}
+	fetchDatasetQuery = preparedQuery{
+		name: "fetch_dataset",
+		sql: "SELECT " + datasetScanner.Columns() + " FROM geolocation.dataset " +
+			"WHERE CASE WHEN $1 = 0 THEN active ELSE id = $1 END;",
+	}
+	listDatasetsQuery = preparedQuery{
+		name: "list_datasets",
+		sql:  "SELECT " + datasetScanner.Columns() + " FROM geolocation.dataset ORDER BY id;",
+	}
+	resolveDatasetQuery = preparedQuery{
+		name: "resolve_dataset",
+		sql:  "SELECT id FROM geolocation.dataset WHERE CASE WHEN $1 = 0 THEN active ELSE id = $1 END;",
+	}
+	declareIPLocationCursorQuery = preparedQuery{
+		name: "declare_ip_location_cursor",
+		sql: "DECLARE ip_location_cursor NO SCROLL CURSOR FOR " +
+			"SELECT " + ipLocationScanner.Columns() + " FROM geolocation.ip_location_all " +
+			"WHERE dataset_id = $1 AND ($2 = '' OR country_code = upper($2)) ORDER BY id;",
+	}
+	countIPsQuery = preparedQuery{
+		name: "count_ips",
+		sql: "SELECT count(*), count(*) FILTER (WHERE locations > 1) FROM (" +
+			"SELECT count(*) AS locations FROM geolocation.ip_location_all WHERE dataset_id = $1 GROUP BY ip_address" +
+			") AS ips;",
+	}
+	countCountriesQuery = preparedQuery{
+		name: "count_countries",
+		sql: "SELECT coalesce(country_code, ''), count(*) FROM geolocation.ip_location_all " +
+			"WHERE dataset_id = $1 GROUP BY country_code;",
+	}
+)
+
+var preparedQueries = []preparedQuery{
+	fetchLocationsByIPQuery,
+	fetchDatasetQuery,
+	listDatasetsQuery,
+	resolveDatasetQuery,
+	declareIPLocationCursorQuery,
+	countIPsQuery,
+	countCountriesQuery,
+}
+
+// prepareQueries is the AfterConnect hook of the pools, preparing the queries once the schema is migrated.
+// Connections opened before, or the ones failed to prepare, prepare the queries on first use.
+func prepareQueries(ctx context.Context, conn *pgx.Conn) error {
+	var migrated bool
+	err := conn.QueryRow(ctx, "SELECT to_regclass('geolocation.ip_location_all') IS NOT NULL;").Scan(&migrated)
+	if err != nil {
+		return fmt.Errorf("unable to check schema: %w", err)
+	}
+	if !migrated {
+		return nil
+	}
+	for _, q := range preparedQueries {
+		_, _ = conn.Prepare(ctx, q.name, q.sql) // Left to the first use, where the error is reported.
+	}
+	return nil
+}
+
+// queryPrepared runs the prepared query on the connection, preparing it if it is not yet.
+func queryPrepared(ctx context.Context, conn *pgx.Conn, q preparedQuery, args ...interface{}) (pgx.Rows, error) {
+	if _, err := conn.Prepare(ctx, q.name, q.sql); err != nil {
+		return nil, fmt.Errorf("unable to prepare %s: %w", q.name, err)
+	}
+	return conn.Query(ctx, q.name, args...) //nolint:wrapcheck
+}
+
+// queryRowPrepared is queryPrepared returning a single row, the error is deferred to Scan.
+func queryRowPrepared(ctx context.Context, conn *pgx.Conn, q preparedQuery, args ...interface{}) pgx.Row {
+	if _, err := conn.Prepare(ctx, q.name, q.sql); err != nil {
+		return errRow{err: fmt.Errorf("unable to prepare %s: %w", q.name, err)}
+	}
+	return conn.QueryRow(ctx, q.name, args...)
+}
+
+// acquireFunc calls fn with a connection of the pool.
+func acquireFunc(ctx context.Context, pool *pgxpool.Pool, fn func(conn *pgx.Conn) error) error {
+	return pool.AcquireFunc(ctx, func(conn *pgxpool.Conn) error { //nolint:wrapcheck
+		return fn(conn.Conn())
+	})
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...interface{}) error {
+	return r.err
+}
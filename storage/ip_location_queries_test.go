@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+func TestPreparedQueries(t *testing.T) {
+	names := map[string]bool{}
+	for _, q := range preparedQueries {
+		assert.False(t, names[q.name], "duplicated name %s", q.name)
+		names[q.name] = true
+	}
+	assert.Contains(t, fetchLocationsByIPQuery.sql, "SELECT ip_address, country_code, country_name, city, "+
+		"latitude, longitude, mystery_value, dataset_id, source_id, source_name, import_run_id, source_line, "+
+		"observed_at FROM")
+}
+
+func TestIPLocationRow_IPLocation(t *testing.T) {
+	row := ipLocationRow{IP: net.ParseIP("8.8.8.8"), CountryCode: "UK", CountryName: "United Kingdom", City: "London",
+		Lat: 51.5, Lon: -0.1, MysteryValue: 42, DatasetID: 3}
+	loc, err := row.ipLocation()
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.IPLocation{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK",
+		CountryName: "United Kingdom", City: "London", Coordinate: geolocation.Coordinate{Lat: 51.5, Lon: -0.1},
+		MysteryValue: 42, DatasetVersion: 3}, loc, "IPv4 scanned as 16 bytes is unmapped")
+
+	observed := time.Date(2022, 7, 17, 8, 27, 44, 0, time.FixedZone("CEST", 2*60*60))
+	row.SourceName, row.ImportRunID, row.SourceLine, row.ObservedAt = "dump.csv", "run-1", 2, &observed
+	loc, err = row.ipLocation()
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.Provenance{SourceName: "dump.csv", ImportRunID: "run-1", Line: 2,
+		ObservedAt: observed.UTC()}, loc.Provenance)
+
+	_, err = (&ipLocationRow{IP: net.IP{1, 2, 3}}).ipLocation()
+	require.Error(t, err)
+}
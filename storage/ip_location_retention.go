@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/dronnix/search-accomodation/model/geolocation"
+)
+
+var _ geolocation.DatasetRemover = (*IPLocationStorage)(nil)
+
+// RemoveDataset - see geolocation.DatasetRemover interface specification. The partition of a partitioned dataset
+// is dropped at once, and its size is reclaimed. Locations of other layouts are deleted, and their size is reused
+// by the following imports once vacuumed, rather than returned to the file system.
+func (s *IPLocationStorage) RemoveDataset(ctx context.Context, version int) (int64, error) {
+	var reclaimed int64
+	err := s.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		active, err := lockDataset(ctx, tx, version)
+		if err != nil {
+			return err
+		}
+		if active {
+			return geolocation.ErrDatasetActive
+		}
+		if reclaimed, err = removeIPLocations(ctx, tx, version); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, "DELETE FROM geolocation.dataset WHERE id = $1;", version); err != nil {
+			return fmt.Errorf("unable to delete dataset: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to remove dataset %d: %w", version, err)
+	}
+	s.logger.InfoContext(ctx, "dataset removed", "dataset_version", version, "reclaimed_bytes", reclaimed)
+	return reclaimed, nil
+}
+
+// lockDataset locks the dataset until the end of the transaction, so that it is not activated meanwhile.
+// Returns whether the dataset is active, or ErrDatasetNotFound.
+func lockDataset(ctx context.Context, tx pgx.Tx, version int) (bool, error) {
+	var active bool
+	err := tx.QueryRow(ctx, "SELECT active FROM geolocation.dataset WHERE id = $1 FOR UPDATE;", version).
+		Scan(&active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, geolocation.ErrDatasetNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to lock dataset: %w", err)
+	}
+	return active, nil
+}
+
+// removeIPLocations drops the partition of the dataset, if any, and returns its size. Otherwise returns the size
+// of the locations, which are deleted along with the dataset.
+func removeIPLocations(ctx context.Context, tx pgx.Tx, version int) (int64, error) {
+	var size int64
+	err := tx.QueryRow(ctx, "SELECT pg_total_relation_size(c.oid) FROM pg_class c "+
+		"JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = $2;",
+		partitionSchema, partitionPrefix+strconv.Itoa(version)).Scan(&size)
+	if err == nil {
+		if _, err = tx.Exec(ctx, "DROP TABLE "+partitionTable(version).Sanitize()+";"); err != nil {
+			return 0, fmt.Errorf("unable to drop partition: %w", err)
+		}
+		return size, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("unable to fetch partition: %w", err)
+	}
+	const sizeOf = "(SELECT coalesce(sum(pg_column_size(l.*)), 0) FROM %s l WHERE dataset_id = $1)"
+	err = tx.QueryRow(ctx, "SELECT "+fmt.Sprintf(sizeOf, "geolocation.ip_location")+" + "+
+		fmt.Sprintf(sizeOf, "geolocation_normalized.ip_location")+";", version).Scan(&size)
+	if err != nil {
+		return 0, fmt.Errorf("unable to size ip locations: %w", err)
+	}
+	return size, nil
+}
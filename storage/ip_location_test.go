@@ -2,20 +2,22 @@ package storage
 
 import (
 	"context"
-	"net"
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/dronnix/search-accomodation/internal/logging"
 	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
 )
 
-const migrationsDir = "migrations/iplocation"
+var migrations = IPLocationMigrations()
 
 func setupIPLocationStorage(ctx context.Context, t *testing.T) (storage *IPLocationStorage, teardown func()) {
 	pool, teardown := testConnectionPool(ctx, t)
-	return NewIPLocationStorage(pool), teardown
+	return NewIPLocationStorage(pool, logging.Discard()), teardown
 }
 
 func setUpDB(t *testing.T) (context.Context, *IPLocationStorage, func()) {
@@ -24,21 +26,32 @@ func setUpDB(t *testing.T) (context.Context, *IPLocationStorage, func()) {
 	return ctx, storage, teardown
 }
 
+func TestIPLocationStorage_Conformance(t *testing.T) {
+	geolocationtest.TestStorage(t, func(t *testing.T) geolocationtest.Storage {
+		ctx, storage, teardown := setUpDB(t)
+		t.Cleanup(teardown)
+		require.NoError(t, storage.MigrateUp(ctx, migrations))
+		return storage
+	})
+}
+
 // Test utilities
 func TestStorage_StoreObservations_MigrateUp(t *testing.T) {
 	ctx := context.Background()
 	storage, teardown := setupIPLocationStorage(ctx, t)
 	defer teardown()
-	require.NoError(t, storage.MigrateUp(ctx, migrationsDir))
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
 }
 
 func TestIPLocationStorage_StoreIPLocations(t *testing.T) {
 	ctx, storage, teardown := setUpDB(t)
 	defer teardown()
-	require.NoError(t, storage.MigrateUp(ctx, migrationsDir))
-	require.NoError(t, storage.StoreIPLocations(ctx, []geolocation.IPLocation{
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
+	dataset, err := storage.CreateDataset(ctx)
+	require.NoError(t, err)
+	require.NoError(t, storage.StoreIPLocations(ctx, dataset.Version, []geolocation.IPLocation{
 		{
-			IP:          net.IPv4(8, 8, 8, 8),
+			IP:          netip.AddrFrom4([4]byte{8, 8, 8, 8}),
 			CountryCode: "UK",
 			CountryName: "United Kingdom",
 			City:        "London",
@@ -58,86 +71,119 @@ func TestIPLocationStorage_StoreIPLocations(t *testing.T) {
 	assert.Equal(t, 1, count)
 }
 
-func TestIPLocationStorage_FetchLocationsByIP(t *testing.T) {
+func TestIPLocationStorage_ListIPLocations(t *testing.T) {
 	ctx, storage, teardown := setUpDB(t)
 	defer teardown()
-	require.NoError(t, storage.MigrateUp(ctx, migrationsDir))
-	locsToSave := []geolocation.IPLocation{
-		{
-			IP:          net.IP{8, 8, 8, 8},
-			CountryCode: "UK",
-			CountryName: "United Kingdom",
-			City:        "London",
-			Coordinate: geolocation.Coordinate{
-				Lat: 0.42,
-				Lon: -0.23,
-			},
-			MysteryValue: 31337,
-		},
-		{
-			IP:          net.IP{9, 9, 9, 9},
-			CountryCode: "US",
-			CountryName: "United States",
-			City:        "New York",
-			Coordinate: geolocation.Coordinate{
-				Lat: 49.42,
-				Lon: 112.23,
-			},
-			MysteryValue: 31337,
-		},
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
+	locsToSave := geolocationtest.Locations
+	first := geolocationtest.StoreDataset(ctx, t, storage, locsToSave[:1], true)
+	firstLocs := geolocationtest.WithVersion(locsToSave[:1], first)
+	locsToSave = geolocationtest.WithVersion(locsToSave, geolocationtest.StoreDataset(ctx, t, storage, locsToSave, true))
+
+	list := func(filter geolocation.ExportFilter) ([][]geolocation.IPLocation, error) {
+		var batches [][]geolocation.IPLocation
+		err := storage.ListIPLocations(ctx, filter, 2, func(locs []geolocation.IPLocation) error {
+			batches = append(batches, locs)
+			return nil
+		})
+		return batches, err
 	}
-	require.NoError(t, storage.StoreIPLocations(ctx, locsToSave))
 
-	locs, err := storage.FetchLocationsByIP(ctx, net.IP{8, 8, 8, 8})
+	batches, err := list(geolocation.ExportFilter{})
 	require.NoError(t, err)
-	require.Equal(t, 1, len(locs))
-	assert.Equal(t, locsToSave[0], locs[0])
+	assert.Equal(t, [][]geolocation.IPLocation{locsToSave[:2], locsToSave[2:]}, batches)
+
+	batches, err = list(geolocation.ExportFilter{CountryCode: "uk"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]geolocation.IPLocation{{locsToSave[0], locsToSave[2]}}, batches)
+
+	batches, err = list(geolocation.ExportFilter{DatasetVersion: first})
+	require.NoError(t, err)
+	assert.Equal(t, [][]geolocation.IPLocation{firstLocs}, batches)
+
+	_, err = list(geolocation.ExportFilter{DatasetVersion: 42})
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
 }
 
-func TestIPLocationStorage_FetchLocationsMultiIP(t *testing.T) {
+func TestIPLocationStorage_FetchDataset(t *testing.T) {
 	ctx, storage, teardown := setUpDB(t)
 	defer teardown()
-	require.NoError(t, storage.MigrateUp(ctx, migrationsDir))
-	locsToSave := []geolocation.IPLocation{
-		{
-			IP:          net.IP{8, 8, 8, 8},
-			CountryCode: "UK",
-			CountryName: "United Kingdom",
-			City:        "London",
-			Coordinate: geolocation.Coordinate{
-				Lat: 0.42,
-				Lon: -0.23,
-			},
-			MysteryValue: 31337,
-		},
-		{
-			IP:          net.IP{9, 9, 9, 9},
-			CountryCode: "US",
-			CountryName: "United States",
-			City:        "New York",
-			Coordinate: geolocation.Coordinate{
-				Lat: 49.42,
-				Lon: 112.23,
-			},
-			MysteryValue: 31337,
-		},
-		{
-			IP:          net.IP{8, 8, 8, 8},
-			CountryCode: "GE",
-			CountryName: "Georgia",
-			City:        "Tbilisi",
-			Coordinate: geolocation.Coordinate{
-				Lat: 0.42,
-				Lon: -0.23,
-			},
-			MysteryValue: 31337,
-		},
-	}
-	require.NoError(t, storage.StoreIPLocations(ctx, locsToSave))
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
 
-	locs, err := storage.FetchLocationsByIP(ctx, net.IP{8, 8, 8, 8})
+	_, err := storage.FetchDataset(ctx, 0)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+
+	version := geolocationtest.StoreDataset(ctx, t, storage, []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK", CountryName: "United Kingdom", City: "London"},
+		{IP: netip.AddrFrom4([4]byte{9, 9, 9, 9}), CountryCode: "US", CountryName: "United States", City: "New York"},
+	}, true)
+	inactive, err := storage.CreateDataset(ctx)
+	require.NoError(t, err)
+
+	active, err := storage.FetchDataset(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, version, active.Version)
+	assert.True(t, active.Active)
+	assert.False(t, active.ActivatedAt.IsZero())
+	assert.Equal(t, 2, active.Records)
+
+	dataset, err := storage.FetchDataset(ctx, inactive.Version)
 	require.NoError(t, err)
-	require.Equal(t, 2, len(locs))
-	assert.Equal(t, locsToSave[0], locs[0])
-	assert.Equal(t, locsToSave[2], locs[1])
+	assert.False(t, dataset.Active)
+	assert.True(t, dataset.ActivatedAt.IsZero())
+	assert.Equal(t, 0, dataset.Records)
+}
+
+func TestIPLocationStorage_ListDatasets(t *testing.T) {
+	ctx, storage, teardown := setUpDB(t)
+	defer teardown()
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
+
+	datasets, err := storage.ListDatasets(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, datasets)
+
+	active := geolocationtest.StoreDataset(ctx, t, storage, []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK", CountryName: "United Kingdom", City: "London"},
+	}, true)
+	inactive, err := storage.CreateDataset(ctx)
+	require.NoError(t, err)
+
+	datasets, err = storage.ListDatasets(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 2)
+	assert.Equal(t, active, datasets[0].Version)
+	assert.True(t, datasets[0].Active)
+	assert.Equal(t, 1, datasets[0].Records)
+	assert.Equal(t, inactive.Version, datasets[1].Version)
+	assert.False(t, datasets[1].Active)
+}
+
+func TestIPLocationStorage_ProfileDataset(t *testing.T) {
+	ctx, storage, teardown := setUpDB(t)
+	defer teardown()
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
+
+	_, err := storage.ProfileDataset(ctx, 0)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
+
+	version := geolocationtest.StoreDataset(ctx, t, storage, []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK", CountryName: "United Kingdom", City: "London"},
+		{IP: netip.AddrFrom4([4]byte{8, 8, 8, 8}), CountryCode: "UK", CountryName: "United Kingdom", City: "Leeds"},
+		{IP: netip.AddrFrom4([4]byte{9, 9, 9, 9}), CountryCode: "US", CountryName: "United States", City: "New York"},
+	}, true)
+	inactive, err := storage.CreateDataset(ctx)
+	require.NoError(t, err)
+
+	profile, err := storage.ProfileDataset(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.DatasetProfile{IPs: 2, AmbiguousIPs: 1, Countries: map[string]int{"UK": 2, "US": 1}},
+		profile)
+
+	profile, err = storage.ProfileDataset(ctx, inactive.Version)
+	require.NoError(t, err)
+	assert.Equal(t, geolocation.DatasetProfile{Countries: map[string]int{}}, profile)
+
+	_, err = storage.ProfileDataset(ctx, version+42)
+	require.ErrorIs(t, err, geolocation.ErrDatasetNotFound)
 }
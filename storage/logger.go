@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// pgxLogger passes pgx logs to slog. Query arguments are not logged, they may contain personal data.
+type pgxLogger struct {
+	logger *slog.Logger
+}
+
+var _ pgx.Logger = pgxLogger{}
+
+func (l pgxLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	attrs := make([]slog.Attr, 0, len(data))
+	for k, v := range data {
+		if k == "args" {
+			continue
+		}
+		attrs = append(attrs, slog.Any(k, v))
+	}
+	l.logger.LogAttrs(ctx, slogLevel(level), msg, attrs...)
+}
+
+// slogLevel maps pgx levels to slog ones, pgx logs every query with info level, so it's debug for us.
+func slogLevel(level pgx.LogLevel) slog.Level {
+	switch {
+	case level >= pgx.LogLevelDebug:
+		return slog.LevelDebug - 4 //nolint:gomnd // More verbose than debug.
+	case level >= pgx.LogLevelInfo:
+		return slog.LevelDebug
+	case level >= pgx.LogLevelWarn:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+// pgxLogLevel returns the most verbose pgx level enabled in the logger, so pgx doesn't prepare skipped records.
+func pgxLogLevel(ctx context.Context, logger *slog.Logger) pgx.LogLevel {
+	for _, level := range []pgx.LogLevel{pgx.LogLevelTrace, pgx.LogLevelInfo, pgx.LogLevelWarn} {
+		if logger.Enabled(ctx, slogLevel(level)) {
+			return level
+		}
+	}
+	return pgx.LogLevelError
+}
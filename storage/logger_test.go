@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgxLogger_Log(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := pgxLogger{logger: slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+
+	logger.Log(context.Background(), pgx.LogLevelInfo, "Query",
+		map[string]interface{}{"sql": "SELECT 1 WHERE $1", "args": []interface{}{"1.2.3.4"}})
+	logger.Log(context.Background(), pgx.LogLevelTrace, "skipped", nil)
+
+	record := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "DEBUG", record["level"])
+	assert.Equal(t, "Query", record["msg"])
+	assert.Equal(t, "SELECT 1 WHERE $1", record["sql"])
+	assert.NotContains(t, record, "args")
+}
+
+func TestPgxLogLevel(t *testing.T) {
+	levels := map[slog.Level]pgx.LogLevel{
+		slog.LevelDebug - 4: pgx.LogLevelTrace,
+		slog.LevelDebug:     pgx.LogLevelInfo,
+		slog.LevelInfo:      pgx.LogLevelWarn,
+		slog.LevelWarn:      pgx.LogLevelWarn,
+		slog.LevelError:     pgx.LogLevelError,
+	}
+	for level, want := range levels {
+		logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: level}))
+		assert.Equal(t, want, pgxLogLevel(context.Background(), logger), level.String())
+	}
+}
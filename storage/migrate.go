@@ -2,39 +2,164 @@ package storage
 
 import (
 	"context"
+	"embed"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/jackc/tern/migrate"
 )
 
 const migrationsTableName = "migration"
 
-// Migrate runs migrations from migrationsDir.
+//go:embed migrations/iplocation/*.sql
+var embeddedMigrations embed.FS
+
+// IPLocationMigrations returns migrations of the geolocation schema embedded into the binary.
+func IPLocationMigrations() fs.FS {
+	migrations, err := fs.Sub(embeddedMigrations, "migrations/iplocation")
+	if err != nil {
+		panic(err) // The path is constant, so it can't happen.
+	}
+	return migrations
+}
+
+// Migrate runs all migrations from the migrations file system.
 func Migrate(
 	ctx context.Context,
 	pool *pgxpool.Pool,
-	migrationsDir string,
+	migrations fs.FS,
 ) (version int32, err error) {
+	err = withMigrator(ctx, pool, migrations, func(migrator *migrate.Migrator) error {
+		if err := migrator.Migrate(ctx); err != nil {
+			return fmt.Errorf("unable to migrate: %w", err)
+		}
+		if version, err = migrator.GetCurrentVersion(ctx); err != nil {
+			return fmt.Errorf("unable to get migration version: %w", err)
+		}
+		return nil
+	})
+	return version, err
+}
+
+// MigrateTo migrates up or down to the version, 0 means all migrations are rolled back.
+func MigrateTo(ctx context.Context, pool *pgxpool.Pool, migrations fs.FS, version int32) error {
+	return withMigrator(ctx, pool, migrations, func(migrator *migrate.Migrator) error {
+		if err := migrator.MigrateTo(ctx, version); err != nil {
+			return fmt.Errorf("unable to migrate to version %d: %w", version, err)
+		}
+		return nil
+	})
+}
+
+// MigrationStatus - the current schema version and names of the available migrations, the first Current of them
+// are applied.
+type MigrationStatus struct {
+	Current    int32
+	Migrations []string
+}
+
+// Latest returns the version all available migrations are applied at.
+func (s MigrationStatus) Latest() int32 {
+	return int32(len(s.Migrations))
+}
+
+// FetchMigrationStatus returns the current schema version and the migrations available in the file system.
+func FetchMigrationStatus(ctx context.Context, pool *pgxpool.Pool, migrations fs.FS) (MigrationStatus, error) {
+	var status MigrationStatus
+	err := withMigrator(ctx, pool, migrations, func(migrator *migrate.Migrator) error {
+		current, err := migrator.GetCurrentVersion(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to get migration version: %w", err)
+		}
+		status.Current = current
+		for _, m := range migrator.Migrations {
+			status.Migrations = append(status.Migrations, m.Name)
+		}
+		return nil
+	})
+	return status, err
+}
+
+// MigrationVersions returns the current schema version and the latest one available in the file system.
+func MigrationVersions(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	migrations fs.FS,
+) (current, latest int32, err error) {
+	status, err := FetchMigrationStatus(ctx, pool, migrations)
+	if err != nil {
+		return 0, 0, err
+	}
+	return status.Current, status.Latest(), nil
+}
+
+func withMigrator(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	migrations fs.FS,
+	fn func(*migrate.Migrator) error,
+) error {
 	conn, err := pool.Acquire(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("unable to get connection from pool: %w", err)
+		return fmt.Errorf("unable to get connection from pool: %w", err)
 	}
 	defer conn.Release()
 
-	migrator, err := migrate.NewMigrator(ctx, conn.Conn(), migrationsTableName)
+	migrator, err := newMigrator(ctx, conn.Conn(), migrations)
 	if err != nil {
-		return 0, fmt.Errorf("unable to create migrator: %w", err)
+		return err
 	}
-	if err = migrator.LoadMigrations(migrationsDir); err != nil {
-		return 0, fmt.Errorf("unable to load migrations: %w", err)
+	return fn(migrator)
+}
+
+func newMigrator(ctx context.Context, conn *pgx.Conn, migrations fs.FS) (*migrate.Migrator, error) {
+	migrator, err := migrate.NewMigratorEx(ctx, conn, migrationsTableName,
+		&migrate.MigratorOptions{MigratorFS: migratorFS{fsys: migrations}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create migrator: %w", err)
 	}
-	if err = migrator.Migrate(ctx); err != nil {
-		return 0, fmt.Errorf("unable to migrate: %w", err)
+	if err = migrator.LoadMigrations("."); err != nil {
+		return nil, fmt.Errorf("unable to load migrations: %w", err)
 	}
-	ver, err := migrator.GetCurrentVersion(ctx)
+	return migrator, nil
+}
+
+// migratorFS adapts fs.FS to the file system interface of the migrator, which uses OS-specific paths.
+type migratorFS struct {
+	fsys fs.FS
+}
+
+func (m migratorFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	entries, err := fs.ReadDir(m.fsys, filepath.ToSlash(dirname))
 	if err != nil {
-		return 0, fmt.Errorf("unable to get  migration version: %w", err)
+		return nil, err //nolint:wrapcheck
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (m migratorFS) ReadFile(filename string) ([]byte, error) {
+	return fs.ReadFile(m.fsys, filepath.ToSlash(filename)) //nolint:wrapcheck
+}
+
+func (m migratorFS) Glob(pattern string) ([]string, error) {
+	matches, err := fs.Glob(m.fsys, filepath.ToSlash(pattern))
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	for i := range matches {
+		matches[i] = filepath.FromSlash(matches[i])
 	}
-	return ver, nil
+	return matches, nil
 }
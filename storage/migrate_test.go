@@ -2,19 +2,68 @@ package storage
 
 import (
 	"context"
+	"os"
 	"testing"
 
+	"github.com/jackc/tern/migrate"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+var testMigrations = os.DirFS("testdata")
+
 func TestMigrate(t *testing.T) {
 	ctx := context.Background()
 	pool, teardown := testConnectionPool(ctx, t)
 	defer teardown()
-	ver, err := Migrate(ctx, pool, "testdata")
+	ver, err := Migrate(ctx, pool, testMigrations)
 	require.NoError(t, err)
 	assert.Equal(t, int32(2), ver)
 }
 
+func TestMigrationVersions(t *testing.T) {
+	ctx := context.Background()
+	pool, teardown := testConnectionPool(ctx, t)
+	defer teardown()
+	current, latest, err := MigrationVersions(ctx, pool, testMigrations)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), current)
+	assert.Equal(t, int32(2), latest)
+
+	_, err = Migrate(ctx, pool, testMigrations)
+	require.NoError(t, err)
+	current, latest, err = MigrationVersions(ctx, pool, testMigrations)
+	require.NoError(t, err)
+	assert.Equal(t, latest, current)
+}
+
+func TestMigrateTo(t *testing.T) {
+	ctx := context.Background()
+	pool, teardown := testConnectionPool(ctx, t)
+	defer teardown()
+	_, err := Migrate(ctx, pool, testMigrations)
+	require.NoError(t, err)
+
+	require.NoError(t, MigrateTo(ctx, pool, testMigrations, 1))
+	status, err := FetchMigrationStatus(ctx, pool, testMigrations)
+	require.NoError(t, err)
+	assert.Equal(t, MigrationStatus{
+		Current:    1,
+		Migrations: []string{"0001_test_migrations.sql", "0002_test_migrations.sql"},
+	}, status)
+
+	require.Error(t, MigrateTo(ctx, pool, testMigrations, 3))
+}
+
+func TestIPLocationMigrations(t *testing.T) {
+	embedded, err := migrate.FindMigrationsEx(".", migratorFS{fsys: IPLocationMigrations()})
+	require.NoError(t, err)
+	onDisk, err := migrate.FindMigrations("migrations/iplocation")
+	require.NoError(t, err)
+	require.Len(t, embedded, len(onDisk))
+	for i := range onDisk {
+		assert.Equal(t, "migrations/iplocation/"+embedded[i], onDisk[i])
+	}
+}
+
 // TODO: Add tests for negative cases.
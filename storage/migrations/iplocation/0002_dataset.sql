@@ -0,0 +1,29 @@
+-- Every import creates a new dataset version, only one dataset is active (used for lookups) at a time.
+CREATE TABLE geolocation.dataset
+(
+    id serial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    activated_at timestamptz,
+    active boolean NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX dataset_active_idx ON geolocation.dataset (active) WHERE active;
+
+-- Locations imported before versioning become the first active dataset.
+INSERT INTO geolocation.dataset (activated_at, active)
+SELECT now(), true
+WHERE EXISTS (SELECT 1 FROM geolocation.ip_location);
+
+ALTER TABLE geolocation.ip_location ADD COLUMN dataset_id int REFERENCES geolocation.dataset (id) ON DELETE CASCADE;
+UPDATE geolocation.ip_location SET dataset_id = (SELECT id FROM geolocation.dataset WHERE active);
+ALTER TABLE geolocation.ip_location ALTER COLUMN dataset_id SET NOT NULL;
+
+CREATE INDEX ip_location_dataset_idx ON geolocation.ip_location (dataset_id);
+
+-- ---- create above / drop below ----
+
+DROP INDEX geolocation.ip_location_dataset_idx;
+
+ALTER TABLE geolocation.ip_location DROP COLUMN dataset_id;
+
+DROP TABLE geolocation.dataset;
@@ -0,0 +1,10 @@
+-- Number of records is counted once on activation, counting them on every request is too slow for big datasets.
+ALTER TABLE geolocation.dataset ADD COLUMN records bigint NOT NULL DEFAULT 0;
+
+UPDATE geolocation.dataset d
+SET records = (SELECT count(*) FROM geolocation.ip_location l WHERE l.dataset_id = d.id)
+WHERE d.activated_at IS NOT NULL;
+
+-- ---- create above / drop below ----
+
+ALTER TABLE geolocation.dataset DROP COLUMN records;
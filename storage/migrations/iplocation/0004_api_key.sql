@@ -0,0 +1,16 @@
+-- API keys of clients, only SHA-256 of the secret is stored.
+CREATE TABLE geolocation.api_key
+(
+    id serial PRIMARY KEY,
+    name text NOT NULL,
+    hash text NOT NULL UNIQUE,
+    rate_per_second float NOT NULL DEFAULT 0,
+    burst int NOT NULL DEFAULT 0,
+    daily_quota int NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    revoked_at timestamptz
+);
+
+-- ---- create above / drop below ----
+
+DROP TABLE geolocation.api_key;
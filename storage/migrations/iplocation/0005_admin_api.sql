@@ -0,0 +1,31 @@
+-- Admin API is available with admin keys only.
+ALTER TABLE geolocation.api_key ADD COLUMN admin boolean NOT NULL DEFAULT false;
+
+-- Imports started with admin API, run by the server in the background.
+CREATE TABLE geolocation.import_job
+(
+    id serial PRIMARY KEY,
+    source text NOT NULL,
+    format text NOT NULL,
+    state text NOT NULL DEFAULT 'pending',
+    dataset_id int REFERENCES geolocation.dataset (id) ON DELETE SET NULL,
+    imported bigint NOT NULL DEFAULT 0,
+    non_valid bigint NOT NULL DEFAULT 0,
+    non_valid_reasons jsonb NOT NULL DEFAULT '{}',
+    duplicated bigint NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    cancel_requested boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    -- Running jobs are updated periodically, a job not updated for long has lost its runner.
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    started_at timestamptz,
+    finished_at timestamptz
+);
+
+CREATE INDEX import_job_state_idx ON geolocation.import_job (state, id) WHERE state IN ('pending', 'running');
+
+-- ---- create above / drop below ----
+
+DROP TABLE geolocation.import_job;
+
+ALTER TABLE geolocation.api_key DROP COLUMN admin;
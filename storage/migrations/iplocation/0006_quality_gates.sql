@@ -0,0 +1,6 @@
+-- Results of quality gates checked before activation of the imported dataset.
+ALTER TABLE geolocation.import_job ADD COLUMN quality_gates jsonb NOT NULL DEFAULT '[]';
+
+-- ---- create above / drop below ----
+
+ALTER TABLE geolocation.import_job DROP COLUMN quality_gates;
@@ -0,0 +1,9 @@
+-- IPv4 addresses were stored as IPv4-mapped IPv6 (::ffff:1.2.3.4) when parsed to 16 bytes,
+-- normalized addresses are stored as IPv4.
+UPDATE geolocation.ip_location SET ip_address = '0.0.0.0'::inet + (ip_address - '::ffff:0.0.0.0'::inet)
+WHERE ip_address << '::ffff:0.0.0.0/96';
+
+-- ---- create above / drop below ----
+
+UPDATE geolocation.ip_location SET ip_address = '::ffff:0.0.0.0'::inet + (ip_address - '0.0.0.0'::inet)
+WHERE family(ip_address) = 4;
@@ -0,0 +1,58 @@
+-- Optional normalized schema for real feeds, where countries, cities and locations are repeated a lot.
+-- Datasets are stored either in geolocation.ip_location or here, depending on the importer.
+CREATE SCHEMA geolocation_normalized;
+
+-- The same code may come with different names.
+CREATE TABLE geolocation_normalized.country
+(
+    id serial PRIMARY KEY,
+    code varchar(2) NOT NULL,
+    name text NOT NULL,
+    UNIQUE (code, name)
+);
+
+CREATE TABLE geolocation_normalized.city
+(
+    id serial PRIMARY KEY,
+    country_id int NOT NULL REFERENCES geolocation_normalized.country (id),
+    name text NOT NULL,
+    UNIQUE (country_id, name)
+);
+
+CREATE TABLE geolocation_normalized.location
+(
+    id serial PRIMARY KEY,
+    city_id int NOT NULL REFERENCES geolocation_normalized.city (id),
+    latitude float NOT NULL,
+    longitude float NOT NULL,
+    UNIQUE (city_id, latitude, longitude)
+);
+
+CREATE TABLE geolocation_normalized.ip_location
+(
+    id bigserial PRIMARY KEY,
+    dataset_id int NOT NULL REFERENCES geolocation.dataset (id) ON DELETE CASCADE,
+    ip_address inet NOT NULL,
+    location_id int NOT NULL REFERENCES geolocation_normalized.location (id),
+    mystery_value bigint NOT NULL
+);
+
+CREATE INDEX ip_location_ip_idx ON geolocation_normalized.ip_location USING hash(ip_address);
+CREATE INDEX ip_location_dataset_idx ON geolocation_normalized.ip_location (dataset_id);
+
+-- Locations of both schemas, conditions on dataset_id and ip_address are pushed down to the indexes of each.
+CREATE VIEW geolocation.ip_location_all AS
+SELECT id::bigint AS id, ip_address, country_code, country_name, city, latitude, longitude, mystery_value, dataset_id
+FROM geolocation.ip_location
+UNION ALL
+SELECT l.id, l.ip_address, c.code, c.name, ci.name, lo.latitude, lo.longitude, l.mystery_value, l.dataset_id
+FROM geolocation_normalized.ip_location l
+JOIN geolocation_normalized.location lo ON lo.id = l.location_id
+JOIN geolocation_normalized.city ci ON ci.id = lo.city_id
+JOIN geolocation_normalized.country c ON c.id = ci.country_id;
+
+-- ---- create above / drop below ----
+
+DROP VIEW geolocation.ip_location_all;
+
+DROP SCHEMA geolocation_normalized CASCADE;
@@ -0,0 +1,48 @@
+-- Optional layout for big datasets, partitioned by dataset: every dataset is stored to a partition of its own,
+-- which is indexed and vacuumed separately, detached or dropped instantly.
+CREATE SCHEMA geolocation_partitioned;
+
+-- No reference to geolocation.dataset, deleting a dataset would delete its rows one by one instead of dropping
+-- the partition. Partitions are named ip_location_<dataset_id>.
+CREATE TABLE geolocation_partitioned.ip_location
+(
+    id bigserial NOT NULL,
+    dataset_id int NOT NULL,
+    ip_address inet NOT NULL,
+    country_code varchar(2),
+    country_name text,
+    city text,
+    latitude float,
+    longitude float,
+    mystery_value bigint
+) PARTITION BY LIST (dataset_id);
+
+CREATE INDEX ip_location_ip_idx ON geolocation_partitioned.ip_location USING hash(ip_address);
+
+-- Locations of all layouts, partitions of other datasets are pruned.
+CREATE OR REPLACE VIEW geolocation.ip_location_all AS
+SELECT id::bigint AS id, ip_address, country_code, country_name, city, latitude, longitude, mystery_value, dataset_id
+FROM geolocation.ip_location
+UNION ALL
+SELECT l.id, l.ip_address, c.code, c.name, ci.name, lo.latitude, lo.longitude, l.mystery_value, l.dataset_id
+FROM geolocation_normalized.ip_location l
+JOIN geolocation_normalized.location lo ON lo.id = l.location_id
+JOIN geolocation_normalized.city ci ON ci.id = lo.city_id
+JOIN geolocation_normalized.country c ON c.id = ci.country_id
+UNION ALL
+SELECT id, ip_address, country_code, country_name, city, latitude, longitude, mystery_value, dataset_id
+FROM geolocation_partitioned.ip_location;
+
+-- ---- create above / drop below ----
+
+CREATE OR REPLACE VIEW geolocation.ip_location_all AS
+SELECT id::bigint AS id, ip_address, country_code, country_name, city, latitude, longitude, mystery_value, dataset_id
+FROM geolocation.ip_location
+UNION ALL
+SELECT l.id, l.ip_address, c.code, c.name, ci.name, lo.latitude, lo.longitude, l.mystery_value, l.dataset_id
+FROM geolocation_normalized.ip_location l
+JOIN geolocation_normalized.location lo ON lo.id = l.location_id
+JOIN geolocation_normalized.city ci ON ci.id = lo.city_id
+JOIN geolocation_normalized.country c ON c.id = ci.country_id;
+
+DROP SCHEMA geolocation_partitioned CASCADE;
@@ -0,0 +1,95 @@
+-- Where every IP location comes from: the source file, the import run and the line of the file, and when the
+-- location was observed. Locations imported before are left without provenance.
+ALTER TABLE geolocation.ip_location
+    ADD COLUMN source_id text,
+    ADD COLUMN source_name text,
+    ADD COLUMN import_run_id text,
+    ADD COLUMN source_line int,
+    ADD COLUMN observed_at timestamptz;
+
+ALTER TABLE geolocation_normalized.ip_location
+    ADD COLUMN source_id text,
+    ADD COLUMN source_name text,
+    ADD COLUMN import_run_id text,
+    ADD COLUMN source_line int,
+    ADD COLUMN observed_at timestamptz;
+
+-- Columns are added to the attached partitions as well, detached ones must match to be attached back.
+ALTER TABLE geolocation_partitioned.ip_location
+    ADD COLUMN source_id text,
+    ADD COLUMN source_name text,
+    ADD COLUMN import_run_id text,
+    ADD COLUMN source_line int,
+    ADD COLUMN observed_at timestamptz;
+
+DO $$
+DECLARE
+    detached regclass;
+BEGIN
+    FOR detached IN
+        SELECT c.oid FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+        WHERE n.nspname = 'geolocation_partitioned' AND c.relkind = 'r' AND NOT c.relispartition
+          AND c.relname ~ '^ip_location_[0-9]+$'
+    LOOP
+        EXECUTE format('ALTER TABLE %s ADD COLUMN source_id text, ADD COLUMN source_name text, '
+            'ADD COLUMN import_run_id text, ADD COLUMN source_line int, ADD COLUMN observed_at timestamptz',
+            detached);
+    END LOOP;
+END $$;
+
+CREATE OR REPLACE VIEW geolocation.ip_location_all AS
+SELECT id::bigint AS id, ip_address, country_code, country_name, city, latitude, longitude, mystery_value, dataset_id,
+       coalesce(source_id, '') AS source_id, coalesce(source_name, '') AS source_name,
+       coalesce(import_run_id, '') AS import_run_id, coalesce(source_line, 0) AS source_line, observed_at
+FROM geolocation.ip_location
+UNION ALL
+SELECT l.id, l.ip_address, c.code, c.name, ci.name, lo.latitude, lo.longitude, l.mystery_value, l.dataset_id,
+       coalesce(l.source_id, ''), coalesce(l.source_name, ''), coalesce(l.import_run_id, ''),
+       coalesce(l.source_line, 0), l.observed_at
+FROM geolocation_normalized.ip_location l
+JOIN geolocation_normalized.location lo ON lo.id = l.location_id
+JOIN geolocation_normalized.city ci ON ci.id = lo.city_id
+JOIN geolocation_normalized.country c ON c.id = ci.country_id
+UNION ALL
+SELECT id, ip_address, country_code, country_name, city, latitude, longitude, mystery_value, dataset_id,
+       coalesce(source_id, ''), coalesce(source_name, ''), coalesce(import_run_id, ''), coalesce(source_line, 0),
+       observed_at
+FROM geolocation_partitioned.ip_location;
+
+-- ---- create above / drop below ----
+
+DROP VIEW geolocation.ip_location_all;
+
+CREATE VIEW geolocation.ip_location_all AS
+SELECT id::bigint AS id, ip_address, country_code, country_name, city, latitude, longitude, mystery_value, dataset_id
+FROM geolocation.ip_location
+UNION ALL
+SELECT l.id, l.ip_address, c.code, c.name, ci.name, lo.latitude, lo.longitude, l.mystery_value, l.dataset_id
+FROM geolocation_normalized.ip_location l
+JOIN geolocation_normalized.location lo ON lo.id = l.location_id
+JOIN geolocation_normalized.city ci ON ci.id = lo.city_id
+JOIN geolocation_normalized.country c ON c.id = ci.country_id
+UNION ALL
+SELECT id, ip_address, country_code, country_name, city, latitude, longitude, mystery_value, dataset_id
+FROM geolocation_partitioned.ip_location;
+
+ALTER TABLE geolocation_partitioned.ip_location
+    DROP COLUMN source_id,
+    DROP COLUMN source_name,
+    DROP COLUMN import_run_id,
+    DROP COLUMN source_line,
+    DROP COLUMN observed_at;
+
+ALTER TABLE geolocation_normalized.ip_location
+    DROP COLUMN source_id,
+    DROP COLUMN source_name,
+    DROP COLUMN import_run_id,
+    DROP COLUMN source_line,
+    DROP COLUMN observed_at;
+
+ALTER TABLE geolocation.ip_location
+    DROP COLUMN source_id,
+    DROP COLUMN source_name,
+    DROP COLUMN import_run_id,
+    DROP COLUMN source_line,
+    DROP COLUMN observed_at;
@@ -3,14 +3,30 @@ package storage
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-func CreateConnectionPool(ctx context.Context, connString string) (*pgxpool.Pool, error) {
-	config, err := pgxpool.ParseConfig(connString)
+// PoolOptions tunes connection pools, zero values keep the defaults of pgxpool or the connection string.
+type PoolOptions struct {
+	MaxConns         int32
+	MinConns         int32
+	MaxConnLifetime  time.Duration
+	StatementTimeout time.Duration // Queries running longer are canceled by the server.
+}
+
+func CreateConnectionPool(
+	ctx context.Context,
+	connString string,
+	opts PoolOptions,
+	logger *slog.Logger,
+) (*pgxpool.Pool, error) {
+	config, err := newPoolConfig(ctx, connString, opts, logger)
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse connection string: %w", err)
+		return nil, err
 	}
 
 	pool, err := pgxpool.ConnectConfig(ctx, config)
@@ -19,11 +35,41 @@ func CreateConnectionPool(ctx context.Context, connString string) (*pgxpool.Pool
 	}
 	conn, err := pool.Acquire(ctx)
 	if err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("unable to accuire connection: %w", err)
 	}
 	defer conn.Release()
 	if err := conn.Conn().Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("unable to ping DB: %w", err)
 	}
 	return pool, nil
 }
+
+func newPoolConfig(
+	ctx context.Context,
+	connString string,
+	opts PoolOptions,
+	logger *slog.Logger,
+) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse connection string: %w", err)
+	}
+	config.ConnConfig.Logger = pgxLogger{logger: logger}
+	config.ConnConfig.LogLevel = pgxLogLevel(ctx, logger)
+	config.AfterConnect = prepareQueries
+	if opts.MaxConns > 0 {
+		config.MaxConns = opts.MaxConns
+	}
+	if opts.MinConns > 0 {
+		config.MinConns = opts.MinConns
+	}
+	if opts.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.StatementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	}
+	return config, nil
+}
@@ -11,21 +11,41 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
 )
 
 func TestCreateConnectionPool_EmptyConnString(t *testing.T) {
-	pool, err := CreateConnectionPool(context.Background(), "")
+	pool, err := CreateConnectionPool(context.Background(), "", PoolOptions{}, logging.Discard())
 	require.Error(t, err)
 	assert.Nil(t, pool)
 }
 
 func TestCreateConnectionPool_Default(t *testing.T) {
-	pool, err := CreateConnectionPool(context.Background(), testConnectionString(testDBName))
+	ctx := context.Background()
+	pool, err := CreateConnectionPool(ctx, testConnectionString(testDBName), PoolOptions{}, logging.Discard())
 	require.NoError(t, err)
 	assert.NotNil(t, pool)
 	defer pool.Close()
 }
 
+func TestNewPoolConfig(t *testing.T) {
+	ctx := context.Background()
+	config, err := newPoolConfig(ctx, "postgres://u:p@localhost/db?pool_max_conns=7", PoolOptions{}, logging.Discard())
+	require.NoError(t, err)
+	assert.Equal(t, int32(7), config.MaxConns, "connection string is kept by zero options")
+	assert.NotContains(t, config.ConnConfig.RuntimeParams, "statement_timeout")
+
+	config, err = newPoolConfig(ctx, "postgres://u:p@localhost/db?pool_max_conns=7", PoolOptions{
+		MaxConns: 10, MinConns: 2, MaxConnLifetime: time.Minute, StatementTimeout: 1500 * time.Millisecond,
+	}, logging.Discard())
+	require.NoError(t, err)
+	assert.Equal(t, int32(10), config.MaxConns)
+	assert.Equal(t, int32(2), config.MinConns)
+	assert.Equal(t, time.Minute, config.MaxConnLifetime)
+	assert.Equal(t, "1500", config.ConnConfig.RuntimeParams["statement_timeout"])
+}
+
 const testDBName = "test"
 
 func testConnectionString(dbName string) string {
@@ -47,7 +67,7 @@ func getTestDBName(testName string) string {
 }
 
 func testConnectionPool(ctx context.Context, t *testing.T) (p *pgxpool.Pool, teardown func()) {
-	helperPool, err := CreateConnectionPool(ctx, testConnectionString(testDBName))
+	helperPool, err := CreateConnectionPool(ctx, testConnectionString(testDBName), PoolOptions{}, logging.Discard())
 	require.NoError(t, err)
 	defer helperPool.Close()
 
@@ -55,7 +75,7 @@ func testConnectionPool(ctx context.Context, t *testing.T) (p *pgxpool.Pool, tea
 	_, err = helperPool.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s;", dbName))
 	require.NoError(t, err)
 
-	pool, err := CreateConnectionPool(ctx, testConnectionString(dbName))
+	pool, err := CreateConnectionPool(ctx, testConnectionString(dbName), PoolOptions{}, logging.Discard())
 	require.NoError(t, err)
 	return pool, func() {
 		pool.Close()
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// replicaConnectTimeout limits the first health check of the replicas.
+const replicaConnectTimeout = 5 * time.Second
+
+// Replicas routes reads round-robin to healthy read replicas, and to the primary if none of them is healthy.
+type Replicas struct {
+	primary *pgxpool.Pool
+	pools   []*pgxpool.Pool
+	healthy []atomic.Bool
+	next    atomic.Uint64
+	logger  *slog.Logger
+}
+
+// ConnectReplicas creates pools of the read replicas and checks their health. Unlike CreateConnectionPool,
+// unreachable replicas are not an error, they are skipped until they become healthy.
+func ConnectReplicas(
+	ctx context.Context,
+	primary *pgxpool.Pool,
+	connStrings []string,
+	opts PoolOptions,
+	logger *slog.Logger,
+) (*Replicas, error) {
+	r := &Replicas{primary: primary, healthy: make([]atomic.Bool, len(connStrings)), logger: logger}
+	for _, connString := range connStrings {
+		config, err := newPoolConfig(ctx, connString, opts, logger)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("read replica: %w", err)
+		}
+		config.LazyConnect = true
+		pool, err := pgxpool.ConnectConfig(ctx, config)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("unable to connect read replica: %w", err)
+		}
+		r.pools = append(r.pools, pool)
+		r.healthy[len(r.pools)-1].Store(true) // Until checked, so that unhealthy ones are logged.
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, replicaConnectTimeout)
+	defer cancel()
+	r.CheckHealth(checkCtx)
+	return r, nil
+}
+
+// Pool returns the pool to read from.
+func (r *Replicas) Pool() *pgxpool.Pool {
+	n := uint64(len(r.pools))
+	for i := uint64(0); i < n; i++ {
+		// Unhealthy replicas take their turns, so the next healthy one isn't picked twice as often.
+		if idx := r.next.Add(1) % n; r.healthy[idx].Load() {
+			return r.pools[idx]
+		}
+	}
+	return r.primary
+}
+
+// CheckHealth pings the replicas, a replica is healthy if the ping succeeds.
+func (r *Replicas) CheckHealth(ctx context.Context) {
+	for i, pool := range r.pools {
+		err := pool.Ping(ctx)
+		healthy := err == nil
+		if r.healthy[i].Swap(healthy) == healthy {
+			continue
+		}
+		host := pool.Config().ConnConfig.Host
+		if healthy {
+			r.logger.InfoContext(ctx, "read replica is healthy", "host", host)
+		} else {
+			r.logger.WarnContext(ctx, "read replica is unhealthy", "host", host, "error", err)
+		}
+	}
+}
+
+// Run checks health of the replicas every period, until the context is done.
+func (r *Replicas) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, period)
+			r.CheckHealth(checkCtx)
+			cancel()
+		}
+	}
+}
+
+// Close closes pools of the replicas, the primary is left open.
+func (r *Replicas) Close() {
+	for _, pool := range r.pools {
+		pool.Close()
+	}
+}
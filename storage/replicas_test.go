@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/search-accomodation/internal/logging"
+)
+
+// unreachableReplicas connects replicas which can't be pinged, the primary is the first of them.
+func unreachableReplicas(t *testing.T, n int) *Replicas {
+	t.Helper()
+	connStrings := make([]string, n)
+	for i := range connStrings {
+		connStrings[i] = "postgres://u:p@127.0.0.1:1/db?connect_timeout=1"
+	}
+	replicas, err := ConnectReplicas(context.Background(), nil, connStrings, PoolOptions{}, logging.Discard())
+	require.NoError(t, err)
+	t.Cleanup(replicas.Close)
+	replicas.primary = replicas.pools[0]
+	return replicas
+}
+
+func TestReplicas_Pool_RoundRobin(t *testing.T) {
+	replicas := unreachableReplicas(t, 3)
+	for i := range replicas.healthy {
+		replicas.healthy[i].Store(i != 1)
+	}
+	var got []int
+	for i := 0; i < 4; i++ {
+		pool := replicas.Pool()
+		for j := range replicas.pools {
+			if replicas.pools[j] == pool {
+				got = append(got, j)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []int{0, 0, 2, 2}, got, "unhealthy replicas are skipped")
+}
+
+func TestReplicas_Pool_FallbackToPrimary(t *testing.T) {
+	replicas := unreachableReplicas(t, 2)
+	replicas.primary = nil
+	for i := range replicas.healthy {
+		assert.False(t, replicas.healthy[i].Load(), "unreachable replica is unhealthy")
+	}
+	assert.Nil(t, replicas.Pool())
+
+	replicas.healthy[1].Store(true)
+	assert.Same(t, replicas.pools[1], replicas.Pool())
+	replicas.CheckHealth(context.Background())
+	assert.Nil(t, replicas.Pool())
+}
+
+func TestConnectReplicas_BadConnString(t *testing.T) {
+	_, err := ConnectReplicas(context.Background(), nil, []string{"postgres://:-1"}, PoolOptions{}, logging.Discard())
+	require.Error(t, err)
+}
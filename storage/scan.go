@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// structScanner scans rows to structs of type T, columns are matched to the fields by their `db` tags,
+// so the order of columns doesn't matter and unknown columns are an error rather than shifted values.
+type structScanner[T any] struct {
+	fields  map[string]int // Field index by column.
+	columns []string       // In order of the fields.
+}
+
+// newStructScanner panics if T is not a struct with `db` tags, as it is a programming error.
+func newStructScanner[T any]() *structScanner[T] {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("storage: %v is not a struct", typ))
+	}
+	s := &structScanner[T]{fields: map[string]int{}}
+	for i := 0; i < typ.NumField(); i++ {
+		column := typ.Field(i).Tag.Get("db")
+		if column == "" || column == "-" {
+			continue
+		}
+		s.fields[column] = i
+		s.columns = append(s.columns, column)
+	}
+	if len(s.columns) == 0 {
+		panic(fmt.Sprintf("storage: %v has no db tags", typ))
+	}
+	return s
+}
+
+// Columns returns the column list to select.
+func (s *structScanner[T]) Columns() string {
+	return strings.Join(s.columns, ", ")
+}
+
+// Scan scans the current row.
+func (s *structScanner[T]) Scan(rows pgx.Rows) (T, error) {
+	var dst T
+	value := reflect.ValueOf(&dst).Elem()
+	fields := rows.FieldDescriptions()
+	targets := make([]interface{}, len(fields))
+	for i := range fields {
+		idx, ok := s.fields[string(fields[i].Name)]
+		if !ok {
+			return dst, fmt.Errorf("unexpected column %q", fields[i].Name)
+		}
+		targets[i] = value.Field(idx).Addr().Interface()
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return dst, err //nolint:wrapcheck
+	}
+	return dst, nil
+}
+
+// ScanAll scans the rows until they are exhausted, and closes them.
+func (s *structScanner[T]) ScanAll(rows pgx.Rows, capacity int) ([]T, error) {
+	defer rows.Close()
+	res := make([]T, 0, capacity)
+	for rows.Next() {
+		dst, err := s.Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, dst)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return res, nil
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scannedRow struct {
+	ID      int    `db:"id"`
+	Name    string `db:"name"`
+	Ignored string
+	Skipped string `db:"-"`
+}
+
+// rowsStub - pgx.Rows of the columns with the values, scanned by assignment.
+type rowsStub struct {
+	pgx.Rows
+	columns []string
+	values  [][]interface{}
+	next    int
+	closed  bool
+}
+
+func (r *rowsStub) FieldDescriptions() []pgproto3.FieldDescription {
+	fields := make([]pgproto3.FieldDescription, len(r.columns))
+	for i := range r.columns {
+		fields[i].Name = []byte(r.columns[i])
+	}
+	return fields
+}
+
+func (r *rowsStub) Next() bool {
+	r.next++
+	return r.next <= len(r.values)
+}
+
+func (r *rowsStub) Scan(dest ...interface{}) error {
+	for i, value := range r.values[r.next-1] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (r *rowsStub) Err() error {
+	return nil
+}
+
+func (r *rowsStub) Close() {
+	r.closed = true
+}
+
+func TestStructScanner(t *testing.T) {
+	scanner := newStructScanner[scannedRow]()
+	assert.Equal(t, "id, name", scanner.Columns())
+
+	rows := &rowsStub{columns: []string{"name", "id"}, values: [][]interface{}{{"first", 1}, {"second", 2}}}
+	scanned, err := scanner.ScanAll(rows, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []scannedRow{{ID: 1, Name: "first"}, {ID: 2, Name: "second"}}, scanned)
+	assert.True(t, rows.closed)
+
+	rows = &rowsStub{columns: []string{"id", "country"}, values: [][]interface{}{{1, "UK"}}}
+	_, err = scanner.ScanAll(rows, 0)
+	require.EqualError(t, err, `unexpected column "country"`)
+}
+
+func TestNewStructScanner_NoTags(t *testing.T) {
+	assert.Panics(t, func() { newStructScanner[struct{ ID int }]() })
+	assert.Panics(t, func() { newStructScanner[int]() })
+}
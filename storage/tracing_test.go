@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dronnix/search-accomodation/internal/tracing/tracingtest"
+	"github.com/dronnix/search-accomodation/model/geolocation"
+	"github.com/dronnix/search-accomodation/model/geolocation/geolocationtest"
+)
+
+func TestIPLocationStorage_Spans(t *testing.T) {
+	ctx, storage, teardown := setUpDB(t)
+	defer teardown()
+	require.NoError(t, storage.MigrateUp(ctx, migrations))
+	exporter := tracingtest.Setup(t)
+
+	version := geolocationtest.StoreDataset(ctx, t, storage, []geolocation.IPLocation{
+		{IP: netip.AddrFrom4([4]byte{1, 2, 3, 4}), CountryCode: "UK", CountryName: "United Kingdom", City: "London"},
+		{IP: netip.AddrFrom4([4]byte{1, 2, 3, 5}), CountryCode: "UK", CountryName: "United Kingdom", City: "London"},
+	}, true)
+	_, err := storage.FetchLocationsByIP(ctx, netip.AddrFrom4([4]byte{1, 2, 3, 4}))
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	store, fetch := spans[0], spans[1]
+	assert.Equal(t, "storage.StoreIPLocations", store.Name)
+	assert.Equal(t, trace.SpanKindClient, store.SpanKind)
+	assert.Contains(t, store.Attributes, semconv.DBSystemPostgreSQL)
+	assert.Contains(t, store.Attributes, geolocation.BatchSizeKey.Int(2))
+	assert.Contains(t, store.Attributes, geolocation.DatasetVersionKey.Int(version))
+	assert.Equal(t, "storage.FetchLocationsByIP", fetch.Name)
+	assert.Contains(t, fetch.Attributes, semconv.NetworkTypeIpv4)
+	assert.Contains(t, fetch.Attributes, geolocation.ResultCountKey.Int(1))
+}